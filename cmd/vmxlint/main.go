@@ -0,0 +1,67 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command vmxlint validates .vmx files against the catalog of the known parameters.
+//
+// Usage:
+//
+//	vmxlint [-unknown=false] [-guestos] file.vmx...
+//
+// It prints the issues as "file:line: key: kind: message", and exits with 1 if any issue is found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/go-vm/vmware/vmx"
+	"github.com/go-vm/vmware/vmx/vmxlint"
+)
+
+var (
+	unknown = flag.Bool("unknown", true, "report the keys which are not in the catalog")
+	guestOS = flag.Bool("guestos", false, "report the guestOS value which is not in the catalog")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: vmxlint [flags] file.vmx...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	status := 0
+	for _, name := range flag.Args() {
+		f, err := vmx.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 2
+			continue
+		}
+		issues := vmxlint.Validate(f, nil)
+		if *guestOS {
+			issues = append(issues, vmxlint.ValidateGuestOS(f, nil)...)
+			sort.SliceStable(issues, func(i, j int) bool {
+				return issues[i].Line < issues[j].Line
+			})
+		}
+		for _, issue := range issues {
+			if issue.Kind == vmxlint.Unknown && !*unknown {
+				continue
+			}
+			fmt.Printf("%s:%s\n", name, issue)
+			if status == 0 {
+				status = 1
+			}
+		}
+	}
+
+	os.Exit(status)
+}
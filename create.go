@@ -0,0 +1,329 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-vm/vmware/vdiskmanager"
+	"github.com/go-vm/vmware/vmx"
+)
+
+// List of the defaults of VMSpec.
+const (
+	// DefaultHWVersion is the default virtual hardware version, which is supported by VMware Fusion 10 and Workstation 14.
+	DefaultHWVersion = 14
+	// DefaultGuestOS is the default guest OS identifier.
+	DefaultGuestOS = "other-64"
+	// DefaultMemory is the memory size in MB of the guest OS which is not in the catalog.
+	DefaultMemory = 1024
+	// DefaultDiskSize is the virtual disk size in MB of the guest OS which is not in the catalog.
+	DefaultDiskSize = 20000
+)
+
+// DiskSpec represents a virtual disk created by CreateVM.
+type DiskSpec struct {
+	// Size is the capacity in MB. If zero, the recommended size of the guest OS is used.
+	Size int
+	// Bus is the controller type which the disk is attached to. If empty, the recommended bus of the guest OS is used.
+	Bus vmx.Bus
+	// DiskType is the vmware-vdiskmanager disk type, such as 0 for a single growable disk.
+	DiskType int
+}
+
+// VMSpec represents a VM created by CreateVM.
+//
+// The zero fields of the virtual hardware are the recommended values of the guest OS in the vmx.GuestOSInfo catalog.
+// If the guest OS is not in the catalog, DefaultMemory, DefaultDiskSize, the SCSI disks and the e1000 network adapter are used.
+type VMSpec struct {
+	// Product is the VMware product which runs the VM, which determines the layout of the VM directory.
+	Product Product
+	// Dir is the parent directory of the VM directory.
+	Dir string
+	// Name is the display name of the VM and the base name of its files.
+	Name string
+	// GuestOS is the guest OS identifier, such as "ubuntu-64". If empty, DefaultGuestOS is used.
+	GuestOS string
+	// HWVersion is the virtual hardware version. If zero, DefaultHWVersion is used.
+	HWVersion int
+	// CPUs is the number of the virtual CPUs. If zero, one CPU is used.
+	CPUs int
+	// CoresPerSocket is the number of the cores per virtual CPU socket. If zero, the VMware default is used.
+	CoresPerSocket int
+	// Memory is the memory size in MB. If zero, the recommended size of the guest OS is used.
+	Memory int
+	// Firmware is the firmware. If empty, the recommended firmware of the guest OS is used.
+	Firmware vmx.Firmware
+	// SCSIController is the virtual device of the SCSI controllers, such as "lsilogic" or "pvscsi".
+	// If empty, the recommended controller of the guest OS, or "lsilogic" is used.
+	SCSIController string
+	// Disks is the virtual disks created in the VM directory, in the order of the boot priority.
+	Disks []DiskSpec
+	// ISO is the installer ISO image attached to a SATA CD-ROM drive, if not empty.
+	ISO string
+	// Network is the connection type of the network adapter, such as "nat" or "bridged". If empty, "nat" is used.
+	Network string
+	// NICDevice is the virtual device of the network adapter, such as "e1000e" or "vmxnet3".
+	// If empty, the recommended device of the guest OS is used.
+	NICDevice string
+	// Username and Password are the guest OS credentials of the returned Host.
+	Username string
+	Password string
+	// VDiskManager creates the virtual disks. If nil, vdiskmanager.DefaultClient is used.
+	VDiskManager *vdiskmanager.Client
+}
+
+// ErrVMExists is returned by CreateVM when the VM directory already exists.
+var ErrVMExists = errors.New("vmware: VM directory already exists")
+
+// vmDir returns the VM directory of spec, which is the .vmwarevm bundle for VMware Fusion.
+func (spec *VMSpec) vmDir() string {
+	if spec.Product == ProductFusion {
+		return filepath.Join(spec.Dir, spec.Name+".vmwarevm")
+	}
+
+	return filepath.Join(spec.Dir, spec.Name)
+}
+
+// guestOS returns the recommended virtual hardware of the guest OS of spec.
+func (spec *VMSpec) guestOS() vmx.GuestOS {
+	id := spec.GuestOS
+	if id == "" {
+		id = DefaultGuestOS
+	}
+	if g, err := vmx.GuestOSInfo(id); err == nil {
+		return g
+	}
+
+	return vmx.GuestOS{ID: id, NICDevice: "e1000", DiskBus: vmx.BusSCSI, SCSIController: "lsilogic", Memory: DefaultMemory, DiskSize: DefaultDiskSize}
+}
+
+// diskFileName returns the .vmdk file name of the i-th disk, such as "test.vmdk" and "test-2.vmdk".
+func (spec *VMSpec) diskFileName(i int) string {
+	if i == 0 {
+		return spec.Name + ".vmdk"
+	}
+
+	return spec.Name + "-" + strconv.Itoa(i+1) + ".vmdk"
+}
+
+// adapterType returns the vmware-vdiskmanager adapter type of the bus,
+// which is lsilogic for the buses other than IDE and BusLogic.
+func adapterType(bus vmx.Bus) vdiskmanager.AdapterType {
+	if bus == vmx.BusIDE {
+		return vdiskmanager.Ide
+	}
+
+	return vdiskmanager.LsiLogic
+}
+
+// newVMID returns the random VM ID of the .vmxf file such as "52 3c 5e 1f 6b 23 d5 a7-f2 0a 5b 4f 3b 1e 0c 6d".
+func newVMID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[0] = 0x52
+
+	hex := make([]string, len(b))
+	for i, c := range b {
+		hex[i] = fmt.Sprintf("%02x", c)
+	}
+
+	return strings.Join(hex[:8], " ") + "-" + strings.Join(hex[8:], " "), nil
+}
+
+// vmxfTemplate is the .vmxf extended configuration file which VMware Fusion and Workstation write for a new VM.
+const vmxfTemplate = `<?xml version="1.0"?>
+<Foundry>
+<VM>
+<VMId type="string">%s</VMId>
+<ClientMetaData>
+<clientMetaDataAttributes/>
+<HistoryEventList/></ClientMetaData>
+<vmxPathName type="string">%s</vmxPathName></VM></Foundry>
+`
+
+// escapeXML returns s escaped as the XML character data.
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s)) // strings.Builder never fails to write
+
+	return b.String()
+}
+
+// newVMX returns the .vmx file of spec. The disks of spec are attached with their file names.
+func (spec *VMSpec) newVMX() (*vmx.File, error) {
+	f := vmx.New()
+	f.Set("config.version", "8")
+	f.Set("nvram", spec.Name+".nvram")
+	f.Set("extendedConfigFile", spec.Name+".vmxf")
+	// the PCI Express root ports are required by vmxnet3, pvscsi and NVMe.
+	f.Set("pciBridge0.present", "TRUE")
+	for i := 4; i < 8; i++ {
+		p := "pciBridge" + strconv.Itoa(i) + "."
+		f.Set(p+"present", "TRUE")
+		f.Set(p+"virtualDev", "pcieRootPort")
+		f.Set(p+"functions", "8")
+	}
+	f.Set("vmci0.present", "TRUE")
+	f.Set("hpet0.present", "TRUE")
+	f.Set("floppy0.present", "FALSE")
+	f.Set("tools.syncTime", "TRUE")
+
+	g := spec.guestOS()
+	vm := &vmx.VirtualMachine{
+		DisplayName: spec.Name,
+		GuestOS:     g.ID,
+		HWVersion:   spec.HWVersion,
+		CPU:         vmx.CPU{Count: spec.CPUs, CoresPerSocket: spec.CoresPerSocket},
+		Memory:      spec.Memory,
+		Firmware:    spec.Firmware,
+		USB:         vmx.USB{Present: true},
+	}
+	if vm.HWVersion == 0 {
+		vm.HWVersion = DefaultHWVersion
+	}
+	if vm.CPU.Count == 0 {
+		vm.CPU.Count = 1
+	}
+	if vm.Memory == 0 {
+		vm.Memory = g.Memory
+	}
+	if vm.Firmware == "" {
+		vm.Firmware = g.Firmware
+	}
+
+	var bootOrder []string
+	for i, d := range spec.Disks {
+		bus := d.Bus
+		if bus == "" {
+			bus = g.DiskBus
+		}
+		disk, err := vm.AddDisk(bus, spec.diskFileName(i))
+		if err != nil {
+			return nil, err
+		}
+		bootOrder = append(bootOrder, disk.Node.String())
+	}
+	if spec.ISO != "" {
+		// the guest OS which boots from IDE, such as Windows XP, may not support SATA.
+		bus := vmx.BusSATA
+		if g.DiskBus == vmx.BusIDE {
+			bus = vmx.BusIDE
+		}
+		if _, err := vm.AddCDROM(bus, spec.ISO); err != nil {
+			return nil, err
+		}
+	}
+	network, device := spec.Network, spec.NICDevice
+	if network == "" {
+		network = "nat"
+	}
+	if device == "" {
+		device = g.NICDevice
+	}
+	if _, err := vm.AddNIC(network, device); err != nil {
+		return nil, err
+	}
+
+	if err := f.SetVirtualMachine(vm); err != nil {
+		return nil, err
+	}
+	if len(bootOrder) > 0 {
+		f.Set("bios.hddOrder", strings.Join(bootOrder, ","))
+	}
+	controller := spec.SCSIController
+	if controller == "" {
+		controller = g.SCSIController
+	}
+	if controller == "" {
+		controller = "lsilogic"
+	}
+	for i := 0; i < 4; i++ {
+		p := "scsi" + strconv.Itoa(i)
+		if v, _ := f.Get(p + ".present"); strings.EqualFold(v, "TRUE") {
+			f.Set(p+".virtualDev", controller)
+		}
+	}
+
+	return f, nil
+}
+
+// CreateVM creates the new VM of spec, and returns the Host of the VM which is ready to start.
+//
+// The VM directory is the spec.Name+".vmwarevm" bundle for VMware Fusion, or the spec.Name directory
+// for VMware Workstation and Player, in spec.Dir. It returns ErrVMExists if the VM directory exists.
+// If the creation fails, the VM directory is removed.
+func CreateVM(spec *VMSpec) (*Host, error) {
+	return CreateVMContext(context.Background(), spec)
+}
+
+// CreateVMContext is like CreateVM but includes a context.
+func CreateVMContext(ctx context.Context, spec *VMSpec) (h *Host, err error) {
+	if spec.Name == "" || strings.ContainsAny(spec.Name, `/\`) {
+		return nil, fmt.Errorf("vmware: invalid VM name %q", spec.Name)
+	}
+	f, err := spec.newVMX()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := spec.vmDir()
+	if err := os.Mkdir(dir, 0755); err != nil {
+		if os.IsExist(err) {
+			return nil, ErrVMExists
+		}
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	vdisk := spec.VDiskManager
+	if vdisk == nil {
+		vdisk = vdiskmanager.DefaultClient
+	}
+	g := spec.guestOS()
+	for i, d := range spec.Disks {
+		size, bus := d.Size, d.Bus
+		if size == 0 {
+			size = g.DiskSize
+		}
+		if bus == "" {
+			bus = g.DiskBus
+		}
+		config := &vdiskmanager.Config{Size: size, DiskType: d.DiskType, Adapter: adapterType(bus)}
+		if err := vdisk.Create(ctx, filepath.Join(dir, spec.diskFileName(i)), config); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := newVMID()
+	if err != nil {
+		return nil, err
+	}
+	vmxPath := filepath.Join(dir, spec.Name+".vmx")
+	vmxf := fmt.Sprintf(vmxfTemplate, id, escapeXML(spec.Name+".vmx"))
+	if err := ioutil.WriteFile(filepath.Join(dir, spec.Name+".vmxf"), []byte(vmxf), 0644); err != nil {
+		return nil, err
+	}
+	if err := f.WriteFileMode(vmxPath, 0644); err != nil {
+		return nil, err
+	}
+
+	return NewHost(spec.Product, vmxPath, spec.Username, spec.Password), nil
+}
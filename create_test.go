@@ -0,0 +1,270 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vdiskmanager"
+	"github.com/go-vm/vmware/vmx"
+	"github.com/go-vm/vmware/vmx/vmxlint"
+)
+
+// fakeVDiskManager returns the vdiskmanager client which creates the empty disk file,
+// or fails for the disk named fail.
+func fakeVDiskManager(created *[][]string, fail string) *vdiskmanager.Client {
+	return vdiskmanager.NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		*created = append(*created, args)
+		dst := args[len(args)-1]
+		if filepath.Base(dst) == fail {
+			return &runner.Result{ExitCode: 1, Stdout: "Failed to create the disk"}, nil
+		}
+		return &runner.Result{}, ioutil.WriteFile(dst, nil, 0644)
+	}))
+}
+
+func TestCreateVM(t *testing.T) {
+	dir := t.TempDir()
+	var created [][]string
+	spec := &VMSpec{
+		Product:        ProductFusion,
+		Dir:            dir,
+		Name:           "test",
+		GuestOS:        "ubuntu-64",
+		CPUs:           2,
+		Memory:         2048,
+		Firmware:       vmx.FirmwareEFI,
+		SCSIController: "pvscsi",
+		Disks:          []DiskSpec{{Size: 40000}, {Bus: vmx.BusSATA}},
+		ISO:            "/iso/ubuntu.iso",
+		NICDevice:      "vmxnet3",
+		VDiskManager:   fakeVDiskManager(&created, ""),
+	}
+
+	h, err := CreateVM(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vmDir := filepath.Join(dir, "test.vmwarevm")
+	wantVMX := filepath.Join(vmDir, "test.vmx")
+	if h.VMX() != wantVMX || h.Product() != ProductFusion {
+		t.Fatalf("Host = %v %q, want %v %q", h.Product(), h.VMX(), ProductFusion, wantVMX)
+	}
+	wantCreated := [][]string{
+		{"-c", "-s", "40000MB", "-t", "0", "-a", "lsilogic", filepath.Join(vmDir, "test.vmdk")},
+		{"-c", "-s", "20480MB", "-t", "0", "-a", "lsilogic", filepath.Join(vmDir, "test-2.vmdk")},
+	}
+	if !reflect.DeepEqual(created, wantCreated) {
+		t.Errorf("vmware-vdiskmanager = %q, want %q", created, wantCreated)
+	}
+
+	vmxf, err := ioutil.ReadFile(filepath.Join(vmDir, "test.vmxf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(vmxf), `<vmxPathName type="string">test.vmx</vmxPathName>`) {
+		t.Errorf(".vmxf = %s, want vmxPathName test.vmx", vmxf)
+	}
+
+	f, err := vmx.ReadFile(wantVMX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vm := f.VirtualMachine()
+	want := &vmx.VirtualMachine{
+		DisplayName: "test",
+		GuestOS:     "ubuntu-64",
+		HWVersion:   DefaultHWVersion,
+		CPU:         vmx.CPU{Count: 2},
+		Memory:      2048,
+		Firmware:    vmx.FirmwareEFI,
+		Disks: []vmx.Disk{
+			{Node: vmx.Node{Bus: vmx.BusSATA, Unit: 0}, FileName: "test-2.vmdk"},
+			{Node: vmx.Node{Bus: vmx.BusSCSI, Unit: 0}, FileName: "test.vmdk"},
+		},
+		CDROMs: []vmx.CDROM{{Node: vmx.Node{Bus: vmx.BusSATA, Unit: 1}, DeviceType: "cdrom-image", FileName: "/iso/ubuntu.iso", StartConnected: true}},
+		NICs:   []vmx.NIC{{Index: 0, ConnectionType: "nat", VirtualDev: "vmxnet3", StartConnected: true}},
+		USB:    vmx.USB{Present: true},
+	}
+	if !reflect.DeepEqual(vm, want) {
+		t.Errorf("VirtualMachine = %+v, want %+v", vm, want)
+	}
+	for key, want := range map[string]string{"bios.hddOrder": "scsi0:0,sata0:0", "scsi0.virtualDev": "pvscsi"} {
+		if got, _ := f.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if issues := vmxlint.Validate(f, nil); len(issues) > 0 {
+		t.Errorf("vmxlint issues = %v", issues)
+	}
+
+	if _, err := CreateVM(spec); err != ErrVMExists {
+		t.Fatalf("CreateVM error = %v, want %v", err, ErrVMExists)
+	}
+}
+
+func TestCreateVMGuestOSDefaults(t *testing.T) {
+	tests := []struct {
+		guestOS     string
+		wantCreated []string
+		want        *vmx.VirtualMachine
+		wantSCSI    string
+	}{
+		{
+			guestOS:     "windows9-64",
+			wantCreated: []string{"-c", "-s", "61440MB", "-t", "0", "-a", "lsilogic"},
+			want: &vmx.VirtualMachine{
+				Memory:   2048,
+				Firmware: vmx.FirmwareEFI,
+				Disks:    []vmx.Disk{{Node: vmx.Node{Bus: vmx.BusNVMe}, FileName: "test.vmdk"}},
+				NICs:     []vmx.NIC{{ConnectionType: "nat", VirtualDev: "e1000e", StartConnected: true}},
+			},
+		},
+		{
+			guestOS:     "centos7-64",
+			wantCreated: []string{"-c", "-s", "20480MB", "-t", "0", "-a", "lsilogic"},
+			want: &vmx.VirtualMachine{
+				Memory:   2048,
+				Firmware: vmx.FirmwareBIOS,
+				Disks:    []vmx.Disk{{Node: vmx.Node{Bus: vmx.BusSCSI}, FileName: "test.vmdk"}},
+				NICs:     []vmx.NIC{{ConnectionType: "nat", VirtualDev: "vmxnet3", StartConnected: true}},
+			},
+			wantSCSI: "pvscsi",
+		},
+		{
+			guestOS:     "winxppro",
+			wantCreated: []string{"-c", "-s", "40960MB", "-t", "0", "-a", "ide"},
+			want: &vmx.VirtualMachine{
+				Memory:   512,
+				Firmware: vmx.FirmwareBIOS,
+				Disks:    []vmx.Disk{{Node: vmx.Node{Bus: vmx.BusIDE}, FileName: "test.vmdk"}},
+				NICs:     []vmx.NIC{{ConnectionType: "nat", VirtualDev: "e1000", StartConnected: true}},
+			},
+		},
+		{
+			// the guest OS which is not in the catalog.
+			guestOS:     "vmkernel7",
+			wantCreated: []string{"-c", "-s", "20000MB", "-t", "0", "-a", "lsilogic"},
+			want: &vmx.VirtualMachine{
+				Memory: DefaultMemory,
+				Disks:  []vmx.Disk{{Node: vmx.Node{Bus: vmx.BusSCSI}, FileName: "test.vmdk"}},
+				NICs:   []vmx.NIC{{ConnectionType: "nat", VirtualDev: "e1000", StartConnected: true}},
+			},
+			wantSCSI: "lsilogic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.guestOS, func(t *testing.T) {
+			dir := t.TempDir()
+			var created [][]string
+			spec := &VMSpec{
+				Product:      ProductWorkstation,
+				Dir:          dir,
+				Name:         "test",
+				GuestOS:      tt.guestOS,
+				Disks:        []DiskSpec{{}},
+				VDiskManager: fakeVDiskManager(&created, ""),
+			}
+
+			h, err := CreateVM(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantCreated := [][]string{append(tt.wantCreated, filepath.Join(dir, "test", "test.vmdk"))}
+			if !reflect.DeepEqual(created, wantCreated) {
+				t.Errorf("vmware-vdiskmanager = %q, want %q", created, wantCreated)
+			}
+
+			f, err := vmx.ReadFile(h.VMX())
+			if err != nil {
+				t.Fatal(err)
+			}
+			vm := f.VirtualMachine()
+			want := *tt.want
+			want.DisplayName, want.GuestOS, want.HWVersion = "test", tt.guestOS, DefaultHWVersion
+			want.CPU = vmx.CPU{Count: 1}
+			want.USB = vmx.USB{Present: true}
+			if !reflect.DeepEqual(vm, &want) {
+				t.Errorf("VirtualMachine = %+v, want %+v", vm, &want)
+			}
+			if got, _ := f.Get("scsi0.virtualDev"); got != tt.wantSCSI {
+				t.Errorf("scsi0.virtualDev = %q, want %q", got, tt.wantSCSI)
+			}
+			issues, err := vmxlint.ValidateFile(h.VMX())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(issues) > 0 {
+				t.Errorf("vmxlint issues = %v", issues)
+			}
+		})
+	}
+}
+
+func TestCreateVMCleanup(t *testing.T) {
+	dir := t.TempDir()
+	var created [][]string
+	spec := &VMSpec{
+		Product:      ProductWorkstation,
+		Dir:          dir,
+		Name:         "test",
+		Disks:        []DiskSpec{{}, {}},
+		VDiskManager: fakeVDiskManager(&created, "test-2.vmdk"),
+	}
+
+	if _, err := CreateVM(spec); err == nil {
+		t.Fatal("CreateVM error = nil, want error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("VM directory stat error = %v, want not exist", err)
+	}
+}
+
+func TestCreateVMXMLName(t *testing.T) {
+	dir := t.TempDir()
+	var created [][]string
+	spec := &VMSpec{Product: ProductWorkstation, Dir: dir, Name: "A&B <x>", VDiskManager: fakeVDiskManager(&created, "")}
+	h, err := CreateVM(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, spec.Name, spec.Name+".vmxf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vmxf struct {
+		VMXPathName string `xml:"VM>vmxPathName"`
+	}
+	if err := xml.Unmarshal(data, &vmxf); err != nil {
+		t.Fatalf("malformed .vmxf: %v\n%s", err, data)
+	}
+	if want := "A&B <x>.vmx"; vmxf.VMXPathName != want {
+		t.Fatalf("vmxPathName = %q, want %q", vmxf.VMXPathName, want)
+	}
+	if info, err := os.Stat(h.VMX()); err != nil || info.Mode().Perm() != 0644 {
+		t.Fatalf("Stat(.vmx) = %v, %v, want 0644 mode", info, err)
+	}
+}
+
+func TestCreateVMInvalidName(t *testing.T) {
+	for _, name := range []string{"", "a/b"} {
+		if _, err := CreateVM(&VMSpec{Dir: t.TempDir(), Name: name}); err == nil {
+			t.Errorf("CreateVM(%q) error = nil, want error", name)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package docs embeds the reference documents of VMware used by the tools.
+package docs
+
+import _ "embed" // for go:embed
+
+// VMXParameters is the content of vmx.md, the categorized list of the known .vmx parameters.
+//
+//go:embed vmx.md
+var VMXParameters string
+
+// VMXStrings is the content of vmware-vmx.strings, the strings(1) output of the vmware-vmx binary,
+// which includes the .vmx parameter names with the "%d" placeholders of the device numbers.
+//
+//go:embed vmware-vmx.strings
+var VMXStrings string
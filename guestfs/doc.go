@@ -0,0 +1,6 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package guestfs implements the io/fs file system of the guest OS of a VM.
+package guestfs
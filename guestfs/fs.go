@@ -0,0 +1,498 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package guestfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-vm/vmware"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// errNotDir is returned when a directory operation is called on a file.
+var errNotDir = errors.New("not a directory")
+
+// FS represents the file system of the guest OS rooted at a guest directory, which implements
+// fs.FS, fs.ReadDirFS, fs.ReadFileFS and fs.StatFS.
+//
+// The guest files are copied to a local cache directory on the first read, and the directory listings
+// and the file kinds are cached as well. Invalidate drops the cache if the guest files are changed outside of FS.
+type FS struct {
+	ctx      context.Context
+	vm       vmware.GuestManager
+	root     string
+	cacheDir string
+
+	mu    sync.Mutex
+	seq   int
+	kinds map[string]bool     // name -> whether it is a directory
+	files map[string]string   // name -> cached host file path
+	dirs  map[string][]string // name -> sorted entry names
+}
+
+var (
+	_ fs.FS         = (*FS)(nil)
+	_ fs.ReadDirFS  = (*FS)(nil)
+	_ fs.ReadFileFS = (*FS)(nil)
+	_ fs.StatFS     = (*FS)(nil)
+)
+
+// New returns the new FS of the guest OS of vm rooted at the root guest directory.
+//
+// The guest commands run with ctx. Close must be called to remove the local cache.
+func New(ctx context.Context, vm vmware.GuestManager, root string) (*FS, error) {
+	cacheDir, err := ioutil.TempDir("", "guestfs")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FS{
+		ctx:      ctx,
+		vm:       vm,
+		root:     root,
+		cacheDir: cacheDir,
+	}
+	f.Invalidate()
+
+	return f, nil
+}
+
+// Close removes the local cache of f.
+func (f *FS) Close() error {
+	return os.RemoveAll(f.cacheDir)
+}
+
+// Invalidate drops the cache of f.
+func (f *FS) Invalidate() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, host := range f.files {
+		_ = os.Remove(host)
+	}
+	f.kinds = make(map[string]bool)
+	f.files = make(map[string]string)
+	f.dirs = make(map[string][]string)
+}
+
+// guestPath returns the guest path of the name, which is joined to the root with the separator of the root.
+func (f *FS) guestPath(name string) string {
+	if name == "." {
+		return f.root
+	}
+
+	sep := "/"
+	if strings.Contains(f.root, `\`) {
+		sep = `\`
+	}
+
+	return strings.TrimSuffix(f.root, sep) + sep + strings.Replace(name, "/", sep, -1)
+}
+
+// pathError returns the *fs.PathError of the guest command error, which maps the vmrun errors to the fs errors.
+func pathError(op, name string, err error) error {
+	switch {
+	case errors.Is(err, vmrun.ErrFileNotFound):
+		err = fs.ErrNotExist
+	case errors.Is(err, vmrun.ErrFileExists):
+		err = fs.ErrExist
+	case errors.Is(err, vmrun.ErrPermissionDenied):
+		err = fs.ErrPermission
+	}
+
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// isDir reports whether the name is a directory, or returns fs.ErrNotExist if it does not exist.
+//
+// f.mu must be held.
+func (f *FS) isDir(name string) (bool, error) {
+	if isDir, ok := f.kinds[name]; ok {
+		return isDir, nil
+	}
+
+	guest := f.guestPath(name)
+	isDir, err := f.vm.DirectoryExistsInGuestContext(f.ctx, guest)
+	if err != nil {
+		return false, err
+	}
+	if !isDir {
+		exist, err := f.vm.FileExistsInGuestContext(f.ctx, guest)
+		if err != nil {
+			return false, err
+		}
+		if !exist {
+			return false, fs.ErrNotExist
+		}
+	}
+	f.kinds[name] = isDir
+
+	return isDir, nil
+}
+
+// fetch copies the name guest file to the local cache if it is not cached, and returns the host file path.
+//
+// f.mu must be held.
+func (f *FS) fetch(name string) (string, error) {
+	if host, ok := f.files[name]; ok {
+		return host, nil
+	}
+
+	f.seq++
+	host := filepath.Join(f.cacheDir, strconv.Itoa(f.seq))
+	if err := f.vm.CopyFileFromGuestToHostContext(f.ctx, f.guestPath(name), host); err != nil {
+		return "", err
+	}
+	f.files[name] = host
+
+	return host, nil
+}
+
+// list returns the sorted entry names of the name guest directory.
+//
+// f.mu must be held.
+func (f *FS) list(name string) ([]string, error) {
+	if names, ok := f.dirs[name]; ok {
+		return names, nil
+	}
+
+	isDir, err := f.isDir(name)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, errNotDir
+	}
+	names, err := f.vm.ListDirectoryInGuestContext(f.ctx, f.guestPath(name))
+	if err != nil {
+		return nil, err
+	}
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	f.dirs[name] = names
+
+	return names, nil
+}
+
+// stat returns the fs.FileInfo of the name.
+//
+// f.mu must be held.
+func (f *FS) stat(name string) (fs.FileInfo, error) {
+	isDir, err := f.isDir(name)
+	if err != nil {
+		return nil, err
+	}
+	if isDir {
+		return &fileInfo{name: path.Base(name), mode: fs.ModeDir | 0555}, nil
+	}
+
+	// the guest file is not copied until its size or content is read, since vmrun does not list the file sizes.
+	return &fileInfo{name: path.Base(name), mode: 0444, fsys: f, path: name}, nil
+}
+
+// size returns the size of the name guest file, which is copied to the local cache if it is not cached.
+//
+// f.mu must be held.
+func (f *FS) size(name string) (int64, error) {
+	host, err := f.fetch(name)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(host)
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+// Open implements a fs.FS interface.
+func (f *FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	if info.IsDir() {
+		return &dir{fsys: f, name: name, info: info}, nil
+	}
+
+	return &file{fsys: f, name: name, info: info}, nil
+}
+
+// Stat implements a fs.StatFS interface.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+
+	return info, nil
+}
+
+// ReadFile implements a fs.ReadFileFS interface.
+func (f *FS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	isDir, err := f.isDir(name)
+	if err == nil && isDir {
+		err = errors.New("is a directory")
+	}
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+	host, err := f.fetch(name)
+	if err != nil {
+		return nil, pathError("readfile", name, err)
+	}
+
+	return ioutil.ReadFile(host)
+}
+
+// ReadDir implements a fs.ReadDirFS interface.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, pathError("readdir", name, err)
+	}
+
+	return entries, nil
+}
+
+// readDir returns the sorted directory entries of the name guest directory.
+//
+// f.mu must be held.
+func (f *FS) readDir(name string) ([]fs.DirEntry, error) {
+	names, err := f.list(name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fs.DirEntry, 0, len(names))
+	for _, n := range names {
+		child := path.Join(name, n)
+		isDir, err := f.isDir(child)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &dirEntry{fsys: f, name: n, path: child, isDir: isDir})
+	}
+
+	return entries, nil
+}
+
+// fileInfo implements a fs.FileInfo interface of the guest file.
+//
+// The guest modification time is unknown from vmrun, so ModTime returns the zero time.
+type fileInfo struct {
+	name string
+	mode fs.FileMode
+	// fsys and path is the regular file which size is read from the local cache.
+	fsys *FS
+	path string
+}
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *fileInfo) ModTime() time.Time { return time.Time{} }
+func (i *fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *fileInfo) Sys() interface{}   { return nil }
+
+// Size returns the size of the regular file, which copies the guest file to the local cache on the first call.
+// It returns 0 for the directory, or if the guest file can not be copied.
+func (i *fileInfo) Size() int64 {
+	if i.fsys == nil {
+		return 0
+	}
+
+	i.fsys.mu.Lock()
+	defer i.fsys.mu.Unlock()
+
+	size, err := i.fsys.size(i.path)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// dirEntry implements a fs.DirEntry interface of the guest file.
+type dirEntry struct {
+	fsys  *FS
+	name  string
+	path  string
+	isDir bool
+}
+
+func (e *dirEntry) Name() string { return e.name }
+func (e *dirEntry) IsDir() bool  { return e.isDir }
+
+func (e *dirEntry) Type() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
+
+// Info returns the fs.FileInfo of e.
+func (e *dirEntry) Info() (fs.FileInfo, error) {
+	return e.fsys.Stat(e.path)
+}
+
+// file implements a fs.File interface of the guest file, which reads the local cache.
+//
+// The guest file is copied to the local cache on the first read, so opening the file is cheap.
+type file struct {
+	fsys   *FS
+	name   string
+	info   fs.FileInfo
+	host   *os.File
+	closed bool
+}
+
+// open opens the local cache of the guest file, which is copied if it is not cached.
+func (f *file) open(op string) error {
+	if f.closed {
+		return &fs.PathError{Op: op, Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.host != nil {
+		return nil
+	}
+
+	f.fsys.mu.Lock()
+	host, err := f.fsys.fetch(f.name)
+	f.fsys.mu.Unlock()
+	if err != nil {
+		return pathError(op, f.name, err)
+	}
+	r, err := os.Open(host)
+	if err != nil {
+		return pathError(op, f.name, err)
+	}
+	f.host = r
+
+	return nil
+}
+
+// Stat returns the fs.FileInfo of the guest file instead of the local cache.
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *file) Read(p []byte) (int, error) {
+	if err := f.open("read"); err != nil {
+		return 0, err
+	}
+	return f.host.Read(p)
+}
+
+func (f *file) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.open("read"); err != nil {
+		return 0, err
+	}
+	return f.host.ReadAt(p, off)
+}
+
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	if err := f.open("seek"); err != nil {
+		return 0, err
+	}
+	return f.host.Seek(offset, whence)
+}
+
+func (f *file) Close() error {
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	if f.host == nil {
+		return nil
+	}
+	return f.host.Close()
+}
+
+// dir implements a fs.ReadDirFile interface of the guest directory.
+type dir struct {
+	fsys    *FS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+	read    bool
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) Close() error { return nil }
+
+// ReadDir implements a fs.ReadDirFile interface.
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		d.fsys.mu.Lock()
+		entries, err := d.fsys.readDir(d.name)
+		d.fsys.mu.Unlock()
+		if err != nil {
+			return nil, pathError("readdir", d.name, err)
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+
+	return rest[:n], nil
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package guestfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-vm/vmware"
+	"github.com/go-vm/vmware/vmtest"
+)
+
+// newTestVM returns the powered on vmtest.Fake which guest has the files, which keys are the guest paths.
+func newTestVM(t *testing.T, files map[string]string) *vmtest.Fake {
+	t.Helper()
+	ctx := context.Background()
+
+	vm := vmtest.NewFake()
+	if err := vm.StartContext(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	hostDir := t.TempDir()
+	for name, data := range files {
+		for _, dir := range parents(path.Dir(name)) {
+			if exist, _ := vm.DirectoryExistsInGuestContext(ctx, dir); !exist {
+				if err := vm.CreateDirectoryInGuestContext(ctx, dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		src := filepath.Join(hostDir, "src")
+		if err := ioutil.WriteFile(src, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.CopyFileFromHostToGuestContext(ctx, src, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return vm
+}
+
+// parents returns dir and its ancestors from the top, excluding the root.
+func parents(dir string) []string {
+	if dir == "/" {
+		return nil
+	}
+	return append(parents(path.Dir(dir)), dir)
+}
+
+func newTestFS(t *testing.T, files map[string]string) *FS {
+	t.Helper()
+	fsys, err := New(context.Background(), newTestVM(t, files), "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fsys.Close() })
+
+	return fsys
+}
+
+func TestFS(t *testing.T) {
+	fsys := newTestFS(t, map[string]string{
+		"/src/go.mod":            "module example.com/m\n",
+		"/src/main.go":           "package main\n",
+		"/src/cmd/tool/main.go":  "package main\n",
+		"/src/templates/a.tmpl":  "{{.A}}\n",
+		"/src/templates/b.tmpl":  "{{.B}}\n",
+		"/outside/ignored.txt":   "ignored\n",
+		"/src/templates/sub/c.x": "c\n",
+	})
+
+	if err := fstest.TestFS(fsys, "go.mod", "main.go", "cmd/tool/main.go", "templates/a.tmpl", "templates/sub/c.x"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFSGlob(t *testing.T) {
+	fsys := newTestFS(t, map[string]string{
+		"/src/templates/a.tmpl": "{{.A}}\n",
+		"/src/templates/b.tmpl": "{{.B}}\n",
+		"/src/templates/c.txt":  "c\n",
+	})
+
+	got, err := fs.Glob(fsys, "templates/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"templates/a.tmpl", "templates/b.tmpl"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Glob = %q, want %q", got, want)
+	}
+}
+
+func TestFSNotExist(t *testing.T) {
+	fsys := newTestFS(t, map[string]string{"/src/a.txt": "a\n"})
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{name: "Open", fn: func() error { _, err := fsys.Open("missing.txt"); return err }},
+		{name: "Stat", fn: func() error { _, err := fsys.Stat("dir/missing.txt"); return err }},
+		{name: "ReadFile", fn: func() error { _, err := fsys.ReadFile("missing.txt"); return err }},
+		{name: "ReadDir", fn: func() error { _, err := fsys.ReadDir("missing"); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) || !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("%s error = %v, want *fs.PathError of fs.ErrNotExist", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestFSCache(t *testing.T) {
+	ctx := context.Background()
+	vm := newTestVM(t, map[string]string{"/src/a.txt": "a\n"})
+	fsys, err := New(ctx, vm, "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	if _, err := fsys.ReadFile("a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.DeleteFileInGuestContext(ctx, "/src/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fsys.ReadFile("a.txt"); err != nil || string(data) != "a\n" {
+		t.Fatalf("ReadFile from cache = %q, %v, want %q", data, err, "a\n")
+	}
+	fsys.Invalidate()
+	if _, err := fsys.ReadFile("a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("ReadFile after Invalidate error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
+
+// copyCounter counts the guest files copied to the host.
+type copyCounter struct {
+	vmware.GuestManager
+	copies int
+}
+
+func (c *copyCounter) CopyFileFromGuestToHostContext(ctx context.Context, guestPath, hostPath string) error {
+	c.copies++
+	return c.GuestManager.CopyFileFromGuestToHostContext(ctx, guestPath, hostPath)
+}
+
+func TestFSLazyCopy(t *testing.T) {
+	vm := &copyCounter{GuestManager: newTestVM(t, map[string]string{
+		"/src/a.txt":     "a\n",
+		"/src/sub/b.txt": "bb\n",
+	})}
+	fsys, err := New(context.Background(), vm, "/src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fsys.Close()
+
+	// walking and globbing the tree and opening a file do not copy the guest files.
+	var names []string
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, err := d.Info(); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{".", "a.txt", "sub", "sub/b.txt"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("WalkDir = %q, want %q", names, want)
+	}
+	if _, err := fs.Glob(fsys, "sub/*.txt"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fsys.Stat("sub/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if vm.copies != 0 {
+		t.Fatalf("copies = %d, want 0", vm.copies)
+	}
+
+	// the size and the content are read from the copy.
+	if info.Size() != 3 {
+		t.Fatalf("Size = %d, want 3", info.Size())
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil || string(data) != "a\n" {
+		t.Fatalf("Read = %q, %v, want %q", data, err, "a\n")
+	}
+	if vm.copies != 2 {
+		t.Fatalf("copies = %d, want 2", vm.copies)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package guestfs
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// errNotEmpty is returned when Remove is called on a non-empty directory.
+var errNotEmpty = errors.New("directory not empty")
+
+// validWritePath reports whether name is a valid path to modify, which must not be the root.
+func validWritePath(name string) bool {
+	return fs.ValidPath(name) && name != "."
+}
+
+// forget drops the cache of name, its descendants and the listing of its parent directory.
+//
+// f.mu must be held.
+func (f *FS) forget(name string) {
+	prefix := name + "/"
+	for n := range f.kinds {
+		if n == name || strings.HasPrefix(n, prefix) {
+			delete(f.kinds, n)
+		}
+	}
+	for n, host := range f.files {
+		if n == name || strings.HasPrefix(n, prefix) {
+			_ = os.Remove(host)
+			delete(f.files, n)
+		}
+	}
+	for n := range f.dirs {
+		if n == name || strings.HasPrefix(n, prefix) {
+			delete(f.dirs, n)
+		}
+	}
+	delete(f.dirs, path.Dir(name))
+}
+
+// WriteFile writes data to the name guest file, creating it if necessary.
+//
+// The perm is ignored because vmrun can not set the permission of the guest file.
+func (f *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !validWritePath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if isDir, err := f.isDir(name); err == nil && isDir {
+		return &fs.PathError{Op: "write", Path: name, Err: errors.New("is a directory")}
+	}
+
+	f.seq++
+	host := filepath.Join(f.cacheDir, strconv.Itoa(f.seq))
+	if err := ioutil.WriteFile(host, data, 0600); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	defer os.Remove(host)
+
+	f.forget(name)
+	if err := f.vm.CopyFileFromHostToGuestContext(f.ctx, host, f.guestPath(name)); err != nil {
+		return pathError("write", name, err)
+	}
+
+	return nil
+}
+
+// Mkdir creates the name guest directory. Its parent must exist.
+//
+// The perm is ignored because vmrun can not set the permission of the guest directory.
+func (f *FS) Mkdir(name string, perm fs.FileMode) error {
+	if !validWritePath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.mkdir(name); err != nil {
+		return pathError("mkdir", name, err)
+	}
+
+	return nil
+}
+
+// mkdir creates the name guest directory.
+//
+// f.mu must be held.
+func (f *FS) mkdir(name string) error {
+	f.forget(name)
+	if err := f.vm.CreateDirectoryInGuestContext(f.ctx, f.guestPath(name)); err != nil {
+		return err
+	}
+	f.kinds[name] = true
+
+	return nil
+}
+
+// MkdirAll creates the name guest directory and its parents if they do not exist.
+//
+// The perm is ignored because vmrun can not set the permission of the guest directory.
+func (f *FS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	elems := strings.Split(name, "/")
+	for i := range elems {
+		p := path.Join(elems[:i+1]...)
+
+		isDir, err := f.isDir(p)
+		switch {
+		case err == nil && isDir:
+			continue
+		case err == nil:
+			return &fs.PathError{Op: "mkdir", Path: p, Err: errNotDir}
+		case !errors.Is(err, fs.ErrNotExist):
+			return pathError("mkdir", p, err)
+		}
+
+		if err := f.mkdir(p); err != nil && !errors.Is(err, vmrun.ErrFileExists) {
+			return pathError("mkdir", p, err)
+		}
+	}
+
+	return nil
+}
+
+// Remove removes the name guest file or empty directory.
+func (f *FS) Remove(name string) error {
+	if !validWritePath(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	isDir, err := f.isDir(name)
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	if !isDir {
+		f.forget(name)
+		if err := f.vm.DeleteFileInGuestContext(f.ctx, f.guestPath(name)); err != nil {
+			return pathError("remove", name, err)
+		}
+		return nil
+	}
+
+	names, err := f.list(name)
+	if err != nil {
+		return pathError("remove", name, err)
+	}
+	if len(names) > 0 {
+		return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+	}
+	f.forget(name)
+	if err := f.vm.DeleteDirectoryInGuestContext(f.ctx, f.guestPath(name)); err != nil {
+		return pathError("remove", name, err)
+	}
+
+	return nil
+}
+
+// RemoveAll removes the name guest file or directory and any children it contains.
+//
+// It returns nil if name does not exist.
+func (f *FS) RemoveAll(name string) error {
+	if !validWritePath(name) {
+		return &fs.PathError{Op: "removeall", Path: name, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.removeAll(name); err != nil {
+		return pathError("removeall", name, err)
+	}
+
+	return nil
+}
+
+// removeAll removes the name guest file or directory recursively.
+//
+// f.mu must be held.
+func (f *FS) removeAll(name string) error {
+	isDir, err := f.isDir(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	guest := f.guestPath(name)
+	if !isDir {
+		f.forget(name)
+		if err := f.vm.DeleteFileInGuestContext(f.ctx, guest); err != nil && !errors.Is(err, vmrun.ErrFileNotFound) {
+			return err
+		}
+		return nil
+	}
+
+	// some guests refuse to delete the non-empty directory, so the children are removed first in that case.
+	names, err := f.list(name)
+	if err != nil {
+		return err
+	}
+	f.forget(name)
+	err = f.vm.DeleteDirectoryInGuestContext(f.ctx, guest)
+	if !errors.Is(err, vmrun.ErrDirectoryNotEmpty) {
+		return err
+	}
+	for _, n := range names {
+		if err := f.removeAll(path.Join(name, n)); err != nil {
+			return err
+		}
+	}
+
+	return f.vm.DeleteDirectoryInGuestContext(f.ctx, guest)
+}
+
+// Rename renames the oldname guest file to newname.
+func (f *FS) Rename(oldname, newname string) error {
+	if !validWritePath(oldname) {
+		return &fs.PathError{Op: "rename", Path: oldname, Err: fs.ErrInvalid}
+	}
+	if !validWritePath(newname) {
+		return &fs.PathError{Op: "rename", Path: newname, Err: fs.ErrInvalid}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.isDir(oldname); err != nil {
+		return pathError("rename", oldname, err)
+	}
+
+	f.forget(oldname)
+	f.forget(newname)
+	if err := f.vm.RenameFileInGuestContext(f.ctx, f.guestPath(oldname), f.guestPath(newname)); err != nil {
+		return pathError("rename", oldname, err)
+	}
+
+	return nil
+}
+
+// CreateTemp creates a new temporary file in the temporary directory of the guest OS, and returns its guest path.
+//
+// The temporary file is usually outside of the root of f, so the returned path is not a name of f.
+func (f *FS) CreateTemp() (string, error) {
+	name, err := f.vm.CreateTempfileInGuestContext(f.ctx)
+	if err != nil {
+		return "", pathError("createtemp", "", err)
+	}
+
+	return name, nil
+}
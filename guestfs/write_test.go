@@ -0,0 +1,128 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package guestfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tree returns all of the file and directory names of fsys.
+func tree(t *testing.T, fsys fs.FS) []string {
+	t.Helper()
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			p += "/"
+		}
+		names = append(names, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return names
+}
+
+func TestFSWrite(t *testing.T) {
+	fsys := newTestFS(t, map[string]string{"/src/a.txt": "a\n"})
+
+	if err := fsys.MkdirAll("b/c", 0755); err != nil {
+		t.Fatalf("MkdirAll error = %v", err)
+	}
+	if err := fsys.MkdirAll("b/c", 0755); err != nil {
+		t.Fatalf("MkdirAll of the existing directory error = %v", err)
+	}
+	if err := fsys.WriteFile("b/c/d.txt", []byte("d\n"), 0644); err != nil {
+		t.Fatalf("WriteFile error = %v", err)
+	}
+	if err := fsys.WriteFile("a.txt", []byte("new a\n"), 0644); err != nil {
+		t.Fatalf("WriteFile of the existing file error = %v", err)
+	}
+	if err := fsys.Rename("b/c/d.txt", "b/e.txt"); err != nil {
+		t.Fatalf("Rename error = %v", err)
+	}
+	if err := fsys.Mkdir("f", 0755); err != nil {
+		t.Fatalf("Mkdir error = %v", err)
+	}
+
+	if got, want := tree(t, fsys), []string{"./", "a.txt", "b/", "b/c/", "b/e.txt", "f/"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tree = %q, want %q", got, want)
+	}
+	if data, err := fs.ReadFile(fsys, "a.txt"); err != nil || string(data) != "new a\n" {
+		t.Fatalf("ReadFile = %q, %v, want %q", data, err, "new a\n")
+	}
+
+	if err := fsys.Remove("b/e.txt"); err != nil {
+		t.Fatalf("Remove error = %v", err)
+	}
+	if err := fsys.Remove("f"); err != nil {
+		t.Fatalf("Remove of the empty directory error = %v", err)
+	}
+	if err := fsys.RemoveAll("b"); err != nil {
+		t.Fatalf("RemoveAll error = %v", err)
+	}
+	if err := fsys.RemoveAll("b"); err != nil {
+		t.Fatalf("RemoveAll of the missing directory error = %v", err)
+	}
+
+	if got, want := tree(t, fsys), []string{"./", "a.txt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tree = %q, want %q", got, want)
+	}
+}
+
+func TestFSWriteError(t *testing.T) {
+	fsys := newTestFS(t, map[string]string{
+		"/src/a.txt":   "a\n",
+		"/src/b/c.txt": "c\n",
+	})
+
+	tests := []struct {
+		name    string
+		fn      func() error
+		wantErr error
+	}{
+		{name: "Mkdir existing", fn: func() error { return fsys.Mkdir("b", 0755) }, wantErr: fs.ErrExist},
+		{name: "Mkdir missing parent", fn: func() error { return fsys.Mkdir("x/y", 0755) }, wantErr: fs.ErrNotExist},
+		{name: "MkdirAll under file", fn: func() error { return fsys.MkdirAll("a.txt/y", 0755) }, wantErr: errNotDir},
+		{name: "WriteFile missing parent", fn: func() error { return fsys.WriteFile("x/y.txt", nil, 0644) }, wantErr: fs.ErrNotExist},
+		{name: "Remove missing", fn: func() error { return fsys.Remove("x.txt") }, wantErr: fs.ErrNotExist},
+		{name: "Remove non-empty", fn: func() error { return fsys.Remove("b") }, wantErr: errNotEmpty},
+		{name: "Rename missing", fn: func() error { return fsys.Rename("x.txt", "y.txt") }, wantErr: fs.ErrNotExist},
+		{name: "RemoveAll root", fn: func() error { return fsys.RemoveAll(".") }, wantErr: fs.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn()
+			var pathErr *fs.PathError
+			if !errors.As(err, &pathErr) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want *fs.PathError of %v", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), "vmrun") {
+				t.Fatalf("error = %v, want no raw vmrun message", err)
+			}
+		})
+	}
+}
+
+func TestFSCreateTemp(t *testing.T) {
+	fsys := newTestFS(t, nil)
+
+	name, err := fsys.CreateTemp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exist, err := fsys.vm.FileExistsInGuestContext(context.Background(), name); err != nil || !exist {
+		t.Fatalf("FileExistsInGuestContext(%q) = %v, %v, want true", name, exist, err)
+	}
+}
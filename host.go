@@ -0,0 +1,686 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// Product represents a VMware product which runs the VM.
+type Product int
+
+const (
+	// ProductFusion is a VMware Fusion.
+	ProductFusion Product = iota
+	// ProductWorkstation is a VMware Workstation.
+	ProductWorkstation
+	// ProductPlayer is a VMware Workstation Player.
+	ProductPlayer
+)
+
+// App returns the vmrun host type of p, which is passed to the -T flag.
+func (p Product) App() string {
+	switch p {
+	case ProductFusion:
+		return "fusion"
+	case ProductWorkstation:
+		return "ws"
+	case ProductPlayer:
+		return "player"
+	default:
+		return ""
+	}
+}
+
+// String implements a fmt.Stringer interface.
+func (p Product) String() string {
+	switch p {
+	case ProductFusion:
+		return "VMware Fusion"
+	case ProductWorkstation:
+		return "VMware Workstation"
+	case ProductPlayer:
+		return "VMware Workstation Player"
+	default:
+		return ""
+	}
+}
+
+// unsupportedOps is the vmrun commands which are not supported by each product.
+var unsupportedOps = map[Product]map[string]bool{
+	ProductPlayer: {
+		"listSnapshots":    true,
+		"snapshot":         true,
+		"deleteSnapshot":   true,
+		"revertToSnapshot": true,
+		"clone":            true,
+	},
+}
+
+// ErrUnsupported is returned when the operation is not supported by the product.
+var ErrUnsupported = errors.New("vmware: operation is not supported")
+
+// UnsupportedError represents an operation which is not supported by the product.
+type UnsupportedError struct {
+	Product Product
+	Op      string
+}
+
+// Error implements a error interface.
+func (e *UnsupportedError) Error() string {
+	return "vmware: " + e.Op + " is not supported by " + e.Product.String()
+}
+
+// Is reports whether the target is ErrUnsupported or vmrun.ErrNotSupported.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == ErrUnsupported || target == vmrun.ErrNotSupported
+}
+
+// Host represents a VM on the VMware product.
+type Host struct {
+	product  Product
+	vmx      string
+	username string
+	password string
+	auth     *vmrun.Auth
+	client   *vmrun.Client
+}
+
+// NewHost return the new Host of the product.
+func NewHost(product Product, vmx, username, password string) *Host {
+	return &Host{
+		product:  product,
+		vmx:      vmx,
+		username: username,
+		password: password,
+		client:   vmrun.DefaultClient,
+	}
+}
+
+// Product returns the product of h.
+func (h *Host) Product() Product {
+	return h.product
+}
+
+// VMX returns the .vmx file path of h.
+func (h *Host) VMX() string {
+	return h.vmx
+}
+
+// SetClient sets the vmrun client used by h.
+//
+// The authentication flags set by SetAuth take precedence over the Auth of c.
+func (h *Host) SetClient(c *vmrun.Client) {
+	if h.auth != nil {
+		c = c.WithAuth(h.auth)
+	}
+	h.client = c
+}
+
+// SetAuth sets the authentication flags passed to all commands of h,
+// such as the remote host credentials or the password of the encrypted VM.
+//
+// The guest OS credentials given to NewHost take precedence over the GuestUser and GuestPassword of auth.
+func (h *Host) SetAuth(auth *vmrun.Auth) {
+	h.auth = auth
+	h.client = h.client.WithAuth(auth)
+}
+
+// supports returns the *UnsupportedError if the op vmrun command is not supported by the product of h.
+func (h *Host) supports(op string) error {
+	if unsupportedOps[h.product][op] {
+		return &UnsupportedError{Product: h.product, Op: op}
+	}
+
+	return nil
+}
+
+// Start start a VM or Team.
+func (h *Host) Start(gui bool) error {
+	return h.StartContext(context.Background(), gui)
+}
+
+// StartContext is like Start but includes a context.
+func (h *Host) StartContext(ctx context.Context, gui bool) error {
+	return h.client.Start(ctx, h.product.App(), h.vmx, gui)
+}
+
+// ShutDown wrap of stop command with hard.
+func (h *Host) ShutDown() error {
+	return h.ShutDownContext(context.Background())
+}
+
+// ShutDownContext is like ShutDown but includes a context.
+func (h *Host) ShutDownContext(ctx context.Context) error {
+	return h.client.Stop(ctx, h.product.App(), h.vmx, true)
+}
+
+// Halt wrap of stop command with soft.
+func (h *Host) Halt() error {
+	return h.HaltContext(context.Background())
+}
+
+// HaltContext is like Halt but includes a context.
+func (h *Host) HaltContext(ctx context.Context) error {
+	return h.client.Stop(ctx, h.product.App(), h.vmx, false)
+}
+
+// Reset reset a VM or Team.
+func (h *Host) Reset() error {
+	return h.ResetContext(context.Background())
+}
+
+// ResetContext is like Reset but includes a context.
+func (h *Host) ResetContext(ctx context.Context) error {
+	return h.client.Reset(ctx, h.product.App(), h.vmx, true)
+}
+
+// Restart restart a VM uses wrap of reset command with soft.
+func (h *Host) Restart() error {
+	return h.RestartContext(context.Background())
+}
+
+// RestartContext is like Restart but includes a context.
+func (h *Host) RestartContext(ctx context.Context) error {
+	return h.client.Reset(ctx, h.product.App(), h.vmx, false)
+}
+
+// Suspend Suspend a VM or Team.
+func (h *Host) Suspend(hard bool) error {
+	return h.SuspendContext(context.Background(), hard)
+}
+
+// SuspendContext is like Suspend but includes a context.
+func (h *Host) SuspendContext(ctx context.Context, hard bool) error {
+	return h.client.Suspend(ctx, h.product.App(), h.vmx, hard)
+}
+
+// Pause pause a VM.
+func (h *Host) Pause() error {
+	return h.PauseContext(context.Background())
+}
+
+// PauseContext is like Pause but includes a context.
+func (h *Host) PauseContext(ctx context.Context) error {
+	return h.client.Pause(ctx, h.product.App(), h.vmx)
+}
+
+// Unpause unpause a VM.
+func (h *Host) Unpause() error {
+	return h.UnpauseContext(context.Background())
+}
+
+// UnpauseContext is like Unpause but includes a context.
+func (h *Host) UnpauseContext(ctx context.Context) error {
+	return h.client.Unpause(ctx, h.product.App(), h.vmx)
+}
+
+// ListSnapshots list all snapshots in a VM.
+func (h *Host) ListSnapshots() ([]string, int, error) {
+	return h.ListSnapshotsContext(context.Background())
+}
+
+// ListSnapshotsContext is like ListSnapshots but includes a context.
+func (h *Host) ListSnapshotsContext(ctx context.Context) ([]string, int, error) {
+	if err := h.supports("listSnapshots"); err != nil {
+		return nil, 0, err
+	}
+
+	return h.client.ListSnapshots(ctx, h.product.App(), h.vmx)
+}
+
+// SnapshotTree list all snapshots in a VM as the parent/child tree.
+func (h *Host) SnapshotTree() (*vmrun.SnapshotTree, error) {
+	return h.SnapshotTreeContext(context.Background())
+}
+
+// SnapshotTreeContext is like SnapshotTree but includes a context.
+func (h *Host) SnapshotTreeContext(ctx context.Context) (*vmrun.SnapshotTree, error) {
+	if err := h.supports("listSnapshots"); err != nil {
+		return nil, err
+	}
+
+	return h.client.ListSnapshotTree(ctx, h.product.App(), h.vmx)
+}
+
+// Snapshot create a snapshot of a VM.
+func (h *Host) Snapshot(snapshotName string) error {
+	return h.SnapshotContext(context.Background(), snapshotName)
+}
+
+// SnapshotContext is like Snapshot but includes a context.
+func (h *Host) SnapshotContext(ctx context.Context, snapshotName string) error {
+	if err := h.supports("snapshot"); err != nil {
+		return err
+	}
+
+	return h.client.Snapshot(ctx, h.product.App(), h.vmx, snapshotName)
+}
+
+// CreateSnapshot create a snapshot of a VM with the unique name, and reports whether the snapshot is created.
+func (h *Host) CreateSnapshot(snapshotName string, opts *vmrun.SnapshotOptions) (bool, error) {
+	return h.CreateSnapshotContext(context.Background(), snapshotName, opts)
+}
+
+// CreateSnapshotContext is like CreateSnapshot but includes a context.
+func (h *Host) CreateSnapshotContext(ctx context.Context, snapshotName string, opts *vmrun.SnapshotOptions) (bool, error) {
+	if err := h.supports("snapshot"); err != nil {
+		return false, err
+	}
+
+	return h.client.CreateSnapshot(ctx, h.product.App(), h.vmx, snapshotName, opts)
+}
+
+// DeleteSnapshot remove a snapshot from a VM.
+func (h *Host) DeleteSnapshot(snapshotName string, deleteChildren bool) error {
+	return h.DeleteSnapshotContext(context.Background(), snapshotName, deleteChildren)
+}
+
+// DeleteSnapshotContext is like DeleteSnapshot but includes a context.
+func (h *Host) DeleteSnapshotContext(ctx context.Context, snapshotName string, deleteChildren bool) error {
+	if err := h.supports("deleteSnapshot"); err != nil {
+		return err
+	}
+
+	return h.client.DeleteSnapshot(ctx, h.product.App(), h.vmx, snapshotName, deleteChildren)
+}
+
+// RevertToSnapshot set VM state to a snapshot.
+func (h *Host) RevertToSnapshot(snapshotName string) error {
+	return h.RevertToSnapshotContext(context.Background(), snapshotName)
+}
+
+// RevertToSnapshotContext is like RevertToSnapshot but includes a context.
+func (h *Host) RevertToSnapshotContext(ctx context.Context, snapshotName string) error {
+	if err := h.supports("revertToSnapshot"); err != nil {
+		return err
+	}
+
+	return h.client.RevertToSnapshot(ctx, h.product.App(), h.vmx, snapshotName)
+}
+
+// RunProgramInGuest run a program in Guest OS.
+func (h *Host) RunProgramInGuest(config vmrun.RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
+	return h.RunProgramInGuestContext(context.Background(), config, cmdPath, cmdArgs...)
+}
+
+// RunProgramInGuestContext is like RunProgramInGuest but includes a context.
+func (h *Host) RunProgramInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
+	return h.client.RunProgramInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, config, cmdPath, cmdArgs...)
+}
+
+// FileExistsInGuest check if a file exists in Guest OS.
+func (h *Host) FileExistsInGuest(filename string) bool {
+	exist, _ := h.FileExistsInGuestContext(context.Background(), filename)
+	return exist
+}
+
+// FileExistsInGuestContext is like FileExistsInGuest but includes a context.
+func (h *Host) FileExistsInGuestContext(ctx context.Context, filename string) (bool, error) {
+	return h.client.FileExistsInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, filename)
+}
+
+// DirectoryExistsInGuest check if a directory exists in Guest OS.
+func (h *Host) DirectoryExistsInGuest(dir string) bool {
+	exist, _ := h.DirectoryExistsInGuestContext(context.Background(), dir)
+	return exist
+}
+
+// DirectoryExistsInGuestContext is like DirectoryExistsInGuest but includes a context.
+func (h *Host) DirectoryExistsInGuestContext(ctx context.Context, dir string) (bool, error) {
+	return h.client.DirectoryExistsInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// SetSharedFolderState modify a Host-Guest shared folder.
+func (h *Host) SetSharedFolderState(shareName, hostPath string, writable bool) error {
+	return h.SetSharedFolderStateContext(context.Background(), shareName, hostPath, writable)
+}
+
+// SetSharedFolderStateContext is like SetSharedFolderState but includes a context.
+func (h *Host) SetSharedFolderStateContext(ctx context.Context, shareName, hostPath string, writable bool) error {
+	return h.client.SetSharedFolderState(ctx, h.product.App(), h.vmx, shareName, hostPath, writable)
+}
+
+// AddSharedFolder add a Host-Guest shared folder.
+func (h *Host) AddSharedFolder(shareName, newHostPath string) error {
+	return h.AddSharedFolderContext(context.Background(), shareName, newHostPath)
+}
+
+// AddSharedFolderContext is like AddSharedFolder but includes a context.
+func (h *Host) AddSharedFolderContext(ctx context.Context, shareName, newHostPath string) error {
+	return h.client.AddSharedFolder(ctx, h.product.App(), h.vmx, shareName, newHostPath)
+}
+
+// RemoveSharedFolder remove a Host-Guest shared folder.
+func (h *Host) RemoveSharedFolder(shareName string) error {
+	return h.RemoveSharedFolderContext(context.Background(), shareName)
+}
+
+// RemoveSharedFolderContext is like RemoveSharedFolder but includes a context.
+func (h *Host) RemoveSharedFolderContext(ctx context.Context, shareName string) error {
+	return h.client.RemoveSharedFolder(ctx, h.product.App(), h.vmx, shareName)
+}
+
+// EnableSharedFolders enable shared folders in Guest.
+func (h *Host) EnableSharedFolders(runtime bool) error {
+	return h.EnableSharedFoldersContext(context.Background(), runtime)
+}
+
+// EnableSharedFoldersContext is like EnableSharedFolders but includes a context.
+func (h *Host) EnableSharedFoldersContext(ctx context.Context, runtime bool) error {
+	return h.client.EnableSharedFolders(ctx, h.product.App(), h.vmx, runtime)
+}
+
+// DisableSharedFolders disable shared folders in Guest.
+func (h *Host) DisableSharedFolders(runtime bool) error {
+	return h.DisableSharedFoldersContext(context.Background(), runtime)
+}
+
+// DisableSharedFoldersContext is like DisableSharedFolders but includes a context.
+func (h *Host) DisableSharedFoldersContext(ctx context.Context, runtime bool) error {
+	return h.client.DisableSharedFolders(ctx, h.product.App(), h.vmx, runtime)
+}
+
+// ListProcessesInGuest List running processes in Guest OS.
+func (h *Host) ListProcessesInGuest() ([]vmrun.ListProcessesInGuestInfo, error) {
+	return h.ListProcessesInGuestContext(context.Background())
+}
+
+// ListProcessesInGuestContext is like ListProcessesInGuest but includes a context.
+func (h *Host) ListProcessesInGuestContext(ctx context.Context) ([]vmrun.ListProcessesInGuestInfo, error) {
+	return h.client.ListProcessesInGuest(ctx, h.product.App(), h.vmx, h.username, h.password)
+}
+
+// KillProcessInGuest kill a process in Guest OS.
+func (h *Host) KillProcessInGuest(pid int) error {
+	return h.KillProcessInGuestContext(context.Background(), pid)
+}
+
+// KillProcessInGuestContext is like KillProcessInGuest but includes a context.
+func (h *Host) KillProcessInGuestContext(ctx context.Context, pid int) error {
+	return h.client.KillProcessInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, pid)
+}
+
+// RunScriptInGuest run a script in Guest OS.
+func (h *Host) RunScriptInGuest(config vmrun.RunInGuestConfig, interpreter, script string) error {
+	return h.RunScriptInGuestContext(context.Background(), config, interpreter, script)
+}
+
+// RunScriptInGuestContext is like RunScriptInGuest but includes a context.
+func (h *Host) RunScriptInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, interpreter, script string) error {
+	return h.client.RunScriptInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, config, interpreter, script)
+}
+
+// ExecInGuest run a command line with the shell in Guest OS, and returns its exit code, stdout and stderr.
+func (h *Host) ExecInGuest(shell vmrun.Shell, command string) (*vmrun.ExecResult, error) {
+	return h.ExecInGuestContext(context.Background(), shell, command)
+}
+
+// ExecInGuestContext is like ExecInGuest but includes a context.
+func (h *Host) ExecInGuestContext(ctx context.Context, shell vmrun.Shell, command string) (*vmrun.ExecResult, error) {
+	return h.client.ExecInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, shell, command)
+}
+
+// StreamInGuest run a command line with the shell in Guest OS, writes its stdout and stderr to the writers of opts
+// while it runs, and returns its exit code.
+func (h *Host) StreamInGuest(shell vmrun.Shell, command string, opts *vmrun.StreamOptions) (int, error) {
+	return h.StreamInGuestContext(context.Background(), shell, command, opts)
+}
+
+// StreamInGuestContext is like StreamInGuest but includes a context.
+func (h *Host) StreamInGuestContext(ctx context.Context, shell vmrun.Shell, command string, opts *vmrun.StreamOptions) (int, error) {
+	return h.client.StreamInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, shell, command, opts)
+}
+
+// DeleteFileInGuest delete a file in Guest OS.
+func (h *Host) DeleteFileInGuest(filename string) error {
+	return h.DeleteFileInGuestContext(context.Background(), filename)
+}
+
+// DeleteFileInGuestContext is like DeleteFileInGuest but includes a context.
+func (h *Host) DeleteFileInGuestContext(ctx context.Context, filename string) error {
+	return h.client.DeleteFileInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, filename)
+}
+
+// CreateDirectoryInGuest create a directory in Guest OS.
+func (h *Host) CreateDirectoryInGuest(dir string) error {
+	return h.CreateDirectoryInGuestContext(context.Background(), dir)
+}
+
+// CreateDirectoryInGuestContext is like CreateDirectoryInGuest but includes a context.
+func (h *Host) CreateDirectoryInGuestContext(ctx context.Context, dir string) error {
+	return h.client.CreateDirectoryInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// DeleteDirectoryInGuest delete a directory and its contents in Guest OS.
+func (h *Host) DeleteDirectoryInGuest(dir string) error {
+	return h.DeleteDirectoryInGuestContext(context.Background(), dir)
+}
+
+// DeleteDirectoryInGuestContext is like DeleteDirectoryInGuest but includes a context.
+func (h *Host) DeleteDirectoryInGuestContext(ctx context.Context, dir string) error {
+	return h.client.DeleteDirectoryInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// MkdirAllInGuest create a directory in Guest OS, along with any necessary parents like "mkdir -p".
+func (h *Host) MkdirAllInGuest(dir string) error {
+	return h.MkdirAllInGuestContext(context.Background(), dir)
+}
+
+// MkdirAllInGuestContext is like MkdirAllInGuest but includes a context.
+func (h *Host) MkdirAllInGuestContext(ctx context.Context, dir string) error {
+	return h.client.MkdirAllInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// RemoveAllInGuest remove a file or a directory and any children it contains in Guest OS like "rm -rf".
+func (h *Host) RemoveAllInGuest(path string) error {
+	return h.RemoveAllInGuestContext(context.Background(), path)
+}
+
+// RemoveAllInGuestContext is like RemoveAllInGuest but includes a context.
+func (h *Host) RemoveAllInGuestContext(ctx context.Context, path string) error {
+	return h.client.RemoveAllInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, path)
+}
+
+// CreateTempfileInGuest create a temporary file in Guest OS.
+func (h *Host) CreateTempfileInGuest() (string, error) {
+	return h.CreateTempfileInGuestContext(context.Background())
+}
+
+// CreateTempfileInGuestContext is like CreateTempfileInGuest but includes a context.
+func (h *Host) CreateTempfileInGuestContext(ctx context.Context) (string, error) {
+	return h.client.CreateTempfileInGuest(ctx, h.product.App(), h.vmx, h.username, h.password)
+}
+
+// ListDirectoryInGuest list a directory in Guest OS.
+func (h *Host) ListDirectoryInGuest(dir string) ([]string, error) {
+	return h.ListDirectoryInGuestContext(context.Background(), dir)
+}
+
+// ListDirectoryInGuestContext is like ListDirectoryInGuest but includes a context.
+func (h *Host) ListDirectoryInGuestContext(ctx context.Context, dir string) ([]string, error) {
+	return h.client.ListDirectoryInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// ReadDirInGuest list a directory in Guest OS, and returns its entries sorted by name.
+func (h *Host) ReadDirInGuest(dir string) ([]vmrun.GuestDirEntry, error) {
+	return h.ReadDirInGuestContext(context.Background(), dir)
+}
+
+// ReadDirInGuestContext is like ReadDirInGuest but includes a context.
+func (h *Host) ReadDirInGuestContext(ctx context.Context, dir string) ([]vmrun.GuestDirEntry, error) {
+	return h.client.ReadDirInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, dir)
+}
+
+// CopyFileFromHostToGuest copy a file from host OS to guest OS.
+func (h *Host) CopyFileFromHostToGuest(hostFilepath, guestFilepath string) error {
+	return h.CopyFileFromHostToGuestContext(context.Background(), hostFilepath, guestFilepath)
+}
+
+// CopyFileFromHostToGuestContext is like CopyFileFromHostToGuest but includes a context.
+func (h *Host) CopyFileFromHostToGuestContext(ctx context.Context, hostFilepath, guestFilepath string) error {
+	return h.client.CopyFileFromHostToGuest(ctx, h.product.App(), h.vmx, h.username, h.password, hostFilepath, guestFilepath)
+}
+
+// CopyFileFromGuestToHost copy a file from guest OS to host OS.
+func (h *Host) CopyFileFromGuestToHost(guestFilepath, hostFilepath string) error {
+	return h.CopyFileFromGuestToHostContext(context.Background(), guestFilepath, hostFilepath)
+}
+
+// CopyFileFromGuestToHostContext is like CopyFileFromGuestToHost but includes a context.
+func (h *Host) CopyFileFromGuestToHostContext(ctx context.Context, guestFilepath, hostFilepath string) error {
+	return h.client.CopyFileFromGuestToHost(ctx, h.product.App(), h.vmx, h.username, h.password, guestFilepath, hostFilepath)
+}
+
+// CopyDirToGuest copy a directory tree from host OS to guest OS.
+func (h *Host) CopyDirToGuest(hostDir, guestDir string, opts *vmrun.CopyDirOptions) error {
+	return h.CopyDirToGuestContext(context.Background(), hostDir, guestDir, opts)
+}
+
+// CopyDirToGuestContext is like CopyDirToGuest but includes a context.
+func (h *Host) CopyDirToGuestContext(ctx context.Context, hostDir, guestDir string, opts *vmrun.CopyDirOptions) error {
+	return h.client.CopyDirToGuest(ctx, h.product.App(), h.vmx, h.username, h.password, hostDir, guestDir, opts)
+}
+
+// CopyDirFromGuest copy a directory tree from guest OS to host OS.
+func (h *Host) CopyDirFromGuest(guestDir, hostDir string, opts *vmrun.CopyDirOptions) error {
+	return h.CopyDirFromGuestContext(context.Background(), guestDir, hostDir, opts)
+}
+
+// CopyDirFromGuestContext is like CopyDirFromGuest but includes a context.
+func (h *Host) CopyDirFromGuestContext(ctx context.Context, guestDir, hostDir string, opts *vmrun.CopyDirOptions) error {
+	return h.client.CopyDirFromGuest(ctx, h.product.App(), h.vmx, h.username, h.password, guestDir, hostDir, opts)
+}
+
+// RenameFileInGuest rename a file in Guest OS.
+func (h *Host) RenameFileInGuest(src, dst string) error {
+	return h.RenameFileInGuestContext(context.Background(), src, dst)
+}
+
+// RenameFileInGuestContext is like RenameFileInGuest but includes a context.
+func (h *Host) RenameFileInGuestContext(ctx context.Context, src, dst string) error {
+	return h.client.RenameFileInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, src, dst)
+}
+
+// CaptureScreen capture the screen of the VM to a local file.
+func (h *Host) CaptureScreen(dst string) error {
+	return h.CaptureScreenContext(context.Background(), dst)
+}
+
+// CaptureScreenContext is like CaptureScreen but includes a context.
+func (h *Host) CaptureScreenContext(ctx context.Context, dst string) error {
+	return h.client.CaptureScreen(ctx, h.product.App(), h.vmx, h.username, h.password, dst)
+}
+
+// WriteVariable write a variable in the VM state.
+func (h *Host) WriteVariable(mode vmrun.VariableMode, env, value string) error {
+	return h.WriteVariableContext(context.Background(), mode, env, value)
+}
+
+// WriteVariableContext is like WriteVariable but includes a context.
+func (h *Host) WriteVariableContext(ctx context.Context, mode vmrun.VariableMode, env, value string) error {
+	return h.client.WriteVariable(ctx, h.product.App(), h.vmx, h.username, h.password, mode, env, value)
+}
+
+// ReadVariable read a variable in the VM state.
+func (h *Host) ReadVariable(mode vmrun.VariableMode, env string) (string, error) {
+	return h.ReadVariableContext(context.Background(), mode, env)
+}
+
+// ReadVariableContext is like ReadVariable but includes a context.
+func (h *Host) ReadVariableContext(ctx context.Context, mode vmrun.VariableMode, env string) (string, error) {
+	return h.client.ReadVariable(ctx, h.product.App(), h.vmx, h.username, h.password, mode, env)
+}
+
+// GetGuestIPAddress gets the IP address of the guest.
+func (h *Host) GetGuestIPAddress(wait bool) (string, error) {
+	return h.GetGuestIPAddressContext(context.Background(), wait)
+}
+
+// GetGuestIPAddressContext is like GetGuestIPAddress but includes a context.
+func (h *Host) GetGuestIPAddressContext(ctx context.Context, wait bool) (string, error) {
+	return h.client.GetGuestIPAddress(ctx, h.product.App(), h.vmx, wait)
+}
+
+// IsRunning reports whether the VM is running.
+func (h *Host) IsRunning() (bool, error) {
+	return h.IsRunningContext(context.Background())
+}
+
+// IsRunningContext is like IsRunning but includes a context.
+func (h *Host) IsRunningContext(ctx context.Context) (bool, error) {
+	vmxs, err := h.client.List(ctx, h.product.App())
+	if err != nil {
+		return false, err
+	}
+
+	for _, vmx := range vmxs {
+		if filepath.Clean(vmx) == filepath.Clean(h.vmx) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// UpgradeVM upgrade VM file format, virtual hw.
+func (h *Host) UpgradeVM() error {
+	return h.UpgradeVMContext(context.Background())
+}
+
+// UpgradeVMContext is like UpgradeVM but includes a context.
+func (h *Host) UpgradeVMContext(ctx context.Context) error {
+	return h.client.UpgradeVM(ctx, h.product.App(), h.vmx)
+}
+
+// InstallTools install Tools in Guest.
+func (h *Host) InstallTools() error {
+	return h.InstallToolsContext(context.Background())
+}
+
+// InstallToolsContext is like InstallTools but includes a context.
+func (h *Host) InstallToolsContext(ctx context.Context) error {
+	return h.client.InstallTools(ctx, h.product.App(), h.vmx)
+}
+
+// CheckToolsState check the current Tools state.
+func (h *Host) CheckToolsState() (vmrun.ToolsState, error) {
+	return h.CheckToolsStateContext(context.Background())
+}
+
+// CheckToolsStateContext is like CheckToolsState but includes a context.
+func (h *Host) CheckToolsStateContext(ctx context.Context) (vmrun.ToolsState, error) {
+	return h.client.CheckToolsState(ctx, h.product.App(), h.vmx)
+}
+
+// DeleteVM delete a VM.
+func (h *Host) DeleteVM() error {
+	return h.DeleteVMContext(context.Background())
+}
+
+// DeleteVMContext is like DeleteVM but includes a context.
+func (h *Host) DeleteVMContext(ctx context.Context) error {
+	return h.client.DeleteVM(ctx, h.product.App(), h.vmx)
+}
+
+// Clone create a copy of the VM to the dst .vmx file path.
+//
+// The nil opts creates a full clone of the current state.
+func (h *Host) Clone(dst string, opts *vmrun.CloneOptions) error {
+	return h.CloneContext(context.Background(), dst, opts)
+}
+
+// CloneContext is like Clone but includes a context.
+func (h *Host) CloneContext(ctx context.Context, dst string, opts *vmrun.CloneOptions) error {
+	if err := h.supports("clone"); err != nil {
+		return err
+	}
+
+	return h.client.Clone(ctx, h.product.App(), h.vmx, dst, opts)
+}
@@ -0,0 +1,78 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+const testVMX = "/vm/test.vmx"
+
+func TestHostApp(t *testing.T) {
+	tests := []struct {
+		name string
+		host *Host
+		want string
+	}{
+		{name: "fusion", host: NewFusion(testVMX, "", "").Host, want: "fusion"},
+		{name: "workstation", host: NewWorkstation(testVMX, "", "").Host, want: "ws"},
+		{name: "player", host: NewPlayer(testVMX, "", "").Host, want: "player"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := runnertest.NewFake(runnertest.Step{Args: []string{"-T", tt.want, "start", testVMX, "nogui"}})
+			tt.host.SetClient(vmrun.NewClient(fake))
+
+			if err := tt.host.Start(false); err != nil {
+				t.Fatal(err)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestHostSetAuth(t *testing.T) {
+	fake := runnertest.NewFake(
+		runnertest.Step{Args: []string{"-T", "fusion", "-vp", "secret", "start", testVMX, "nogui"}},
+		runnertest.Step{Args: []string{"-T", "fusion", "-vp", "secret", "-gu", "user", "-gp", "pass", "deleteFileInGuest", testVMX, "/tmp/a"}},
+	)
+	h := NewFusion(testVMX, "user", "pass")
+	h.SetAuth(&vmrun.Auth{VMPassword: "secret", GuestUser: "ignored", GuestPassword: "ignored"})
+	h.SetClient(vmrun.NewClient(fake))
+
+	if err := h.Start(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.DeleteFileInGuest("/tmp/a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayerUnsupported(t *testing.T) {
+	player := NewPlayer(testVMX, "", "")
+	fake := runnertest.NewFake()
+	player.SetClient(vmrun.NewClient(fake))
+
+	err := player.Snapshot("base")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("Snapshot error = %v, want %v", err, ErrUnsupported)
+	}
+	var unsupportedErr *UnsupportedError
+	if !errors.As(err, &unsupportedErr) || unsupportedErr.Op != "snapshot" || unsupportedErr.Product != ProductPlayer {
+		t.Fatalf("Snapshot error = %#v, want *UnsupportedError", err)
+	}
+	if calls := fake.Calls(); len(calls) != 0 {
+		t.Fatalf("vmrun is called %d times, want 0", len(calls))
+	}
+}
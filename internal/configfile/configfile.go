@@ -0,0 +1,294 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EncodingKey is the key of the character encoding header.
+const EncodingKey = ".encoding"
+
+// entry represents a line of the file.
+type entry struct {
+	key   string // empty for a blank or comment line
+	value string
+	raw   string // the original line without the line ending, which is written unless the entry is modified
+	eol   string // the original line ending, which is empty for the last line without a line ending
+	dirty bool
+}
+
+// File represents a configuration file.
+//
+// The values are kept as the byte strings in the encoding of the file, which is reported by Encoding.
+type File struct {
+	entries []*entry
+	eol     string // the line ending of the new entries
+}
+
+// New returns the new empty File, which has the UTF-8 encoding header.
+func New() *File {
+	f := &File{eol: "\n"}
+	f.Set(EncodingKey, "UTF-8")
+
+	return f
+}
+
+// Parse parses the file content from r. The errors are prefixed with format, such as "vmx".
+func Parse(r io.Reader, format string) (*File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{eol: "\n"}
+	if bytes.Contains(data, []byte("\r\n")) {
+		f.eol = "\r\n"
+	}
+
+	br := bufio.NewReader(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if line == "" && err == io.EOF {
+			break
+		}
+
+		e := &entry{raw: line}
+		switch {
+		case strings.HasSuffix(line, "\r\n"):
+			e.raw, e.eol = line[:len(line)-2], "\r\n"
+		case strings.HasSuffix(line, "\n"):
+			e.raw, e.eol = line[:len(line)-1], "\n"
+		}
+
+		trimmed := strings.TrimSpace(e.raw)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			kv := strings.SplitN(trimmed, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("%s: line %d: missing '=': %q", format, n, e.raw)
+			}
+			value, derr := decodeValue(strings.TrimSpace(kv[1]))
+			if derr != nil {
+				return nil, fmt.Errorf("%s: line %d: %v", format, n, derr)
+			}
+			e.key, e.value = strings.TrimSpace(kv[0]), value
+		}
+		f.entries = append(f.entries, e)
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return f, nil
+}
+
+// ReadFile reads and parses the name file. The errors of Parse are prefixed with format.
+func ReadFile(name, format string) (*File, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return Parse(r, format)
+}
+
+// WriteTo writes f to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	for i, e := range f.entries {
+		eol := e.eol
+		if eol == "" && i < len(f.entries)-1 {
+			// the last line without a line ending is followed by the new entries.
+			eol = f.eol
+		}
+		if e.dirty {
+			buf.WriteString(e.key + " = " + encodeValue(e.value) + eol)
+			continue
+		}
+		buf.WriteString(e.raw + eol)
+	}
+
+	return buf.WriteTo(w)
+}
+
+// WriteFile writes f to the name file atomically, by renaming the temporary file in the same directory.
+// The new file is created with 0600, and the existing file keeps its permissions.
+func (f *File) WriteFile(name string) error {
+	return f.WriteFileMode(name, 0600)
+}
+
+// WriteFileMode is like WriteFile but the new file is created with perm.
+func (f *File) WriteFileMode(name string, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := f.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if info, err := os.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// decodeValue decodes the quoted value, which escapes a byte as "|" followed by two hex digits.
+// The unquoted value is returned as is.
+func decodeValue(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s, nil
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, "|") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '|' || i+2 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid escape %q", s[i:i+3])
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+
+	return b.String(), nil
+}
+
+// encodeValue quotes the value, escaping the double quote, the pipe and the control characters.
+func encodeValue(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '|' || c < 0x20:
+			fmt.Fprintf(&b, "|%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// find returns the entry of the key, or nil if not found.
+func (f *File) find(key string) *entry {
+	for _, e := range f.entries {
+		if e.key != "" && strings.EqualFold(e.key, key) {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// Encoding returns the character encoding of the file declared by the .encoding header,
+// or empty if the file has no header.
+func (f *File) Encoding() string {
+	v, _ := f.Get(EncodingKey)
+	return v
+}
+
+// Get returns the value of the key, which is case insensitive.
+func (f *File) Get(key string) (string, bool) {
+	if e := f.find(key); e != nil {
+		return e.value, true
+	}
+
+	return "", false
+}
+
+// Set sets the value of the key, appending it if the key does not exist.
+//
+// The existing key keeps its spelling. The .encoding header is inserted at the top of the file.
+func (f *File) Set(key, value string) {
+	if e := f.find(key); e != nil {
+		if e.value != value {
+			e.value = value
+			e.dirty = true
+		}
+		return
+	}
+
+	e := &entry{key: key, value: value, eol: f.eol, dirty: true}
+	if strings.EqualFold(key, EncodingKey) {
+		f.entries = append([]*entry{e}, f.entries...)
+		return
+	}
+	f.entries = append(f.entries, e)
+}
+
+// Delete deletes the key, including its duplicates.
+func (f *File) Delete(key string) {
+	f.deleteFunc(func(k string) bool { return strings.EqualFold(k, key) })
+}
+
+// DeletePrefix deletes the keys which start with prefix in any case.
+func (f *File) DeletePrefix(prefix string) {
+	prefix = strings.ToLower(prefix)
+	f.deleteFunc(func(k string) bool { return strings.HasPrefix(strings.ToLower(k), prefix) })
+}
+
+// deleteFunc deletes the entries which key satisfies match.
+func (f *File) deleteFunc(match func(key string) bool) {
+	entries := f.entries[:0]
+	for _, e := range f.entries {
+		if e.key == "" || !match(e.key) {
+			entries = append(entries, e)
+		}
+	}
+	f.entries = entries
+}
+
+// Lines returns the 1-based line numbers of the key, which has more than one line if the key is duplicated.
+// The line numbers are the ones of the parsed file until f is modified.
+func (f *File) Lines(key string) []int {
+	var lines []int
+	for i, e := range f.entries {
+		if e.key != "" && strings.EqualFold(e.key, key) {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return lines
+}
+
+// Keys returns the keys in the file order, including the duplicated keys.
+func (f *File) Keys() []string {
+	var keys []string
+	for _, e := range f.entries {
+		if e.key != "" {
+			keys = append(keys, e.key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,242 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package configfile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFile = `.encoding = "UTF-8"
+# created by hand
+config.version = "8"
+virtualHW.version = "16"
+displayName = "test |22vm|22"
+
+memsize = "2048"
+numvcpus=2
+ethernet0.present = "TRUE"
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(testFile), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{key: ".encoding", want: "UTF-8", wantOK: true},
+		{key: "displayName", want: `test "vm"`, wantOK: true},
+		{key: "DISPLAYNAME", want: `test "vm"`, wantOK: true},
+		{key: "numvcpus", want: "2", wantOK: true},
+		{key: "ethernet0.present", want: "TRUE", wantOK: true},
+		{key: "ethernet1.present"},
+	}
+	for _, tt := range tests {
+		got, ok := f.Get(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Get(%q) = %q, %v, want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if got := f.Encoding(); got != "UTF-8" {
+		t.Errorf("Encoding = %q, want %q", got, "UTF-8")
+	}
+	want := []string{".encoding", "config.version", "virtualHW.version", "displayName", "memsize", "numvcpus", "ethernet0.present"}
+	if got := f.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %q, want %q", got, want)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "memsize\n", want: `test: line 1: missing '=': "memsize"`},
+		{in: "a = \"1\"\ndisplayName = \"a|zzb\"\n", want: `test: line 2: invalid escape "|zz"`},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt.in), "test"); err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %s", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestFileWriteTo(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		modify func(f *File)
+		want   string
+	}{
+		{
+			name:   "unchanged",
+			in:     testFile,
+			modify: func(f *File) {},
+			want:   testFile,
+		},
+		{
+			name:   "unchanged crlf without last line ending",
+			in:     "a = \"1\"\r\n# b\r\nc=3",
+			modify: func(f *File) {},
+			want:   "a = \"1\"\r\n# b\r\nc=3",
+		},
+		{
+			name:   "unchanged spacing and comment",
+			in:     "# comment\r\n.encoding   =   \"UTF-8\"\r\n\r\nsnapshot0.uid = \"1\"\r\n",
+			modify: func(f *File) {},
+			want:   "# comment\r\n.encoding   =   \"UTF-8\"\r\n\r\nsnapshot0.uid = \"1\"\r\n",
+		},
+		{
+			name:   "mixed line endings",
+			in:     "a = \"1\"\r\nb = \"2\"\nc = \"3\"\r\n",
+			modify: func(f *File) { f.Set("b", "two") },
+			want:   "a = \"1\"\r\nb = \"two\"\nc = \"3\"\r\n",
+		},
+		{
+			name:   "set same value",
+			in:     testFile,
+			modify: func(f *File) { f.Set("NUMVCPUS", "2") },
+			want:   testFile,
+		},
+		{
+			name: "set",
+			in:   testFile,
+			modify: func(f *File) {
+				f.Set("NUMVCPUS", "4")
+				f.Set("guestOS", "ubuntu-64")
+			},
+			want: strings.Replace(testFile, "numvcpus=2", `numvcpus = "4"`, 1) + "guestOS = \"ubuntu-64\"\n",
+		},
+		{
+			name:   "set escape",
+			in:     "a = \"1\"\n",
+			modify: func(f *File) { f.Set("a", "x|\"y\"") },
+			want:   "a = \"x|7C|22y|22\"\n",
+		},
+		{
+			name:   "append crlf without last line ending",
+			in:     "a = \"1\"\r\nb = \"2\"",
+			modify: func(f *File) { f.Set("c", "3") },
+			want:   "a = \"1\"\r\nb = \"2\"\r\nc = \"3\"\r\n",
+		},
+		{
+			name:   "delete duplicates",
+			in:     "a = \"1\"\nb = \"2\"\nA = \"3\"\n",
+			modify: func(f *File) { f.Delete("a") },
+			want:   "b = \"2\"\n",
+		},
+		{
+			name:   "delete prefix",
+			in:     "scsi0.present = \"TRUE\"\nSCSI0:0.present = \"TRUE\"\nscsi0:0.fileName = \"a.vmdk\"\nscsi1.present = \"TRUE\"\n",
+			modify: func(f *File) { f.DeletePrefix("scsi0:") },
+			want:   "scsi0.present = \"TRUE\"\nscsi1.present = \"TRUE\"\n",
+		},
+		{
+			name:   "insert encoding",
+			in:     "a = \"1\"\n",
+			modify: func(f *File) { f.Set(".encoding", "UTF-8") },
+			want:   ".encoding = \"UTF-8\"\na = \"1\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(tt.in), "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(f)
+
+			var buf bytes.Buffer
+			if _, err := f.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("WriteTo = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	f := New()
+	f.Set("memsize", "1024")
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := ".encoding = \"UTF-8\"\nmemsize = \"1024\"\n"; buf.String() != want {
+		t.Fatalf("WriteTo = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFileWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.vmx")
+	if err := ioutil.WriteFile(name, []byte(testFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ReadFile(name, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Set("memsize", "4096")
+	if err := f.WriteFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(testFile, "2048", "4096", 1); string(data) != want {
+		t.Errorf("content = %q, want %q", data, want)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("perm = %v, want %v", info.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+func TestFileWriteFileMode(t *testing.T) {
+	dir := t.TempDir()
+	f := New()
+	f.Set("memsize", "1024")
+
+	// the new file is created with the perm, and the existing file keeps its permissions.
+	existing := filepath.Join(dir, "existing.vmx")
+	if err := ioutil.WriteFile(existing, nil, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(existing, 0640); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]os.FileMode{filepath.Join(dir, "new.vmx"): 0644, existing: 0640} {
+		if err := f.WriteFileMode(name, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s: perm = %v, want %v", filepath.Base(name), info.Mode().Perm(), want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package configfile implements the lossless reader and writer of the VMware configuration files,
+// such as the .vmx and .vmsd files, which are the lists of "key = "value"" lines.
+//
+// The comments, the order of lines, the quoting and the line endings are kept,
+// so a File which is not modified is written back byte for byte.
+package configfile
@@ -0,0 +1,58 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmwareutil
+
+// flagsWithValue is the vmrun authentication flags which take a value.
+var flagsWithValue = map[string]bool{
+	"-T":  true,
+	"-h":  true,
+	"-P":  true,
+	"-u":  true,
+	"-p":  true,
+	"-vp": true,
+	"-gu": true,
+	"-gp": true,
+}
+
+// secretFlags is the vmrun authentication flags which take a password.
+var secretFlags = map[string]bool{
+	"-p":  true,
+	"-vp": true,
+	"-gp": true,
+}
+
+// Redacted replaces the passwords in the vmrun arguments returned by RedactArgs.
+const Redacted = "<redacted>"
+
+// CommandName returns the vmrun command name in args, skipping the authentication flags.
+func CommandName(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if flagsWithValue[args[i]] {
+			i++
+			continue
+		}
+		return args[i]
+	}
+
+	return ""
+}
+
+// RedactArgs returns the copy of the vmrun args which passwords are redacted.
+func RedactArgs(args []string) []string {
+	redactedArgs := make([]string, len(args))
+	copy(redactedArgs, args)
+
+	for i := 0; i < len(redactedArgs)-1; i++ {
+		if !flagsWithValue[redactedArgs[i]] {
+			break // authentication flags must appear before the command
+		}
+		if secretFlags[redactedArgs[i]] {
+			redactedArgs[i+1] = Redacted
+		}
+		i++
+	}
+
+	return redactedArgs
+}
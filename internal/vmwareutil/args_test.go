@@ -0,0 +1,22 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmwareutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"-T", "ws", "-h", "host", "-u", "user", "-p", "hostpass", "-vp", "vmpass", "-gu", "guest", "-gp", "guestpass", "runScriptInGuest", "/vm/test.vmx", "/bin/sh", "-p"}
+	want := []string{"-T", "ws", "-h", "host", "-u", "user", "-p", Redacted, "-vp", Redacted, "-gu", "guest", "-gp", Redacted, "runScriptInGuest", "/vm/test.vmx", "/bin/sh", "-p"}
+
+	if got := RedactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactArgs(%q) = %q, want %q", args, got, want)
+	}
+	if got := CommandName(args); got != "runScriptInGuest" {
+		t.Errorf("CommandName(%q) = %q, want %q", args, got, "runScriptInGuest")
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+// Player represents a VMware Workstation Player application.
+//
+// Player does not support the snapshot and clone operations, those return the *UnsupportedError.
+type Player struct {
+	*Host
+}
+
+// NewPlayer return the new Player.
+func NewPlayer(vmx, username, password string) *Player {
+	return &Player{
+		Host: NewHost(ProductPlayer, vmx, username, password),
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// PowerState represents the power state of a VM.
+type PowerState int
+
+const (
+	// PowerStateUnknown is the state which could not be determined.
+	PowerStateUnknown PowerState = iota
+	// PoweredOff is the state of the VM which is not running and has no suspended state.
+	PoweredOff
+	// PoweredOn is the state of the running VM.
+	PoweredOn
+	// Suspended is the state of the VM which memory is saved to the .vmss file.
+	Suspended
+	// Paused is the state of the running VM which virtual CPUs are stopped.
+	Paused
+)
+
+// String implements a fmt.Stringer interface.
+func (s PowerState) String() string {
+	switch s {
+	case PoweredOff:
+		return "poweredOff"
+	case PoweredOn:
+		return "poweredOn"
+	case Suspended:
+		return "suspended"
+	case Paused:
+		return "paused"
+	default:
+		return "unknown"
+	}
+}
+
+// powerPollInterval is the interval of polling the power state by WaitForState.
+var powerPollInterval = time.Second
+
+// logTailSize is the size of the vmware.log tail read to determine the power state.
+const logTailSize = 64 << 10
+
+// List of the vmware.log records which determine the power state.
+var (
+	logPaused   = []string{"Pausing VM", "VM paused"}
+	logUnpaused = []string{"Unpausing VM", "VM unpaused"}
+	logExited   = []string{"VMX has left the building"}
+)
+
+// vmFiles represents the on-disk evidence of the power state of a VM.
+type vmFiles struct {
+	suspended bool // the .vmss file exists
+	locked    bool // the .lck directories exist
+	live      bool // any lock is held by a running process
+	paused    bool // the last pause record of vmware.log is not followed by an unpause record
+	exited    bool // the last record of vmware.log is the exit of the VM process
+}
+
+// readVMFiles reads the files next to the vmx file. The missing files are ignored.
+func readVMFiles(vmx string) (*vmFiles, error) {
+	f := &vmFiles{}
+
+	if _, err := os.Stat(strings.TrimSuffix(vmx, filepath.Ext(vmx)) + ".vmss"); err == nil {
+		f.suspended = true
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	dir := filepath.Dir(vmx)
+	locks, err := filepath.Glob(filepath.Join(dir, "*.lck"))
+	if err != nil {
+		return nil, err
+	}
+	f.locked = len(locks) > 0
+	for _, lock := range locks {
+		live, err := lockLive(lock)
+		if err != nil {
+			return nil, err
+		}
+		if live {
+			f.live = true
+			break
+		}
+	}
+
+	tail, err := readTail(filepath.Join(dir, "vmware.log"), logTailSize)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sc := bufio.NewScanner(bytes.NewReader(tail))
+	sc.Buffer(nil, logTailSize+1) // the whole tail fits in a line
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case containsAny(line, logUnpaused):
+			f.paused = false
+		case containsAny(line, logPaused):
+			f.paused = true
+		case containsAny(line, logExited):
+			f.exited = true
+			f.paused = false
+			continue
+		case strings.TrimSpace(line) == "":
+			continue
+		}
+		f.exited = false
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// lockLive reports whether the lock directory, or the lock file of old VMware, is held by a running process.
+//
+// The lock directory contains the member files such as "M12345.lck", which content is:
+//
+//	machineID executionID lamportNumber lockType payload lc=checksum
+//
+// The executionID is the pid of the owner process followed by its creation time, such as "4208-1514764800".
+// The machineID is not compared, so the lock of a VM on the shared storage is live if the pid exists in this host.
+func lockLive(lock string) (bool, error) {
+	info, err := os.Stat(lock)
+	if os.IsNotExist(err) {
+		// the lock is released after the Glob.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	members := []string{lock}
+	if info.IsDir() {
+		members, err = filepath.Glob(filepath.Join(lock, "*.lck"))
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, member := range members {
+		data, err := ioutil.ReadFile(member)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return false, err
+		}
+		if pid, ok := parseLockOwner(data); ok && processExists(pid) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// parseLockOwner parses the pid of the owner process from the lock member file content.
+func parseLockOwner(data []byte) (int, bool) {
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, false
+	}
+	executionID := fields[1]
+	if i := strings.IndexByte(executionID, '-'); i >= 0 {
+		executionID = executionID[:i]
+	}
+	pid, err := strconv.Atoi(executionID)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+
+	return pid, true
+}
+
+// processExists reports whether the process of the pid exists.
+func processExists(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process on Windows, which fails if it does not exist.
+		return true
+	}
+
+	// the signal 0 checks the existence without sending a signal, which is not permitted for the process of another user.
+	err = p.Signal(syscall.Signal(0))
+
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// readTail reads at most the last n bytes of the name file.
+func readTail(name string, n int64) ([]byte, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	info, err := r.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > n {
+		if _, err := r.Seek(info.Size()-n, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, n); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// containsAny reports whether s contains any of substrs.
+func containsAny(s string, substrs []string) bool {
+	for _, substr := range substrs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// powerState determines the power state from whether the VM is listed by vmrun and the files of the VM.
+func powerState(listed bool, f *vmFiles) PowerState {
+	switch {
+	case listed && f.paused:
+		return Paused
+	case listed:
+		return PoweredOn
+	case f.suspended:
+		return Suspended
+	case f.locked && !f.exited && f.live:
+		// the VM is running by another user or product which vmrun does not list.
+		return PoweredOn
+	case f.locked && !f.exited:
+		// the lock may be left by the crashed VM, or held by another host.
+		return PowerStateUnknown
+	default:
+		return PoweredOff
+	}
+}
+
+// PowerState returns the power state of the VM.
+//
+// The running VMs listed by vmrun are combined with the files next to the .vmx file, which are
+// the .vmss suspended state, the .lck lock directories and the tail of vmware.log,
+// so h must be a local VM. The VM which is not listed but locked is PoweredOn only if the owner process
+// of the lock is running, and PowerStateUnknown otherwise.
+func (h *Host) PowerState() (PowerState, error) {
+	return h.PowerStateContext(context.Background())
+}
+
+// PowerStateContext is like PowerState but includes a context.
+func (h *Host) PowerStateContext(ctx context.Context) (PowerState, error) {
+	listed, err := h.IsRunningContext(ctx)
+	if err != nil {
+		return PowerStateUnknown, err
+	}
+	f, err := readVMFiles(h.vmx)
+	if err != nil {
+		return PowerStateUnknown, err
+	}
+
+	return powerState(listed, f), nil
+}
+
+// WaitForState waits until the VM is in the state.
+func (h *Host) WaitForState(state PowerState) error {
+	return h.WaitForStateContext(context.Background(), state)
+}
+
+// WaitForStateContext is like WaitForState but includes a context.
+//
+// It returns the error of ctx if ctx is done before the VM is in the state.
+func (h *Host) WaitForStateContext(ctx context.Context, state PowerState) error {
+	ticker := time.NewTicker(powerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		got, err := h.PowerStateContext(ctx)
+		if err != nil {
+			return err
+		}
+		if got == state {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// EnsureRunning starts, resumes or unpauses the VM unless it is already running, and waits until it is running.
+func (h *Host) EnsureRunning(gui bool) error {
+	return h.EnsureRunningContext(context.Background(), gui)
+}
+
+// EnsureRunningContext is like EnsureRunning but includes a context.
+func (h *Host) EnsureRunningContext(ctx context.Context, gui bool) error {
+	state, err := h.PowerStateContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case PoweredOn:
+		return nil
+	case Paused:
+		err = h.client.Unpause(ctx, h.product.App(), h.vmx)
+	default:
+		// the start command also resumes the suspended VM.
+		err = h.client.Start(ctx, h.product.App(), h.vmx, gui)
+	}
+	if err != nil {
+		return err
+	}
+
+	return h.WaitForStateContext(ctx, PoweredOn)
+}
+
+// EnsureStopped stops the VM unless it is already powered off, and waits until it is powered off.
+//
+// The paused VM is unpaused before the soft stop, since the guest OS can not shut down while paused.
+// The suspended VM is left as is, because vmrun can not stop it without resuming it.
+func (h *Host) EnsureStopped(hard bool) error {
+	return h.EnsureStoppedContext(context.Background(), hard)
+}
+
+// EnsureStoppedContext is like EnsureStopped but includes a context.
+func (h *Host) EnsureStoppedContext(ctx context.Context, hard bool) error {
+	state, err := h.PowerStateContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch state {
+	case PoweredOff, Suspended:
+		return nil
+	case Paused:
+		if !hard {
+			if err := h.client.Unpause(ctx, h.product.App(), h.vmx); err != nil {
+				return err
+			}
+		}
+	}
+	if err := h.client.Stop(ctx, h.product.App(), h.vmx, hard); err != nil {
+		return err
+	}
+
+	return h.WaitForStateContext(ctx, PoweredOff)
+}
@@ -0,0 +1,232 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// deadPID is the pid of the process which does not exist.
+const deadPID = 1 << 30
+
+// writeVMFiles writes the files of the test VM in dir, and returns the .vmx file path.
+// The .vmx file is locked by the lockPID process unless it is 0.
+func writeVMFiles(t *testing.T, dir string, suspended bool, lockPID int, log string) string {
+	t.Helper()
+
+	vmx := filepath.Join(dir, "test.vmx")
+	files := map[string]string{vmx: ""}
+	if suspended {
+		files[filepath.Join(dir, "test.vmss")] = ""
+	}
+	if log != "" {
+		files[filepath.Join(dir, "vmware.log")] = log
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lockPID != 0 {
+		if err := os.Mkdir(vmx+".lck", 0755); err != nil {
+			t.Fatal(err)
+		}
+		member := fmt.Sprintf("52-8d-1c-3e %d-1514764800 1 X (null) lc=12345\n", lockPID)
+		if err := ioutil.WriteFile(filepath.Join(vmx+".lck", "M12345.lck"), []byte(member), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return vmx
+}
+
+func TestHostPowerState(t *testing.T) {
+	const (
+		running = "2018-01-01T00:00:00.000Z| vmx| I125: VMXVmdb_LoadRawConfig: Loading raw config\n"
+		paused  = running + "2018-01-01T00:00:01.000Z| vmx| I125: VMX: Pausing VM\n"
+		resumed = paused + "2018-01-01T00:00:02.000Z| vmx| I125: VMX: Unpausing VM\n"
+		exited  = running + "2018-01-01T00:00:03.000Z| vmx| I125: VMX has left the building: 0.\n"
+	)
+	// long has a line longer than the tail of vmware.log.
+	long := paused + strings.Repeat("x", 2*logTailSize)
+	live := os.Getpid()
+
+	tests := []struct {
+		name      string
+		listed    bool
+		suspended bool
+		lockPID   int
+		log       string
+		want      PowerState
+	}{
+		{name: "powered off", want: PoweredOff},
+		{name: "powered on", listed: true, lockPID: live, log: running, want: PoweredOn},
+		{name: "paused", listed: true, lockPID: live, log: paused, want: Paused},
+		{name: "unpaused", listed: true, lockPID: live, log: resumed, want: PoweredOn},
+		{name: "suspended", suspended: true, log: exited, want: Suspended},
+		{name: "not listed but locked", lockPID: live, log: running, want: PoweredOn},
+		{name: "crashed", lockPID: deadPID, log: running, want: PowerStateUnknown},
+		{name: "stale lock", lockPID: deadPID, log: exited, want: PoweredOff},
+		{name: "long log line", listed: true, lockPID: live, log: long, want: PoweredOn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmx := writeVMFiles(t, t.TempDir(), tt.suspended, tt.lockPID, tt.log)
+			stdout := "Total running VMs: 0\n"
+			if tt.listed {
+				stdout = "Total running VMs: 1\n" + vmx + "\n"
+			}
+
+			fake := runnertest.NewFake(runnertest.Step{Args: []string{"-T", "fusion", "list"}, Result: runner.Result{Stdout: stdout}})
+			h := NewFusion(vmx, "", "")
+			h.SetClient(vmrun.NewClient(fake))
+
+			got, err := h.PowerState()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("PowerState = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeLog writes the vmware.log of the vmx.
+func writeLog(t *testing.T, vmx, log string) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(filepath.Join(filepath.Dir(vmx), "vmware.log"), []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakePower emulates the power commands of vmrun for the VM which is initially in the state.
+func fakePower(t *testing.T, vmx string, state PowerState, commands *[]string) runner.Runner {
+	if state == Paused {
+		// the paused state is only known from vmware.log.
+		writeLog(t, vmx, "VMX: Pausing VM\n")
+	}
+
+	return runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		command := args[2]
+		if command == "list" {
+			stdout := "Total running VMs: 0\n"
+			if state == PoweredOn || state == Paused {
+				stdout = "Total running VMs: 1\n" + vmx + "\n"
+			}
+			return &runner.Result{Stdout: stdout}, nil
+		}
+
+		*commands = append(*commands, strings.Join(args[2:], " "))
+		switch command {
+		case "start":
+			state = PoweredOn
+		case "stop":
+			state = PoweredOff
+		case "unpause":
+			state = PoweredOn
+			writeLog(t, vmx, "VMX: Unpausing VM\n")
+		default:
+			t.Fatalf("unexpected args %q", args)
+		}
+		return &runner.Result{}, nil
+	})
+}
+
+func TestHostEnsureRunning(t *testing.T) {
+	tests := []struct {
+		name  string
+		state PowerState
+		want  []string
+	}{
+		{name: "powered off", state: PoweredOff, want: []string{"start VMX nogui"}},
+		{name: "powered on", state: PoweredOn},
+		{name: "paused", state: Paused, want: []string{"unpause VMX"}},
+		{name: "suspended", state: Suspended, want: []string{"start VMX nogui"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmx := writeVMFiles(t, t.TempDir(), false, 0, "")
+
+			var commands []string
+			h := NewFusion(vmx, "", "")
+			h.SetClient(vmrun.NewClient(fakePower(t, vmx, tt.state, &commands)))
+
+			if err := h.EnsureRunning(false); err != nil {
+				t.Fatal(err)
+			}
+			if want := replaceVMX(tt.want, vmx); !reflect.DeepEqual(commands, want) {
+				t.Fatalf("commands = %q, want %q", commands, want)
+			}
+		})
+	}
+}
+
+func TestHostEnsureStopped(t *testing.T) {
+	tests := []struct {
+		name  string
+		state PowerState
+		hard  bool
+		want  []string
+	}{
+		{name: "powered off", state: PoweredOff},
+		{name: "powered on", state: PoweredOn, want: []string{"stop VMX soft"}},
+		{name: "powered on hard", state: PoweredOn, hard: true, want: []string{"stop VMX hard"}},
+		{name: "paused", state: Paused, want: []string{"unpause VMX", "stop VMX soft"}},
+		{name: "paused hard", state: Paused, hard: true, want: []string{"stop VMX hard"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmx := writeVMFiles(t, t.TempDir(), false, 0, "")
+
+			var commands []string
+			h := NewFusion(vmx, "", "")
+			h.SetClient(vmrun.NewClient(fakePower(t, vmx, tt.state, &commands)))
+
+			if err := h.EnsureStopped(tt.hard); err != nil {
+				t.Fatal(err)
+			}
+			if want := replaceVMX(tt.want, vmx); !reflect.DeepEqual(commands, want) {
+				t.Fatalf("commands = %q, want %q", commands, want)
+			}
+		})
+	}
+}
+
+func TestHostWaitForStateContext(t *testing.T) {
+	vmx := writeVMFiles(t, t.TempDir(), false, 0, "")
+	var commands []string
+	h := NewFusion(vmx, "", "")
+	h.SetClient(vmrun.NewClient(fakePower(t, vmx, PoweredOff, &commands)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.WaitForStateContext(ctx, PoweredOn); err != context.DeadlineExceeded {
+		t.Fatalf("WaitForStateContext error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// replaceVMX replaces "VMX" in commands with vmx.
+func replaceVMX(commands []string, vmx string) []string {
+	var replaced []string
+	for _, c := range commands {
+		replaced = append(replaced, strings.Replace(c, "VMX", vmx, -1))
+	}
+
+	return replaced
+}
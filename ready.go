@@ -0,0 +1,210 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// ReadyStage represents a stage of WaitForGuestReady.
+type ReadyStage int
+
+const (
+	// StageTools waits until the VMware Tools are running.
+	StageTools ReadyStage = iota
+	// StageIPAddress waits until the guest reports a valid IP address.
+	StageIPAddress
+	// StageLogin waits until the guest accepts the credentials.
+	StageLogin
+)
+
+// String implements a fmt.Stringer interface.
+func (s ReadyStage) String() string {
+	switch s {
+	case StageTools:
+		return "VMware Tools"
+	case StageIPAddress:
+		return "IP address"
+	case StageLogin:
+		return "guest login"
+	default:
+		return ""
+	}
+}
+
+// DefaultReadyPollInterval is the default interval of polling the guest by WaitForGuestReady.
+const DefaultReadyPollInterval = 2 * time.Second
+
+// ReadyOptions represents the options of WaitForGuestReady.
+type ReadyOptions struct {
+	// CheckLogin verifies the guest credentials of the VM by running FileExistsInGuest on LoginProbe.
+	CheckLogin bool
+	// LoginProbe is the guest path checked by the login stage. If empty, "/" is used.
+	// It does not need to exist, since only the credential errors are checked.
+	LoginProbe string
+	// PollInterval is the interval of polling the guest. If zero, DefaultReadyPollInterval is used.
+	PollInterval time.Duration
+}
+
+// ReadyReport represents the readiness of the guest reported by WaitForGuestReady.
+type ReadyReport struct {
+	// Tools is the last state of the VMware Tools.
+	Tools vmrun.ToolsState
+	// IPAddress is the IP address of the guest, or empty if it is not reported yet.
+	IPAddress string
+	// LoginChecked reports whether the guest credentials are verified.
+	LoginChecked bool
+	// Elapsed is the time spent in each stage which is started.
+	Elapsed map[ReadyStage]time.Duration
+}
+
+// NotReadyError represents the guest which does not become ready.
+type NotReadyError struct {
+	// Stage is the stage which stalled.
+	Stage ReadyStage
+	// Elapsed is the time spent in the stage.
+	Elapsed time.Duration
+	// Err is the error of the context, or the error which is not worth retrying.
+	Err error
+	// Last is the last error of the stage before the context is done, if any.
+	Last error
+}
+
+// Error implements a error interface.
+func (e *NotReadyError) Error() string {
+	msg := "vmware: guest is not ready: waiting for " + e.Stage.String() + " for " + e.Elapsed.Round(time.Millisecond).String() + ": " + e.Err.Error()
+	if e.Last != nil {
+		msg += " (last error: " + e.Last.Error() + ")"
+	}
+
+	return msg
+}
+
+// Unwrap returns e.Err.
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// validIPAddress reports whether s is an IP address which the host can connect to,
+// which excludes the unspecified, loopback and link-local addresses.
+func validIPAddress(s string) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+
+	return !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// permanentLoginError reports whether err of the login stage is not worth retrying.
+func permanentLoginError(err error) bool {
+	return errors.Is(err, vmrun.ErrInvalidCredentials) || errors.Is(err, vmrun.ErrInteractiveLogin)
+}
+
+// WaitForGuestReady waits until the guest OS is ready for the guest operations.
+//
+// It polls the VMware Tools state until they are running, then the IP address of the guest until a valid one appears,
+// and then optionally verifies the guest credentials. The wait never ends unless the guest is ready,
+// so WaitForGuestReadyContext with the deadline is usually used.
+func (h *Host) WaitForGuestReady(opts *ReadyOptions) (*ReadyReport, error) {
+	return h.WaitForGuestReadyContext(context.Background(), opts)
+}
+
+// WaitForGuestReadyContext is like WaitForGuestReady but includes a context.
+//
+// If ctx is done before the guest is ready, the returned *NotReadyError reports the stalled stage and wraps the error of ctx.
+// The report is returned along with the error.
+func (h *Host) WaitForGuestReadyContext(ctx context.Context, opts *ReadyOptions) (*ReadyReport, error) {
+	if opts == nil {
+		opts = &ReadyOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultReadyPollInterval
+	}
+	probe := opts.LoginProbe
+	if probe == "" {
+		probe = "/"
+	}
+
+	report := &ReadyReport{Elapsed: make(map[ReadyStage]time.Duration)}
+	stages := []struct {
+		stage ReadyStage
+		skip  bool
+		poll  func() (bool, error) // reports whether the stage is done
+	}{
+		{
+			stage: StageTools,
+			poll: func() (bool, error) {
+				state, err := h.client.CheckToolsState(ctx, h.product.App(), h.vmx)
+				report.Tools = state
+				return state == vmrun.ToolsRunning, err
+			},
+		},
+		{
+			stage: StageIPAddress,
+			poll: func() (bool, error) {
+				ip, err := h.client.GetGuestIPAddress(ctx, h.product.App(), h.vmx, false)
+				if err != nil {
+					return false, err
+				}
+				ip = strings.TrimSpace(ip)
+				if !validIPAddress(ip) {
+					return false, nil
+				}
+				report.IPAddress = ip
+				return true, nil
+			},
+		},
+		{
+			stage: StageLogin,
+			skip:  !opts.CheckLogin,
+			poll: func() (bool, error) {
+				if _, err := h.client.FileExistsInGuest(ctx, h.product.App(), h.vmx, h.username, h.password, probe); err != nil {
+					return false, err
+				}
+				report.LoginChecked = true
+				return true, nil
+			},
+		},
+	}
+
+	for _, s := range stages {
+		if s.skip {
+			continue
+		}
+
+		start := time.Now()
+		var last error
+		for {
+			done, err := s.poll()
+			report.Elapsed[s.stage] = time.Since(start)
+			if done && err == nil {
+				break
+			}
+			if s.stage == StageLogin && permanentLoginError(err) {
+				return report, &NotReadyError{Stage: s.stage, Elapsed: report.Elapsed[s.stage], Err: err}
+			}
+			if err != nil && ctx.Err() == nil {
+				last = err
+			}
+
+			select {
+			case <-ctx.Done():
+				report.Elapsed[s.stage] = time.Since(start)
+				return report, &NotReadyError{Stage: s.stage, Elapsed: report.Elapsed[s.stage], Err: ctx.Err(), Last: last}
+			case <-time.After(interval):
+			}
+		}
+	}
+
+	return report, nil
+}
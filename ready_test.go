@@ -0,0 +1,135 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+func TestValidIPAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "192.168.1.10", want: true},
+		{ip: "fd00::10", want: true},
+		{ip: "169.254.10.1"},
+		{ip: "fe80::1"},
+		{ip: "127.0.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "unknown"},
+		{ip: ""},
+	}
+	for _, tt := range tests {
+		if got := validIPAddress(tt.ip); got != tt.want {
+			t.Errorf("validIPAddress(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+// fakeGuest returns the runner which replies each command with the results in order, repeating the last one.
+func fakeGuest(results map[string][]runner.Result) runner.Runner {
+	return runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		command := args[2]
+		if args[2] == "-gu" {
+			command = args[6]
+		}
+		rs := results[command]
+		if len(rs) == 0 {
+			return &runner.Result{ExitCode: 255, Stdout: "Error: unexpected command " + command}, nil
+		}
+		r := rs[0]
+		if len(rs) > 1 {
+			results[command] = rs[1:]
+		}
+		return &r, nil
+	})
+}
+
+func TestHostWaitForGuestReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      ReadyOptions
+		results   map[string][]runner.Result
+		wantIP    string
+		wantLogin bool
+		wantStage ReadyStage
+		wantErr   error
+	}{
+		{
+			name: "ready",
+			opts: ReadyOptions{CheckLogin: true},
+			results: map[string][]runner.Result{
+				"checkToolsState":   {{Stdout: "installed\n"}, {Stdout: "running\n"}},
+				"getGuestIPAddress": {{ExitCode: 255, Stdout: "Error: Unable to get the IP address\n"}, {Stdout: "169.254.10.1\n"}, {Stdout: "192.168.1.10\n"}},
+				"fileExistsInGuest": {{ExitCode: 255, Stdout: "Error: The VMware Tools are not running in the virtual machine\n"}, {Stdout: "The file exists.\n"}},
+			},
+			wantIP:    "192.168.1.10",
+			wantLogin: true,
+		},
+		{
+			name: "tools stalled",
+			results: map[string][]runner.Result{
+				"checkToolsState": {{Stdout: "installed\n"}},
+			},
+			wantStage: StageTools,
+			wantErr:   context.DeadlineExceeded,
+		},
+		{
+			name: "ip address stalled",
+			results: map[string][]runner.Result{
+				"checkToolsState":   {{Stdout: "running\n"}},
+				"getGuestIPAddress": {{Stdout: "169.254.10.1\n"}},
+			},
+			wantStage: StageIPAddress,
+			wantErr:   context.DeadlineExceeded,
+		},
+		{
+			name: "invalid credentials",
+			opts: ReadyOptions{CheckLogin: true},
+			results: map[string][]runner.Result{
+				"checkToolsState":   {{Stdout: "running\n"}},
+				"getGuestIPAddress": {{Stdout: "192.168.1.10\n"}},
+				"fileExistsInGuest": {{ExitCode: 255, Stdout: "Error: Invalid user name or password for the guest OS\n"}},
+			},
+			wantIP:    "192.168.1.10",
+			wantStage: StageLogin,
+			wantErr:   vmrun.ErrInvalidCredentials,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewFusion(testVMX, "user", "pass")
+			h.SetClient(vmrun.NewClient(fakeGuest(tt.results)))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			tt.opts.PollInterval = time.Millisecond
+			report, err := h.WaitForGuestReadyContext(ctx, &tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitForGuestReadyContext error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				var notReady *NotReadyError
+				if !errors.As(err, &notReady) || notReady.Stage != tt.wantStage {
+					t.Fatalf("WaitForGuestReadyContext error = %v, want stage %v", err, tt.wantStage)
+				}
+			}
+			if report.IPAddress != tt.wantIP {
+				t.Errorf("IPAddress = %q, want %q", report.IPAddress, tt.wantIP)
+			}
+			if report.LoginChecked != tt.wantLogin {
+				t.Errorf("LoginChecked = %v, want %v", report.LoginChecked, tt.wantLogin)
+			}
+		})
+	}
+}
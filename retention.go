@@ -0,0 +1,181 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-vm/vmware/vmsd"
+)
+
+// RetentionPolicy represents the rules of the snapshots removed by PruneSnapshots.
+//
+// A snapshot matching Prefix is removed if it is not one of the newest KeepLast snapshots,
+// or if it is older than MaxAge. The zero KeepLast and MaxAge disable each rule.
+// The snapshots which creation time is missing from the .vmsd file are not managed, since their age is unknown.
+type RetentionPolicy struct {
+	// Prefix is the name prefix of the snapshots managed by the policy. If empty, all snapshots are managed.
+	Prefix string
+	// KeepLast is the number of the newest managed snapshots to keep.
+	KeepLast int
+	// MaxAge is the maximum age of the managed snapshots.
+	MaxAge time.Duration
+	// DryRun reports the snapshots which would be removed without removing them.
+	DryRun bool
+	// Now is the time which the age is measured from. If zero, time.Now is used.
+	Now time.Time
+}
+
+// SnapshotInfo represents a snapshot reported by PruneSnapshots.
+type SnapshotInfo struct {
+	// Name is the display name of the snapshot.
+	Name string
+	// Path is the slash separated names from the root snapshot, at the time of the report.
+	Path string
+	// CreateTime is the creation time of the snapshot.
+	CreateTime time.Time
+	// Size is the approximate disk space reclaimed by removing the snapshot.
+	Size int64
+}
+
+// PruneResult represents the result of PruneSnapshots.
+type PruneResult struct {
+	// Removed is the snapshots removed, or which would be removed in the dry run, in the oldest first order.
+	Removed []SnapshotInfo
+	// Protected is the snapshots which the policy selected but kept, because linked clones depend on them.
+	Protected []SnapshotInfo
+	// Reclaimed is the total Size of the Removed snapshots.
+	Reclaimed int64
+}
+
+// snapshotPath returns the slash separated names from the root to s.
+func snapshotPath(f *vmsd.File, s *vmsd.Snapshot) string {
+	names := []string{s.DisplayName}
+	for p := s.Parent; p != 0; {
+		parent, err := f.Snapshot(p)
+		if err != nil || len(names) > len(f.Snapshots()) {
+			break
+		}
+		names = append([]string{parent.DisplayName}, names...)
+		p = parent.Parent
+	}
+
+	return strings.Join(names, "/")
+}
+
+// cloneDependencies returns the uids of the snapshots which have linked clones and their ancestors,
+// since removing an ancestor rewrites the disk chain which the linked clones are based on.
+func cloneDependencies(f *vmsd.File) map[int]bool {
+	deps := make(map[int]bool)
+	for _, s := range f.Snapshots() {
+		if len(s.Clones) == 0 {
+			continue
+		}
+		for uid := s.UID; uid != 0 && !deps[uid]; {
+			deps[uid] = true
+			parent, err := f.Snapshot(uid)
+			if err != nil {
+				break
+			}
+			uid = parent.Parent
+		}
+	}
+
+	return deps
+}
+
+// selectSnapshots returns the snapshots to remove by the policy in the oldest first order.
+func (p *RetentionPolicy) selectSnapshots(f *vmsd.File) []*vmsd.Snapshot {
+	now := p.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var managed []*vmsd.Snapshot
+	for _, s := range f.Snapshots() {
+		if strings.HasPrefix(s.DisplayName, p.Prefix) && !s.CreateTime.IsZero() {
+			managed = append(managed, s)
+		}
+	}
+	// newest first
+	sort.SliceStable(managed, func(i, j int) bool {
+		return managed[i].CreateTime.After(managed[j].CreateTime)
+	})
+
+	var selected []*vmsd.Snapshot
+	for i, s := range managed {
+		if (p.KeepLast > 0 && i >= p.KeepLast) || (p.MaxAge > 0 && now.Sub(s.CreateTime) > p.MaxAge) {
+			selected = append([]*vmsd.Snapshot{s}, selected...)
+		}
+	}
+
+	return selected
+}
+
+// PruneSnapshots removes the snapshots of h selected by the policy. The nil policy removes nothing.
+//
+// The snapshots which linked clones depend on are never removed. The children of a removed snapshot are kept.
+// The snapshot metadata is read from the .vmsd file next to the .vmx file, so h must be a local VM.
+func (h *Host) PruneSnapshots(policy *RetentionPolicy) (*PruneResult, error) {
+	return h.PruneSnapshotsContext(context.Background(), policy)
+}
+
+// PruneSnapshotsContext is like PruneSnapshots but includes a context.
+func (h *Host) PruneSnapshotsContext(ctx context.Context, policy *RetentionPolicy) (*PruneResult, error) {
+	if policy == nil {
+		policy = &RetentionPolicy{}
+	}
+	if err := h.supports("deleteSnapshot"); err != nil {
+		return nil, err
+	}
+
+	vmsdPath := vmsd.Path(h.vmx)
+	f, err := vmsd.ReadFile(vmsdPath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(h.vmx)
+	deps := cloneDependencies(f)
+	result := &PruneResult{}
+	for _, selected := range policy.selectSnapshots(f) {
+		// the paths are changed by the removal of the ancestors, so the metadata is re-read each time.
+		s, err := f.Snapshot(selected.UID)
+		if err != nil {
+			return result, err
+		}
+		size, err := s.Size(dir)
+		if err != nil {
+			return result, err
+		}
+		info := SnapshotInfo{
+			Name:       s.DisplayName,
+			Path:       snapshotPath(f, s),
+			CreateTime: s.CreateTime,
+			Size:       size,
+		}
+
+		if deps[s.UID] {
+			result.Protected = append(result.Protected, info)
+			continue
+		}
+		if !policy.DryRun {
+			if err := h.client.DeleteSnapshot(ctx, h.product.App(), h.vmx, info.Path, false); err != nil {
+				return result, err
+			}
+			if f, err = vmsd.ReadFile(vmsdPath); err != nil {
+				return result, err
+			}
+		}
+		result.Removed = append(result.Removed, info)
+		result.Reclaimed += info.Size
+	}
+
+	return result, nil
+}
@@ -0,0 +1,202 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vmrun"
+	"github.com/go-vm/vmware/vmsd"
+)
+
+var testNow = time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// writeTestVMSD writes the .vmsd file of the chain of snapshots base -> ci-1 -> ... -> ci-5 to dir,
+// which ci-1 has a linked clone, and returns the .vmx file path.
+func writeTestVMSD(t *testing.T, dir string) string {
+	t.Helper()
+
+	ages := []int{30, 10, 8, 6, 3, 1} // days
+	var b strings.Builder
+	b.WriteString(".encoding = \"UTF-8\"\n")
+	for i, age := range ages {
+		name := "base"
+		if i > 0 {
+			name = "ci-" + strconv.Itoa(i)
+		}
+		usec := testNow.Add(-time.Duration(age)*24*time.Hour).UnixNano() / 1e3
+		fmt.Fprintf(&b, "snapshot%d.uid = \"%d\"\n", i, i+1)
+		if i > 0 {
+			fmt.Fprintf(&b, "snapshot%d.parent = \"%d\"\n", i, i)
+		}
+		fmt.Fprintf(&b, "snapshot%d.displayName = %q\n", i, name)
+		fmt.Fprintf(&b, "snapshot%d.filename = \"test-Snapshot%d.vmsn\"\n", i, i+1)
+		fmt.Fprintf(&b, "snapshot%d.createTimeHigh = \"%d\"\n", i, usec>>32)
+		fmt.Fprintf(&b, "snapshot%d.createTimeLow = \"%d\"\n", i, int32(uint32(usec)))
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("test-Snapshot%d.vmsn", i+1)), make([]byte, 10*(i+1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.WriteString("snapshot1.numClones = \"1\"\nsnapshot1.clone0 = \"/vm/clone.vmx\"\n")
+	b.WriteString("snapshot.current = \"6\"\nsnapshot.numSnapshots = \"6\"\n")
+
+	vmx := filepath.Join(dir, "test.vmx")
+	if err := ioutil.WriteFile(vmsd.Path(vmx), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return vmx
+}
+
+// deleteTestSnapshot emulates the deleteSnapshot command on the .vmsd file, which re-parents the children.
+func deleteTestSnapshot(t *testing.T, vmx, path string) {
+	t.Helper()
+
+	f, err := vmsd.ReadFile(vmsd.Path(vmx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range f.Snapshots() {
+		if snapshotPath(f, s) != path {
+			continue
+		}
+		for _, child := range f.Children(s.UID) {
+			f.Set(fmt.Sprintf("snapshot%d.parent", child.Index), strconv.Itoa(s.Parent))
+		}
+		prefix := fmt.Sprintf("snapshot%d.", s.Index)
+		for _, key := range f.Keys() {
+			if strings.HasPrefix(key, prefix) {
+				f.Delete(key)
+			}
+		}
+		if err := f.WriteFile(vmsd.Path(vmx)); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	t.Fatalf("snapshot %q not found", path)
+}
+
+func TestHostPruneSnapshots(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        *RetentionPolicy
+		noCreateTime  []int // the indexes of the snapshots which creation time is removed
+		wantRemoved   []string
+		wantDeleted   []string
+		wantProtected []string
+		wantReclaimed int64
+	}{
+		{
+			name:          "keep last",
+			policy:        &RetentionPolicy{Prefix: "ci-", KeepLast: 2, Now: testNow},
+			wantRemoved:   []string{"base/ci-1/ci-2", "base/ci-1/ci-3"},
+			wantDeleted:   []string{"base/ci-1/ci-2", "base/ci-1/ci-3"},
+			wantProtected: []string{"base/ci-1"},
+			wantReclaimed: 30 + 40,
+		},
+		{
+			name:          "dry run",
+			policy:        &RetentionPolicy{Prefix: "ci-", KeepLast: 2, DryRun: true, Now: testNow},
+			wantRemoved:   []string{"base/ci-1/ci-2", "base/ci-1/ci-2/ci-3"},
+			wantProtected: []string{"base/ci-1"},
+			wantReclaimed: 30 + 40,
+		},
+		{
+			name:          "max age",
+			policy:        &RetentionPolicy{Prefix: "ci-", MaxAge: 7 * 24 * time.Hour, Now: testNow},
+			wantRemoved:   []string{"base/ci-1/ci-2"},
+			wantDeleted:   []string{"base/ci-1/ci-2"},
+			wantProtected: []string{"base/ci-1"},
+			wantReclaimed: 30,
+		},
+		{
+			name:          "unknown age",
+			policy:        &RetentionPolicy{Prefix: "ci-", MaxAge: 7 * 24 * time.Hour, Now: testNow},
+			noCreateTime:  []int{2},
+			wantProtected: []string{"base/ci-1"},
+		},
+		{
+			name:          "keep last with unknown age",
+			policy:        &RetentionPolicy{Prefix: "ci-", KeepLast: 2, Now: testNow},
+			noCreateTime:  []int{5},
+			wantRemoved:   []string{"base/ci-1/ci-2"},
+			wantDeleted:   []string{"base/ci-1/ci-2"},
+			wantProtected: []string{"base/ci-1"},
+			wantReclaimed: 30,
+		},
+		{
+			name:   "no rule",
+			policy: &RetentionPolicy{Now: testNow},
+		},
+		{
+			name: "nil policy",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmx := writeTestVMSD(t, t.TempDir())
+			if len(tt.noCreateTime) > 0 {
+				f, err := vmsd.ReadFile(vmsd.Path(vmx))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, i := range tt.noCreateTime {
+					f.Delete(fmt.Sprintf("snapshot%d.createTimeHigh", i))
+					f.Delete(fmt.Sprintf("snapshot%d.createTimeLow", i))
+				}
+				if err := f.WriteFile(vmsd.Path(vmx)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var deleted []string
+			h := NewFusion(vmx, "", "")
+			h.SetClient(vmrun.NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+				if args[2] != "deleteSnapshot" || len(args) != 5 {
+					t.Fatalf("unexpected args %q", args)
+				}
+				deleted = append(deleted, args[4])
+				deleteTestSnapshot(t, vmx, args[4])
+				return &runner.Result{}, nil
+			})))
+
+			result, err := h.PruneSnapshots(tt.policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			paths := func(infos []SnapshotInfo) []string {
+				var paths []string
+				for _, info := range infos {
+					paths = append(paths, info.Path)
+				}
+				return paths
+			}
+			if got := paths(result.Removed); !reflect.DeepEqual(got, tt.wantRemoved) {
+				t.Errorf("Removed = %q, want %q", got, tt.wantRemoved)
+			}
+			if got := paths(result.Protected); !reflect.DeepEqual(got, tt.wantProtected) {
+				t.Errorf("Protected = %q, want %q", got, tt.wantProtected)
+			}
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("deleteSnapshot = %q, want %q", deleted, tt.wantDeleted)
+			}
+			if result.Reclaimed != tt.wantReclaimed {
+				t.Errorf("Reclaimed = %d, want %d", result.Reclaimed, tt.wantReclaimed)
+			}
+		})
+	}
+}
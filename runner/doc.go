@@ -0,0 +1,6 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package runner implements a command runner interface used by VMware command wrappers.
+package runner
@@ -0,0 +1,61 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+)
+
+// Result represents a result of the command.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Runner represents a command runner.
+type Runner interface {
+	// Run runs the name command with args and returns the result.
+	//
+	// The returned error reports the command could not be run or was canceled by ctx,
+	// a non-zero exit code is reported by Result.ExitCode with a nil error.
+	Run(ctx context.Context, name string, args ...string) (*Result, error)
+}
+
+// Exec represents a Runner which runs the command on the host using os/exec package.
+type Exec struct{}
+
+var _ Runner = Exec{}
+
+// Run implements a Runner interface.
+//
+// The command process is killed when ctx is done.
+func (Exec) Run(ctx context.Context, name string, args ...string) (*Result, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	result := &Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, ctxErr
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			return result, nil
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,6 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package runnertest implements fake runners for testing VMware command wrappers.
+package runnertest
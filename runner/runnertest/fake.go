@@ -0,0 +1,107 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runnertest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/go-vm/vmware/runner"
+)
+
+// Call represents a command invocation received by the fake runner.
+type Call struct {
+	Name string
+	Args []string
+}
+
+// Step represents a scripted response of the Fake.
+type Step struct {
+	// Args is the expected command arguments. If nil, any arguments are accepted.
+	Args []string
+	// Result is the result of the command.
+	Result runner.Result
+	// Err is the error returned instead of Result, such as the command is not found.
+	Err error
+	// Block blocks the command until the context is done, such as the hung command.
+	Block bool
+}
+
+// Fake represents a scripted runner.Runner.
+//
+// Each Run call consumes the next step of Steps in order, and records the invocation to Calls.
+type Fake struct {
+	Steps []Step
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ runner.Runner = (*Fake)(nil)
+
+// NewFake returns the new Fake with steps.
+func NewFake(steps ...Step) *Fake {
+	return &Fake{Steps: steps}
+}
+
+// Run implements a runner.Runner interface.
+func (f *Fake) Run(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+	f.mu.Lock()
+	n := len(f.calls)
+	f.calls = append(f.calls, Call{Name: name, Args: append([]string(nil), args...)})
+	f.mu.Unlock()
+
+	if n >= len(f.Steps) {
+		return nil, fmt.Errorf("runnertest: unexpected call #%d: %s %q", n, name, args)
+	}
+	step := f.Steps[n]
+
+	if step.Args != nil && !reflect.DeepEqual(step.Args, args) {
+		return nil, fmt.Errorf("runnertest: call #%d: got args %q, want %q", n, args, step.Args)
+	}
+
+	if step.Block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if step.Err != nil {
+		return nil, step.Err
+	}
+
+	result := step.Result
+	return &result, nil
+}
+
+// Calls returns the invocations received by f.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Done reports an error if any step of f has not been consumed.
+func (f *Fake) Done() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.calls) < len(f.Steps) {
+		return fmt.Errorf("runnertest: %d of %d steps were not called", len(f.Steps)-len(f.calls), len(f.Steps))
+	}
+
+	return nil
+}
+
+// Func represents a runner.Runner implemented by the function, which is useful to emulate stateful commands.
+type Func func(ctx context.Context, name string, args ...string) (*runner.Result, error)
+
+var _ runner.Runner = Func(nil)
+
+// Run implements a runner.Runner interface.
+func (fn Func) Run(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+	return fn(ctx, name, args...)
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runnertest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-vm/vmware/internal/vmwareutil"
+	"github.com/go-vm/vmware/runner"
+)
+
+// Record represents a recorded command invocation and the result.
+type Record struct {
+	Name     string   `json:"name"`
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exitCode,omitempty"`
+	Err      string   `json:"err,omitempty"`
+}
+
+// WriteRecords writes records to w in JSON Lines format.
+func WriteRecords(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadRecords reads the records written by WriteRecords from r.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var record Record
+		if err := dec.Decode(&record); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// redactArgs returns the copy of args which passwords of the vmrun authentication flags are redacted,
+// so that the records do not leak them. The args of the other commands are returned as is.
+func redactArgs(name string, args []string) []string {
+	// the records may be replayed on another OS, so both path separators are accepted.
+	base := name[strings.LastIndexAny(name, `/\`)+1:]
+	if strings.TrimSuffix(base, ".exe") != "vmrun" {
+		return append([]string(nil), args...)
+	}
+
+	return vmwareutil.RedactArgs(args)
+}
+
+// Recorder represents a runner.Runner which records the invocations of the underlying Runner.
+//
+// The passwords in the vmrun args are recorded as redacted.
+type Recorder struct {
+	Runner runner.Runner
+
+	mu      sync.Mutex
+	records []Record
+}
+
+var _ runner.Runner = (*Recorder)(nil)
+
+// NewRecorder returns the new Recorder which records invocations of r.
+func NewRecorder(r runner.Runner) *Recorder {
+	return &Recorder{Runner: r}
+}
+
+// Run implements a runner.Runner interface.
+func (r *Recorder) Run(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+	result, err := r.Runner.Run(ctx, name, args...)
+
+	record := Record{
+		Name: name,
+		Args: redactArgs(name, args),
+	}
+	if result != nil {
+		record.Stdout = result.Stdout
+		record.Stderr = result.Stderr
+		record.ExitCode = result.ExitCode
+	}
+	if err != nil {
+		record.Err = err.Error()
+	}
+
+	r.mu.Lock()
+	r.records = append(r.records, record)
+	r.mu.Unlock()
+
+	return result, err
+}
+
+// Records returns the recorded invocations.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...)
+}
+
+// Replayer represents a runner.Runner which replays the recorded invocations in order.
+//
+// The command name is not compared so that the records are portable between hosts.
+// The vmrun args are compared after the passwords are redacted as Recorder does.
+type Replayer struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+var _ runner.Runner = (*Replayer)(nil)
+
+// NewReplayer returns the new Replayer which replays records.
+func NewReplayer(records []Record) *Replayer {
+	return &Replayer{records: records}
+}
+
+// Run implements a runner.Runner interface.
+func (r *Replayer) Run(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.records) == 0 {
+		return nil, fmt.Errorf("runnertest: no more records to replay: %s %q", name, args)
+	}
+	record := r.records[0]
+	args = redactArgs(name, args)
+	if !reflect.DeepEqual(record.Args, args) && !(len(record.Args) == 0 && len(args) == 0) {
+		return nil, fmt.Errorf("runnertest: got args %q, want %q", args, record.Args)
+	}
+	r.records = r.records[1:]
+
+	if record.Err != "" {
+		return nil, errors.New(record.Err)
+	}
+
+	return &runner.Result{
+		Stdout:   record.Stdout,
+		Stderr:   record.Stderr,
+		ExitCode: record.ExitCode,
+	}, nil
+}
+
+// Done reports an error if any record has not been replayed.
+func (r *Replayer) Done() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.records) > 0 {
+		return fmt.Errorf("runnertest: %d records were not replayed", len(r.records))
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runnertest
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-vm/vmware/runner"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+
+	fake := NewFake(
+		Step{Result: runner.Result{Stdout: "Total running VMs: 0\n"}},
+		Step{Result: runner.Result{Stdout: "Error: Unrecognized command: foo\n", ExitCode: 255}},
+	)
+	recorder := NewRecorder(fake)
+
+	want := make([]*runner.Result, 0, 2)
+	for _, args := range [][]string{{"-T", "fusion", "list"}, {"-T", "fusion", "foo"}} {
+		result, err := recorder.Run(ctx, "vmrun", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, result)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, recorder.Records()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer := NewReplayer(records)
+	for i, args := range [][]string{{"-T", "fusion", "list"}, {"-T", "fusion", "foo"}} {
+		got, err := replayer.Run(ctx, "/usr/bin/vmrun", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Fatalf("Run(%q) = %+v, want %+v", args, got, want[i])
+		}
+	}
+	if err := replayer.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := replayer.Run(ctx, "vmrun", "list"); err == nil {
+		t.Fatal("Run after all records are replayed: error = nil, want error")
+	}
+}
+
+func TestRecordReplayRedact(t *testing.T) {
+	ctx := context.Background()
+	args := []string{"-T", "ws", "-vp", "vmpass", "-gu", "user", "-gp", "guestpass", "listprocessesinguest", "/vm/test.vmx"}
+
+	recorder := NewRecorder(NewFake(Step{Args: args}))
+	if _, err := recorder.Run(ctx, "/usr/bin/vmrun", args...); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteRecords(&buf, recorder.Records()); err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"vmpass", "guestpass"} {
+		if bytes.Contains(buf.Bytes(), []byte(secret)) {
+			t.Errorf("records contain %q: %s", secret, buf.Bytes())
+		}
+	}
+
+	records, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := NewReplayer(records)
+	if _, err := replayer.Run(ctx, `C:\VMware\vmrun.exe`, args...); err != nil {
+		t.Fatal(err)
+	}
+	if err := replayer.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the args of the other commands are not redacted.
+	vdisk := []string{"-p", "/mnt/disk"}
+	recorder = NewRecorder(NewFake(Step{Args: vdisk}))
+	if _, err := recorder.Run(ctx, "vmware-vdiskmanager", vdisk...); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorder.Records()[0].Args; !reflect.DeepEqual(got, vdisk) {
+		t.Errorf("Args = %q, want %q", got, vdisk)
+	}
+}
+
+func TestFakeUnexpectedArgs(t *testing.T) {
+	fake := NewFake(Step{Args: []string{"start"}})
+
+	if _, err := fake.Run(context.Background(), "vmrun", "stop"); err == nil {
+		t.Fatal("Run error = nil, want error")
+	}
+}
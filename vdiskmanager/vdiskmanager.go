@@ -5,12 +5,13 @@
 package vdiskmanager
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 
 	"github.com/go-vm/vmware/internal/vmwareutil"
+	"github.com/go-vm/vmware/runner"
 )
 
 // VMware Virtual Disk Manager - build 5192483.
@@ -76,20 +77,70 @@ import (
 
 var vdiskmanagerPath = vmwareutil.LookPath("vmware-vdiskmanager")
 
+// Client represents a vmware-vdiskmanager command client.
+type Client struct {
+	// Path is the vmware-vdiskmanager command path.
+	// If empty, the path detected from the installed VMware application is used.
+	Path string
+	// Runner runs the vmware-vdiskmanager command. If nil, runner.Exec is used.
+	Runner runner.Runner
+}
+
+// DefaultClient is the default Client used by the package level functions.
+var DefaultClient = &Client{}
+
+// NewClient returns the new Client which runs the vmware-vdiskmanager command with r.
+func NewClient(r runner.Runner) *Client {
+	return &Client{Runner: r}
+}
+
 // vdiskmanager wrapper of vmware-vdiskmanager command.
-func vdiskmanager(args ...string) error {
-	cmd := exec.Command(vdiskmanagerPath, args...)
+func (c *Client) vdiskmanager(ctx context.Context, args ...string) error {
+	var r runner.Runner = runner.Exec{}
+	if c.Runner != nil {
+		r = c.Runner
+	}
+	path := vdiskmanagerPath
+	if c.Path != "" {
+		path = c.Path
+	}
 
-	if err := cmd.Run(); err != nil {
-		if runErr := err.(*exec.ExitError); runErr != nil {
-			return runErr
-		}
+	result, err := r.Run(ctx, path, args...)
+	if err != nil {
 		return err
 	}
+	if result.ExitCode != 0 {
+		return &Error{Args: args, ExitCode: result.ExitCode, Stdout: result.Stdout, Stderr: result.Stderr}
+	}
 
 	return nil
 }
 
+// Error represents a failed vmware-vdiskmanager command.
+type Error struct {
+	// Args is the vmware-vdiskmanager arguments.
+	Args []string
+	// ExitCode is the exit code of vmware-vdiskmanager.
+	ExitCode int
+	// Stdout is the standard output of vmware-vdiskmanager.
+	Stdout string
+	// Stderr is the standard error of vmware-vdiskmanager.
+	Stderr string
+}
+
+// Error implements a error interface.
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Stdout)
+	if msg == "" {
+		msg = strings.TrimSpace(e.Stderr)
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+
+	return "vmware-vdiskmanager: " + msg
+}
+
 // AdapterType represents a adapter type.
 type AdapterType int
 
@@ -131,6 +182,11 @@ const (
 
 // Create create disk.
 func Create(dst string, config *Config) error {
+	return DefaultClient.Create(context.Background(), dst, config)
+}
+
+// Create create disk.
+func (c *Client) Create(ctx context.Context, dst string, config *Config) error {
 	size := defaultSize
 	diskType := defaultDiskType
 	adapter := defaultAdapter
@@ -151,50 +207,95 @@ func Create(dst string, config *Config) error {
 		dst = dst + ".vmdk"
 	}
 
-	return vdiskmanager("-c", "-s", fmt.Sprintf("%dMB", size), "-t", strconv.Itoa(diskType), "-a", adapter.String(), dst)
+	return c.vdiskmanager(ctx, "-c", "-s", fmt.Sprintf("%dMB", size), "-t", strconv.Itoa(diskType), "-a", adapter.String(), dst)
 }
 
 // Defrag defragment the specified virtual disk.
 func Defrag(src string) error {
-	return vdiskmanager("-d", src)
+	return DefaultClient.Defrag(context.Background(), src)
+}
+
+// Defrag defragment the specified virtual disk.
+func (c *Client) Defrag(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-d", src)
 }
 
 // Shrink shrink the specified virtual disk.
 func Shrink(src string) error {
-	return vdiskmanager("-k", src)
+	return DefaultClient.Shrink(context.Background(), src)
+}
+
+// Shrink shrink the specified virtual disk.
+func (c *Client) Shrink(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-k", src)
 }
 
 // Rename rename the specified virtual disk.
 func Rename(src, dst string) error {
-	return vdiskmanager("-n", src, dst)
+	return DefaultClient.Rename(context.Background(), src, dst)
+}
+
+// Rename rename the specified virtual disk.
+func (c *Client) Rename(ctx context.Context, src, dst string) error {
+	return c.vdiskmanager(ctx, "-n", src, dst)
 }
 
 // Prepare prepare the mounted virtual disk specified by the volume path for shrinking.
 func Prepare(src string) error {
-	return vdiskmanager("-p", src)
+	return DefaultClient.Prepare(context.Background(), src)
+}
+
+// Prepare prepare the mounted virtual disk specified by the volume path for shrinking.
+func (c *Client) Prepare(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-p", src)
 }
 
 // Convert convert the specified disk.
 func Convert(src, dst string, diskType int) error {
-	return vdiskmanager("-r", src, "-t", strconv.Itoa(diskType), dst)
+	return DefaultClient.Convert(context.Background(), src, dst, diskType)
+}
+
+// Convert convert the specified disk.
+func (c *Client) Convert(ctx context.Context, src, dst string, diskType int) error {
+	return c.vdiskmanager(ctx, "-r", src, "-t", strconv.Itoa(diskType), dst)
 }
 
 // Expand expand the disk to the specified capacity.
 func Expand(capacity int, src string) error {
-	return vdiskmanager("-x", fmt.Sprintf("%dMB", capacity), src)
+	return DefaultClient.Expand(context.Background(), capacity, src)
+}
+
+// Expand expand the disk to the specified capacity.
+func (c *Client) Expand(ctx context.Context, capacity int, src string) error {
+	return c.vdiskmanager(ctx, "-x", fmt.Sprintf("%dMB", capacity), src)
 }
 
 // Repair check a sparse virtual disk for consistency and attempt to repair any errors.
 func Repair(src string) error {
-	return vdiskmanager("-R", src)
+	return DefaultClient.Repair(context.Background(), src)
+}
+
+// Repair check a sparse virtual disk for consistency and attempt to repair any errors.
+func (c *Client) Repair(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-R", src)
 }
 
 // Check check for disk chain consistency.
 func Check(src string) error {
-	return vdiskmanager("-e", src)
+	return DefaultClient.Check(context.Background(), src)
+}
+
+// Check check for disk chain consistency.
+func (c *Client) Check(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-e", src)
 }
 
 // Delete make disk deletable.
 func Delete(src string) error {
-	return vdiskmanager("-D", src)
+	return DefaultClient.Delete(context.Background(), src)
+}
+
+// Delete make disk deletable.
+func (c *Client) Delete(ctx context.Context, src string) error {
+	return c.vdiskmanager(ctx, "-D", src)
 }
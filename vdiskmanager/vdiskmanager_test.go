@@ -5,17 +5,32 @@
 package vdiskmanager
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
 )
 
 func createTestVDMK(dst string) error {
 	return Create(dst, nil)
 }
 
+// skipIfNotInstalled skips the test which runs the real vmware-vdiskmanager command if it is not installed.
+func skipIfNotInstalled(t *testing.T) {
+	t.Helper()
+	if _, err := os.Stat(vdiskmanagerPath); err != nil {
+		t.Skipf("vmware-vdiskmanager is not installed: %v", err)
+	}
+}
+
 func TestCreate(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		dst    string
 		config *Config
@@ -102,6 +117,8 @@ func TestCreate(t *testing.T) {
 }
 
 func TestDefrag(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 	}
@@ -132,6 +149,8 @@ func TestDefrag(t *testing.T) {
 }
 
 func TestShrink(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 	}
@@ -162,6 +181,8 @@ func TestShrink(t *testing.T) {
 }
 
 func TestRename(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 		dst string
@@ -231,6 +252,8 @@ func TestRename(t *testing.T) {
 // }
 
 func TestConvert(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src      string
 		dst      string
@@ -273,6 +296,8 @@ func TestConvert(t *testing.T) {
 }
 
 func TestExpand(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		capacity int
 		src      string
@@ -307,6 +332,8 @@ func TestExpand(t *testing.T) {
 }
 
 func TestRepair(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 	}
@@ -339,6 +366,8 @@ func TestRepair(t *testing.T) {
 }
 
 func TestCheck(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 	}
@@ -371,6 +400,8 @@ func TestCheck(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
+	skipIfNotInstalled(t)
+
 	type args struct {
 		src string
 	}
@@ -404,3 +435,79 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(c *Client) error
+		wantArgs []string
+		result   runner.Result
+		wantErr  string
+	}{
+		{
+			name:     "create default",
+			run:      func(c *Client) error { return c.Create(context.Background(), "create", nil) },
+			wantArgs: []string{"-c", "-s", "20000MB", "-t", "0", "-a", "lsilogic", "create.vmdk"},
+		},
+		{
+			name: "create with config",
+			run: func(c *Client) error {
+				return c.Create(context.Background(), "create.vmdk", &Config{Size: 50000, DiskType: 1, Adapter: Ide})
+			},
+			wantArgs: []string{"-c", "-s", "50000MB", "-t", "1", "-a", "ide", "create.vmdk"},
+		},
+		{
+			name:     "rename",
+			run:      func(c *Client) error { return c.Rename(context.Background(), "src.vmdk", "dst.vmdk") },
+			wantArgs: []string{"-n", "src.vmdk", "dst.vmdk"},
+		},
+		{
+			name:     "convert",
+			run:      func(c *Client) error { return c.Convert(context.Background(), "src.vmdk", "dst.vmdk", 1) },
+			wantArgs: []string{"-r", "src.vmdk", "-t", "1", "dst.vmdk"},
+		},
+		{
+			name:     "expand",
+			run:      func(c *Client) error { return c.Expand(context.Background(), 30000, "expand.vmdk") },
+			wantArgs: []string{"-x", "30000MB", "expand.vmdk"},
+		},
+		{
+			name:     "check failed",
+			run:      func(c *Client) error { return c.Check(context.Background(), "check.vmdk") },
+			wantArgs: []string{"-e", "check.vmdk"},
+			result:   runner.Result{Stdout: "The disk chain is not consistent\n", Stderr: "Failed to open the disk\n", ExitCode: 1},
+			wantErr:  "vmware-vdiskmanager: The disk chain is not consistent",
+		},
+		{
+			name:     "stderr only",
+			run:      func(c *Client) error { return c.Check(context.Background(), "check.vmdk") },
+			wantArgs: []string{"-e", "check.vmdk"},
+			result:   runner.Result{Stderr: "Failed to open the disk\n", ExitCode: 1},
+			wantErr:  "vmware-vdiskmanager: Failed to open the disk",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := runnertest.NewFake(runnertest.Step{Args: tt.wantArgs, Result: tt.result})
+			c := &Client{Path: "vmware-vdiskmanager", Runner: fake}
+
+			err := tt.run(c)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				var vdiskErr *Error
+				if !errors.As(err, &vdiskErr) || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want *Error %q", err, tt.wantErr)
+				}
+				if vdiskErr.Stdout != tt.result.Stdout || vdiskErr.Stderr != tt.result.Stderr {
+					t.Fatalf("Stdout, Stderr = %q, %q, want %q, %q", vdiskErr.Stdout, vdiskErr.Stderr, tt.result.Stdout, tt.result.Stderr)
+				}
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+import (
+	"context"
+
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// PowerManager represents the power operations of a VM.
+type PowerManager interface {
+	StartContext(ctx context.Context, gui bool) error
+	ShutDownContext(ctx context.Context) error
+	HaltContext(ctx context.Context) error
+	ResetContext(ctx context.Context) error
+	RestartContext(ctx context.Context) error
+	SuspendContext(ctx context.Context, hard bool) error
+	PauseContext(ctx context.Context) error
+	UnpauseContext(ctx context.Context) error
+}
+
+// SnapshotManager represents the snapshot operations of a VM.
+type SnapshotManager interface {
+	ListSnapshotsContext(ctx context.Context) ([]string, int, error)
+	SnapshotContext(ctx context.Context, snapshotName string) error
+	DeleteSnapshotContext(ctx context.Context, snapshotName string, deleteChildren bool) error
+	RevertToSnapshotContext(ctx context.Context, snapshotName string) error
+}
+
+// GuestManager represents the guest OS operations of a VM.
+type GuestManager interface {
+	RunProgramInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, cmdPath string, cmdArgs ...string) error
+	RunScriptInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, interpreter, script string) error
+	ListProcessesInGuestContext(ctx context.Context) ([]vmrun.ListProcessesInGuestInfo, error)
+	KillProcessInGuestContext(ctx context.Context, pid int) error
+	FileExistsInGuestContext(ctx context.Context, filename string) (bool, error)
+	DirectoryExistsInGuestContext(ctx context.Context, dir string) (bool, error)
+	DeleteFileInGuestContext(ctx context.Context, filename string) error
+	CreateDirectoryInGuestContext(ctx context.Context, dir string) error
+	DeleteDirectoryInGuestContext(ctx context.Context, dir string) error
+	CreateTempfileInGuestContext(ctx context.Context) (string, error)
+	ListDirectoryInGuestContext(ctx context.Context, dir string) ([]string, error)
+	CopyFileFromHostToGuestContext(ctx context.Context, hostFilepath, guestFilepath string) error
+	CopyFileFromGuestToHostContext(ctx context.Context, guestFilepath, hostFilepath string) error
+	RenameFileInGuestContext(ctx context.Context, src, dst string) error
+	CaptureScreenContext(ctx context.Context, dst string) error
+}
+
+// SharedFolderManager represents the Host-Guest shared folder operations of a VM.
+type SharedFolderManager interface {
+	SetSharedFolderStateContext(ctx context.Context, shareName, hostPath string, writable bool) error
+	AddSharedFolderContext(ctx context.Context, shareName, newHostPath string) error
+	RemoveSharedFolderContext(ctx context.Context, shareName string) error
+	EnableSharedFoldersContext(ctx context.Context, runtime bool) error
+	DisableSharedFoldersContext(ctx context.Context, runtime bool) error
+}
+
+// VariableManager represents the VM state variable operations of a VM.
+type VariableManager interface {
+	WriteVariableContext(ctx context.Context, mode vmrun.VariableMode, env, value string) error
+	ReadVariableContext(ctx context.Context, mode vmrun.VariableMode, env string) (string, error)
+}
+
+// VM represents a virtual machine operated by the VMware product.
+//
+// The vmtest package provides the conformance test suite of VM implementations.
+type VM interface {
+	PowerManager
+	SnapshotManager
+	GuestManager
+	SharedFolderManager
+	VariableManager
+
+	GetGuestIPAddressContext(ctx context.Context, wait bool) (string, error)
+}
+
+var (
+	_ VM = (*Host)(nil)
+	_ VM = (*Fusion)(nil)
+	_ VM = (*Workstation)(nil)
+	_ VM = (*Player)(nil)
+)
@@ -0,0 +1,212 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-vm/vmware/internal/vmwareutil"
+	"github.com/go-vm/vmware/runner"
+)
+
+var vmrunPath = vmwareutil.LookPath("vmrun")
+
+// Timeouts represents the default timeouts of each vmrun command class.
+//
+// A timeout is applied only if the context passed to a command has no deadline.
+// A zero value means no timeout.
+type Timeouts struct {
+	// Power is the timeout of power commands such as start and stop.
+	Power time.Duration
+	// Snapshot is the timeout of snapshot commands and the clone command.
+	Snapshot time.Duration
+	// Guest is the timeout of guest OS commands such as runProgramInGuest.
+	Guest time.Duration
+	// General is the timeout of the other commands.
+	General time.Duration
+}
+
+// DefaultTimeouts is the default timeouts used by the Client which Timeouts is nil.
+var DefaultTimeouts = Timeouts{
+	Power:    10 * time.Minute,
+	Snapshot: 30 * time.Minute,
+	Guest:    30 * time.Minute,
+	General:  5 * time.Minute,
+}
+
+// cleanupTimeout is the timeout of the guest cleanup, such as deleting the temporary files or killing the process,
+// which runs after the context of the command is done.
+var cleanupTimeout = time.Minute
+
+// cleanupContext returns the context of the guest cleanup, which is not canceled with the context of the command.
+func cleanupContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cleanupTimeout)
+}
+
+// class represents a vmrun command class.
+type class int
+
+const (
+	classGeneral class = iota
+	classPower
+	classSnapshot
+	classGuest
+)
+
+// timeout returns the timeout of c class.
+func (t Timeouts) timeout(c class) time.Duration {
+	switch c {
+	case classPower:
+		return t.Power
+	case classSnapshot:
+		return t.Snapshot
+	case classGuest:
+		return t.Guest
+	default:
+		return t.General
+	}
+}
+
+// Auth represents the authentication flags of vmrun.
+//
+// The zero value of each field omits the corresponding flag.
+type Auth struct {
+	// HostName is the host name of the remote server, passed as -h.
+	HostName string
+	// HostPort is the port of the remote server, passed as -P.
+	HostPort int
+	// HostUser is the user name of the remote server, passed as -u.
+	HostUser string
+	// HostPassword is the password of the remote server, passed as -p.
+	HostPassword string
+	// GuestUser is the default guest OS user name, passed as -gu.
+	GuestUser string
+	// GuestPassword is the default guest OS password, passed as -gp.
+	GuestPassword string
+	// VMPassword is the password of the encrypted VM, passed as -vp.
+	VMPassword string
+}
+
+// args returns the host and encrypted VM authentication flags of a.
+//
+// The guest OS flags are returned by Client.guestArgs, because only the guest commands need them.
+func (a *Auth) args() []string {
+	if a == nil {
+		return nil
+	}
+
+	var args []string
+	if a.HostName != "" {
+		args = append(args, "-h", a.HostName)
+	}
+	if a.HostPort != 0 {
+		args = append(args, "-P", strconv.Itoa(a.HostPort))
+	}
+	if a.HostUser != "" {
+		args = append(args, "-u", a.HostUser)
+	}
+	if a.HostPassword != "" {
+		args = append(args, "-p", a.HostPassword)
+	}
+	if a.VMPassword != "" {
+		args = append(args, "-vp", a.VMPassword)
+	}
+
+	return args
+}
+
+// Client represents a vmrun command client.
+type Client struct {
+	// Path is the vmrun command path.
+	// If empty, the path detected from the installed VMware application is used.
+	Path string
+	// Runner runs the vmrun command. If nil, runner.Exec is used.
+	Runner runner.Runner
+	// Timeouts is the default timeouts of each command class. If nil, DefaultTimeouts is used.
+	Timeouts *Timeouts
+	// Auth is the authentication flags passed to all commands. If nil, no authentication flag is passed
+	// except the guest OS credentials given to each guest command.
+	Auth *Auth
+}
+
+// DefaultClient is the default Client used by the package level functions.
+var DefaultClient = &Client{}
+
+// NewClient returns the new Client which runs the vmrun command with r.
+func NewClient(r runner.Runner) *Client {
+	return &Client{Runner: r}
+}
+
+// WithAuth returns the shallow copy of c which uses the auth authentication flags.
+func (c *Client) WithAuth(auth *Auth) *Client {
+	c2 := *c
+	c2.Auth = auth
+	return &c2
+}
+
+// guestArgs returns the guest OS authentication flags followed by arg.
+//
+// The empty username or password falls back to the GuestUser or GuestPassword of c.Auth.
+func (c *Client) guestArgs(username, password string, arg ...string) []string {
+	if c.Auth != nil {
+		if username == "" {
+			username = c.Auth.GuestUser
+		}
+		if password == "" {
+			password = c.Auth.GuestPassword
+		}
+	}
+
+	return append([]string{"-gu", username, "-gp", password}, arg...)
+}
+
+// vmrun run the vmrun command with the app name and args, return the stdout result and cmd error.
+//
+// The non-zero exit of vmrun is returned as *Error.
+// The vmrun process is killed when ctx is done, or when the default timeout of c class expires.
+func (c *Client) vmrun(ctx context.Context, cls class, app string, arg ...string) (string, error) {
+	timeouts := DefaultTimeouts
+	if c.Timeouts != nil {
+		timeouts = *c.Timeouts
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		if timeout := timeouts.timeout(cls); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+	}
+
+	var r runner.Runner = runner.Exec{}
+	if c.Runner != nil {
+		r = c.Runner
+	}
+	path := vmrunPath
+	if c.Path != "" {
+		path = c.Path
+	}
+
+	// vmrun with nogui on VMware Fusion through at least 8.0.1 doesn't work right
+	// if the umask is set to not allow world-readable permissions
+	_ = syscall.Umask(022)
+
+	args := append([]string{"-T", app}, c.Auth.args()...)
+	args = append(args, arg...)
+	result, err := r.Run(ctx, path, args...)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", newError(args, result)
+	}
+
+	return result.Stdout, nil
+}
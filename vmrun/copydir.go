@@ -0,0 +1,416 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// CopyDirOptions represents the options of CopyDirToGuest and CopyDirFromGuest.
+type CopyDirOptions struct {
+	// Include is the glob patterns of the files to copy. If empty, all files are copied.
+	//
+	// A pattern is matched by path.Match against the slash separated path relative to the source directory,
+	// or against the base name if the pattern has no slash.
+	// Include is not applied to the directories.
+	Include []string
+	// Exclude is the glob patterns of the files and directories not to copy, which takes precedence over Include.
+	// The contents of the excluded directory are not copied.
+	Exclude []string
+	// Tar bundles the files into a single tar archive, which is copied with one vmrun invocation
+	// and extracted by the tar command of the guest OS.
+	Tar bool
+	// Shell is the guest OS shell which runs the tar command in Tar mode.
+	Shell Shell
+}
+
+// matchGlob reports whether the rel path matches any of the patterns.
+func matchGlob(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match reports whether the rel path should be copied.
+func (o *CopyDirOptions) match(rel string, dir bool) bool {
+	if matchGlob(o.Exclude, rel) {
+		return false
+	}
+	if dir || len(o.Include) == 0 {
+		return true
+	}
+
+	return matchGlob(o.Include, rel)
+}
+
+// guestJoinRel joins the guest directory path and the slash separated rel path with the separator of dir.
+func guestJoinRel(dir, rel string) string {
+	for _, name := range strings.Split(rel, "/") {
+		dir = GuestJoin(dir, name)
+	}
+
+	return dir
+}
+
+// CopyDirToGuest copy a directory tree from host OS to guest OS.
+//
+// The guestDir and its parents are created if they do not exist.
+// Only the regular files and directories are copied.
+func CopyDirToGuest(app, vmx, username, password, hostDir, guestDir string, opts *CopyDirOptions) error {
+	return CopyDirToGuestContext(context.Background(), app, vmx, username, password, hostDir, guestDir, opts)
+}
+
+// CopyDirToGuestContext is like CopyDirToGuest but includes a context.
+func CopyDirToGuestContext(ctx context.Context, app, vmx, username, password, hostDir, guestDir string, opts *CopyDirOptions) error {
+	return DefaultClient.CopyDirToGuest(ctx, app, vmx, username, password, hostDir, guestDir, opts)
+}
+
+// CopyDirToGuest copy a directory tree from host OS to guest OS.
+//
+// The guestDir and its parents are created if they do not exist.
+// Only the regular files and directories are copied.
+func (c *Client) CopyDirToGuest(ctx context.Context, app, vmx, username, password, hostDir, guestDir string, opts *CopyDirOptions) error {
+	if opts == nil {
+		opts = &CopyDirOptions{}
+	}
+
+	if err := c.MkdirAllInGuest(ctx, app, vmx, username, password, guestDir); err != nil {
+		return err
+	}
+	if opts.Tar {
+		return c.copyTarToGuest(ctx, app, vmx, username, password, hostDir, guestDir, opts)
+	}
+
+	return walkHostDir(hostDir, opts, func(p, rel string, d fs.DirEntry) error {
+		dst := guestJoinRel(guestDir, rel)
+		if d.IsDir() {
+			if err := c.CreateDirectoryInGuest(ctx, app, vmx, username, password, dst); err != nil && !errors.Is(err, ErrFileExists) {
+				return err
+			}
+			return nil
+		}
+
+		return c.CopyFileFromHostToGuest(ctx, app, vmx, username, password, p, dst)
+	})
+}
+
+// walkHostDir walks the host directory tree and calls fn for each regular file and directory matched with opts.
+func walkHostDir(root string, opts *CopyDirOptions, fn func(p, rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !opts.match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		return fn(p, rel, d)
+	})
+}
+
+// copyTarToGuest copies the host directory tree into the tar archive, and extracts it in guest OS.
+func (c *Client) copyTarToGuest(ctx context.Context, app, vmx, username, password, hostDir, guestDir string, opts *CopyDirOptions) (err error) {
+	archive, err := ioutil.TempFile("", "vmrun-copydir")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+
+	tw := tar.NewWriter(archive)
+	err = walkHostDir(hostDir, opts, func(p, rel string, d fs.DirEntry) error {
+		return addTar(tw, p, rel, d)
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	guestArchive, err := c.CreateTempfileInGuest(ctx, app, vmx, username, password)
+	if err != nil {
+		return err
+	}
+	defer c.deleteTempInGuest(app, vmx, username, password, guestArchive, &err)
+
+	if err := c.CopyFileFromHostToGuest(ctx, app, vmx, username, password, archive.Name(), guestArchive); err != nil {
+		return err
+	}
+
+	sh := opts.Shell
+	command := fmt.Sprintf("tar -xf %s -C %s", sh.quote(guestArchive), sh.quote(guestDir))
+
+	return c.execTar(ctx, app, vmx, username, password, sh, command)
+}
+
+// addTar writes the p host file or directory to tw as the rel name.
+func addTar(tw *tar.Writer, p, rel string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = rel
+	if d.IsDir() {
+		hdr.Name += "/"
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if d.IsDir() {
+		return nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+
+	return err
+}
+
+// deleteTempInGuest deletes the name temporary file in guest OS even if the context of the copy is done,
+// within the cleanup timeout. The error of the deletion is stored to *err unless it has an error already,
+// as the guest files of ExecInGuest.
+func (c *Client) deleteTempInGuest(app, vmx, username, password, name string, err *error) {
+	ctx, cancel := cleanupContext()
+	defer cancel()
+
+	if deleteErr := c.DeleteFileInGuest(ctx, app, vmx, username, password, name); deleteErr != nil && *err == nil {
+		*err = deleteErr
+	}
+}
+
+// execTar runs the tar command in guest OS, and returns the error if it exits with non-zero.
+func (c *Client) execTar(ctx context.Context, app, vmx, username, password string, shell Shell, command string) error {
+	result, err := c.ExecInGuest(ctx, app, vmx, username, password, shell, command)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("vmrun: %s: exit status %d: %s", command, result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+
+	return nil
+}
+
+// CopyDirFromGuest copy a directory tree from guest OS to host OS.
+//
+// The hostDir and its parents are created if they do not exist.
+func CopyDirFromGuest(app, vmx, username, password, guestDir, hostDir string, opts *CopyDirOptions) error {
+	return CopyDirFromGuestContext(context.Background(), app, vmx, username, password, guestDir, hostDir, opts)
+}
+
+// CopyDirFromGuestContext is like CopyDirFromGuest but includes a context.
+func CopyDirFromGuestContext(ctx context.Context, app, vmx, username, password, guestDir, hostDir string, opts *CopyDirOptions) error {
+	return DefaultClient.CopyDirFromGuest(ctx, app, vmx, username, password, guestDir, hostDir, opts)
+}
+
+// CopyDirFromGuest copy a directory tree from guest OS to host OS.
+//
+// The hostDir and its parents are created if they do not exist.
+func (c *Client) CopyDirFromGuest(ctx context.Context, app, vmx, username, password, guestDir, hostDir string, opts *CopyDirOptions) error {
+	if opts == nil {
+		opts = &CopyDirOptions{}
+	}
+
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		return err
+	}
+	if opts.Tar {
+		return c.copyTarFromGuest(ctx, app, vmx, username, password, guestDir, hostDir, opts)
+	}
+
+	return c.copyDirFromGuest(ctx, app, vmx, username, password, guestDir, hostDir, "", opts)
+}
+
+// copyDirFromGuest copies the dir guest directory to the host directory recursively.
+//
+// The rel is the slash separated path of dir relative to the source directory, which is empty at the top.
+func (c *Client) copyDirFromGuest(ctx context.Context, app, vmx, username, password, dir, hostDir, rel string, opts *CopyDirOptions) error {
+	names, err := c.ListDirectoryInGuest(ctx, app, vmx, username, password, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		src := GuestJoin(dir, name)
+		childRel := path.Join(rel, name)
+
+		isDir, err := c.DirectoryExistsInGuest(ctx, app, vmx, username, password, src)
+		if err != nil {
+			return err
+		}
+		if !opts.match(childRel, isDir) {
+			continue
+		}
+
+		dst := filepath.Join(hostDir, filepath.FromSlash(childRel))
+		if isDir {
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+			if err := c.copyDirFromGuest(ctx, app, vmx, username, password, src, hostDir, childRel, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := c.CopyFileFromGuestToHost(ctx, app, vmx, username, password, src, dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyTarFromGuest archives the guest directory tree by the tar command of guest OS, and extracts it in host OS.
+func (c *Client) copyTarFromGuest(ctx context.Context, app, vmx, username, password, guestDir, hostDir string, opts *CopyDirOptions) (err error) {
+	guestArchive, err := c.CreateTempfileInGuest(ctx, app, vmx, username, password)
+	if err != nil {
+		return err
+	}
+	defer c.deleteTempInGuest(app, vmx, username, password, guestArchive, &err)
+
+	sh := opts.Shell
+	command := fmt.Sprintf("tar -cf %s -C %s .", sh.quote(guestArchive), sh.quote(guestDir))
+	if err := c.execTar(ctx, app, vmx, username, password, sh, command); err != nil {
+		return err
+	}
+
+	archive, err := ioutil.TempFile("", "vmrun-copydir")
+	if err != nil {
+		return err
+	}
+	archive.Close()
+	defer os.Remove(archive.Name())
+
+	if err := c.CopyFileFromGuestToHost(ctx, app, vmx, username, password, guestArchive, archive.Name()); err != nil {
+		return err
+	}
+
+	return extractTar(archive.Name(), hostDir, opts)
+}
+
+// extractTar extracts the regular files and directories matched with opts in the archive to the dir.
+func extractTar(archive, dir string, opts *CopyDirOptions) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var excluded []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := path.Clean(strings.TrimPrefix(filepath.ToSlash(hdr.Name), "./"))
+		if rel == "." {
+			continue
+		}
+		if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("vmrun: invalid tar entry name: %q", hdr.Name)
+		}
+		if underAny(excluded, rel) {
+			continue
+		}
+
+		isDir := hdr.Typeflag == tar.TypeDir
+		if !opts.match(rel, isDir) {
+			if isDir {
+				excluded = append(excluded, rel)
+			}
+			continue
+		}
+
+		dst := filepath.Join(dir, filepath.FromSlash(rel))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(dst, tr, hdr.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// underAny reports whether the rel path is under any of the dirs.
+func underAny(dirs []string, rel string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeFile writes r to the name file with perm, creating its parent directories.
+func writeFile(name string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,417 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+)
+
+func TestCopyDirOptionsMatch(t *testing.T) {
+	opts := &CopyDirOptions{
+		Include: []string{"*.go", "docs/*.md"},
+		Exclude: []string{".git", "*_test.go", "vendor"},
+	}
+	tests := []struct {
+		rel  string
+		dir  bool
+		want bool
+	}{
+		{rel: "main.go", want: true},
+		{rel: "cmd/tool/main.go", want: true},
+		{rel: "main_test.go", want: false},
+		{rel: "README.md", want: false},
+		{rel: "docs/README.md", want: true},
+		{rel: "docs", dir: true, want: true},
+		{rel: ".git", dir: true, want: false},
+		{rel: "third_party/vendor", dir: true, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rel, func(t *testing.T) {
+			if got := opts.match(tt.rel, tt.dir); got != tt.want {
+				t.Errorf("match(%q, %v) = %v, want %v", tt.rel, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuestJoinRel(t *testing.T) {
+	tests := []struct {
+		dir  string
+		rel  string
+		want string
+	}{
+		{dir: "/tmp/src", rel: "a/b.txt", want: "/tmp/src/a/b.txt"},
+		{dir: "/", rel: "a", want: "/a"},
+		{dir: `C:\src`, rel: "a/b.txt", want: `C:\src\a\b.txt`},
+	}
+	for _, tt := range tests {
+		if got := guestJoinRel(tt.dir, tt.rel); got != tt.want {
+			t.Errorf("guestJoinRel(%q, %q) = %q, want %q", tt.dir, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// writeTree writes the files to the dir, which keys are the slash separated paths.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClientCopyDirToGuest(t *testing.T) {
+	hostDir := t.TempDir()
+	writeTree(t, hostDir, map[string]string{
+		"main.go":         "package main\n",
+		"main_test.go":    "package main\n",
+		"sub/lib.go":      "package sub\n",
+		".git/HEAD":       "ref: refs/heads/master\n",
+		"sub/README.md":   "# sub\n",
+		"sub/deep/z.go":   "package deep\n",
+		"other/notes.txt": "notes\n",
+	})
+
+	var got []string
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		// args: -T app -gu user -gp pass command vmx params...
+		switch cmd := args[6]; cmd {
+		case "directoryExistsInGuest":
+			return &runner.Result{Stdout: "The directory exists.\n"}, nil
+		case "createDirectoryInGuest":
+			got = append(got, "mkdir "+args[8])
+		case "CopyFileFromHostToGuest":
+			rel, err := filepath.Rel(hostDir, args[8])
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, "copy "+filepath.ToSlash(rel)+" "+args[9])
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+		return &runner.Result{}, nil
+	}))
+
+	opts := &CopyDirOptions{Include: []string{"*.go"}, Exclude: []string{".git", "*_test.go"}}
+	if err := c.CopyDirToGuest(context.Background(), "fusion", testVMX, "user", "pass", hostDir, "/src", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"copy main.go /src/main.go",
+		"mkdir /src/other",
+		"mkdir /src/sub",
+		"mkdir /src/sub/deep",
+		"copy sub/deep/z.go /src/sub/deep/z.go",
+		"copy sub/lib.go /src/sub/lib.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+}
+
+// tarNames returns the entry names of the tar archive.
+func tarNames(t *testing.T, archive string) []string {
+	t.Helper()
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var names []string
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return names
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
+
+func TestClientCopyDirToGuestTar(t *testing.T) {
+	hostDir := t.TempDir()
+	writeTree(t, hostDir, map[string]string{
+		"a.txt":     "a\n",
+		"b/c.txt":   "c\n",
+		"tmp/d.txt": "d\n",
+	})
+
+	var names []string
+	var program []string
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		switch cmd := args[6]; cmd {
+		case "directoryExistsInGuest":
+			return &runner.Result{Stdout: "The directory exists.\n"}, nil
+		case "CreateTempfileInGuest":
+			return &runner.Result{Stdout: "/tmp/vmware1\n"}, nil
+		case "CopyFileFromHostToGuest":
+			names = tarNames(t, args[8])
+		case "runProgramInGuest":
+			program = args[8:]
+		case "listprocessesinguest":
+		case "CopyFileFromGuestToHost":
+			// all of the exec output files are "0".
+			if err := ioutil.WriteFile(args[9], []byte("0\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		case "deleteFileInGuest":
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+		return &runner.Result{}, nil
+	}))
+
+	opts := &CopyDirOptions{Exclude: []string{"tmp"}, Tar: true}
+	if err := c.CopyDirToGuest(context.Background(), "fusion", testVMX, "user", "pass", hostDir, "/src", opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a.txt", "b/", "b/c.txt"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("tar entries = %q, want %q", names, want)
+	}
+	if !strings.HasPrefix(program[3], `(tar -xf '/tmp/vmware1' -C '/src')`) {
+		t.Fatalf("runProgramInGuest args = %q, want tar -xf", program)
+	}
+}
+
+func TestClientCopyDirFromGuestTar(t *testing.T) {
+	// archive is the tar archive created by the guest tar command.
+	archive := filepath.Join(t.TempDir(), "guest.tar")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	for _, e := range []struct{ name, data string }{
+		{name: "./"},
+		{name: "./a.txt", data: "a\n"},
+		{name: "./b/"},
+		{name: "./b/c.txt", data: "c\n"},
+		{name: "./cache/"},
+		{name: "./cache/x.txt", data: "x\n"},
+		{name: "./b/d.log", data: "d\n"},
+	} {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(e.name, "/") {
+			hdr.Mode, hdr.Typeflag = 0755, tar.TypeDir
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	data, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var temps int
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		switch cmd := args[6]; cmd {
+		case "CreateTempfileInGuest":
+			temps++
+			return &runner.Result{Stdout: fmt.Sprintf("/tmp/vmware%d\n", temps)}, nil
+		case "CopyFileFromGuestToHost":
+			out := []byte("0\n")
+			if args[8] == "/tmp/vmware1" {
+				out = data
+			}
+			if err := ioutil.WriteFile(args[9], out, 0644); err != nil {
+				t.Fatal(err)
+			}
+		case "runProgramInGuest", "listprocessesinguest", "deleteFileInGuest":
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+		return &runner.Result{}, nil
+	}))
+
+	hostDir := filepath.Join(t.TempDir(), "dst")
+	opts := &CopyDirOptions{Include: []string{"*.txt"}, Exclude: []string{"cache"}, Tar: true}
+	if err := c.CopyDirFromGuest(context.Background(), "fusion", testVMX, "user", "pass", "/src", hostDir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	err = filepath.Walk(hostDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(hostDir, p)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if want := []string{".", "a.txt", "b", "b/c.txt"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("extracted files = %q, want %q", got, want)
+	}
+}
+
+func TestClientCopyDirToGuestTarCleanup(t *testing.T) {
+	notFound := runner.Result{Stdout: "Error: A file was not found\n", ExitCode: 255}
+	tests := []struct {
+		name      string
+		cancel    bool          // cancel the context while the guest tar runs
+		deleteErr runner.Result // result of deleting the guest archive
+		wantErr   error
+	}{
+		{name: "canceled", cancel: true, wantErr: context.Canceled},
+		{name: "delete error", deleteErr: notFound, wantErr: ErrFileNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostDir := t.TempDir()
+			writeTree(t, hostDir, map[string]string{"a.txt": "a\n"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var temps int
+			var deleted []string
+			c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				switch cmd := args[6]; cmd {
+				case "directoryExistsInGuest", "CopyFileFromHostToGuest", "listprocessesinguest":
+				case "CreateTempfileInGuest":
+					temps++
+					return &runner.Result{Stdout: fmt.Sprintf("/tmp/vmware%d\n", temps)}, nil
+				case "runProgramInGuest":
+					if tt.cancel {
+						cancel()
+						return nil, ctx.Err()
+					}
+				case "CopyFileFromGuestToHost":
+					if err := ioutil.WriteFile(args[9], []byte("0\n"), 0644); err != nil {
+						t.Fatal(err)
+					}
+				case "deleteFileInGuest":
+					if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > cleanupTimeout {
+						t.Fatalf("deleteFileInGuest deadline = %v, %v, want within %v", deadline, ok, cleanupTimeout)
+					}
+					deleted = append(deleted, args[8])
+					if args[8] == "/tmp/vmware1" {
+						return &tt.deleteErr, nil
+					}
+				default:
+					t.Fatalf("unexpected command %q", cmd)
+				}
+				return &runner.Result{}, nil
+			}))
+
+			err := c.CopyDirToGuest(ctx, "fusion", testVMX, "user", "pass", hostDir, "/src", &CopyDirOptions{Tar: true})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CopyDirToGuest error = %v, want %v", err, tt.wantErr)
+			}
+			if len(deleted) == 0 || deleted[len(deleted)-1] != "/tmp/vmware1" {
+				t.Fatalf("deleted guest files = %q, want the archive /tmp/vmware1 last", deleted)
+			}
+		})
+	}
+}
+
+func TestClientCopyDirFromGuest(t *testing.T) {
+	// guest is the guest directory tree, which values are the file contents or nil for directories.
+	guest := map[string][]byte{
+		"/src/a.txt":         []byte("a\n"),
+		"/src/b":             nil,
+		"/src/b/c.txt":       []byte("c\n"),
+		"/src/b/d.log":       []byte("d\n"),
+		"/src/cache":         nil,
+		"/src/cache/x.txt":   []byte("x\n"),
+		"/src/my file.txt":   []byte("my\n"),
+		"/src/b/empty":       nil,
+		"/src/b/empty/e.txt": []byte("e\n"),
+	}
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		switch cmd := args[6]; cmd {
+		case "listDirectoryInGuest":
+			var names []string
+			for p := range guest {
+				if path.Dir(p) == args[8] {
+					names = append(names, path.Base(p))
+				}
+			}
+			sort.Strings(names)
+			return &runner.Result{Stdout: fmt.Sprintf("Directory list: %d\n%s\n", len(names), strings.Join(names, "\n"))}, nil
+		case "directoryExistsInGuest":
+			if data, ok := guest[args[8]]; !ok || data != nil {
+				return &runner.Result{Stdout: "The directory does not exist.\n", ExitCode: 255}, nil
+			}
+			return &runner.Result{Stdout: "The directory exists.\n"}, nil
+		case "CopyFileFromGuestToHost":
+			if err := ioutil.WriteFile(args[9], guest[args[8]], 0644); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+		return &runner.Result{}, nil
+	}))
+
+	hostDir := filepath.Join(t.TempDir(), "dst")
+	opts := &CopyDirOptions{Include: []string{"*.txt"}, Exclude: []string{"cache", "b/empty"}}
+	if err := c.CopyDirFromGuest(context.Background(), "fusion", testVMX, "user", "pass", "/src", hostDir, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	err := filepath.Walk(hostDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(hostDir, p)
+		got[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.txt": "a\n", "b/c.txt": "c\n", "my file.txt": "my\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("copied files = %q, want %q", got, want)
+	}
+}
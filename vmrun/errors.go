@@ -0,0 +1,159 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-vm/vmware/internal/vmwareutil"
+	"github.com/go-vm/vmware/runner"
+)
+
+// List of the errors parsed from the vmrun output.
+//
+// The returned *Error wraps one of them, so the caller can test it with errors.Is.
+var (
+	// ErrNotPoweredOn is returned when the command requires the powered on VM.
+	ErrNotPoweredOn = errors.New("vmrun: virtual machine is not powered on")
+	// ErrPoweredOn is returned when the command requires the powered off VM.
+	ErrPoweredOn = errors.New("vmrun: virtual machine is powered on")
+	// ErrToolsNotRunning is returned when the VMware Tools are not running in the guest.
+	ErrToolsNotRunning = errors.New("vmrun: VMware Tools are not running")
+	// ErrInvalidCredentials is returned when the guest user name or password is wrong.
+	ErrInvalidCredentials = errors.New("vmrun: invalid guest user name or password")
+	// ErrInteractiveLogin is returned when the command requires the interactively logged in guest user.
+	ErrInteractiveLogin = errors.New("vmrun: guest user must be logged in interactively")
+	// ErrSnapshotNotFound is returned when the snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("vmrun: snapshot not found")
+	// ErrSnapshotNotUnique is returned when the snapshot name matches more than one snapshot.
+	ErrSnapshotNotUnique = errors.New("vmrun: snapshot name is not unique")
+	// ErrVMLocked is returned when the VM is locked by another process.
+	ErrVMLocked = errors.New("vmrun: virtual machine is locked")
+	// ErrVMNotFound is returned when the .vmx file cannot be found.
+	ErrVMNotFound = errors.New("vmrun: virtual machine not found")
+	// ErrFileNotFound is returned when the file or directory does not exist.
+	ErrFileNotFound = errors.New("vmrun: file not found")
+	// ErrFileExists is returned when the file or directory already exists.
+	ErrFileExists = errors.New("vmrun: file already exists")
+	// ErrDirectoryNotEmpty is returned when the directory is not empty.
+	ErrDirectoryNotEmpty = errors.New("vmrun: directory is not empty")
+	// ErrPermissionDenied is returned when the user has insufficient permissions in the host or guest.
+	ErrPermissionDenied = errors.New("vmrun: permission denied")
+	// ErrHostConnection is returned when vmrun cannot connect to the host.
+	ErrHostConnection = errors.New("vmrun: cannot connect to host")
+	// ErrNotSupported is returned when the operation is not supported by the host type or guest.
+	ErrNotSupported = errors.New("vmrun: operation is not supported")
+)
+
+// errorMessages maps the vmrun (VIX) error messages to the errors.
+//
+// The messages are compared in order with lower case, so the more specific one must come first.
+// Each message is the full text of the vmrun message, since the short phrases such as "not supported"
+// also appear in the unrelated messages.
+var errorMessages = []struct {
+	substr string
+	err    error
+}{
+	{"does not uniquely identify one snapshot", ErrSnapshotNotUnique},
+	{"snapshot with the given name does not exist", ErrSnapshotNotFound},
+	{"snapshot does not exist", ErrSnapshotNotFound},
+	{"snapshot was not found", ErrSnapshotNotFound},
+	{"is not powered on", ErrNotPoweredOn},
+	{"needs to be powered on", ErrNotPoweredOn},
+	{"the virtual machine is not running", ErrNotPoweredOn},
+	{"should not be powered on", ErrPoweredOn},
+	{"must be powered off", ErrPoweredOn},
+	{"needs to be powered off", ErrPoweredOn},
+	{"is already powered on", ErrPoweredOn},
+	{"vmware tools are not running", ErrToolsNotRunning},
+	{"tools are not running in the virtual machine", ErrToolsNotRunning},
+	{"invalid user name or password", ErrInvalidCredentials},
+	{"incorrect user name or password", ErrInvalidCredentials},
+	{"authentication failure", ErrInvalidCredentials},
+	{"authentication failed", ErrInvalidCredentials},
+	{"must be logged in interactively", ErrInteractiveLogin},
+	{"appears to be in use", ErrVMLocked},
+	{"is in use by another", ErrVMLocked},
+	{"file is already in use", ErrVMLocked},
+	{"failed to lock the file", ErrVMLocked},
+	{"virtual machine is locked", ErrVMLocked},
+	{"virtual machine cannot be found", ErrVMNotFound},
+	{"the virtual machine does not exist", ErrVMNotFound},
+	{"a file was not found", ErrFileNotFound},
+	{"file does not exist", ErrFileNotFound},
+	{"directory does not exist", ErrFileNotFound},
+	{"cannot find the file", ErrFileNotFound},
+	{"cannot find the path", ErrFileNotFound},
+	{"no such file or directory", ErrFileNotFound},
+	{"the file already exists", ErrFileExists},
+	{"directory is not empty", ErrDirectoryNotEmpty},
+	{"insufficient permissions", ErrPermissionDenied},
+	{"permission denied", ErrPermissionDenied},
+	{"access is denied", ErrPermissionDenied},
+	{"cannot connect to host", ErrHostConnection},
+	{"unable to connect to host", ErrHostConnection},
+	{"failed to connect to host", ErrHostConnection},
+	{"the operation is not supported", ErrNotSupported},
+}
+
+// parseError returns the error which matches the vmrun output s, or nil if unknown.
+func parseError(s string) error {
+	s = strings.ToLower(s)
+	for _, m := range errorMessages {
+		if strings.Contains(s, m.substr) {
+			return m.err
+		}
+	}
+
+	return nil
+}
+
+// Error represents a failed vmrun command.
+type Error struct {
+	// Command is the vmrun command name such as "start".
+	Command string
+	// Args is the vmrun arguments, which passwords are redacted.
+	Args []string
+	// ExitCode is the exit code of vmrun.
+	ExitCode int
+	// Stdout is the standard output of vmrun.
+	Stdout string
+	// Stderr is the standard error of vmrun.
+	Stderr string
+	// Err is the known error parsed from the output, or nil.
+	Err error
+}
+
+// newError returns the new Error from vmrun args and result.
+func newError(args []string, result *runner.Result) *Error {
+	return &Error{
+		Command:  vmwareutil.CommandName(args),
+		Args:     vmwareutil.RedactArgs(args),
+		ExitCode: result.ExitCode,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		Err:      parseError(result.Stdout + "\n" + result.Stderr),
+	}
+}
+
+// Error implements a error interface.
+func (e *Error) Error() string {
+	msg := strings.TrimSpace(e.Stdout)
+	if msg == "" {
+		msg = strings.TrimSpace(e.Stderr)
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("exit status %d", e.ExitCode)
+	}
+
+	return "vmrun " + e.Command + ": " + msg
+}
+
+// Unwrap returns the known error parsed from the output.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
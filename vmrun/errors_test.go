@@ -0,0 +1,149 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-vm/vmware/internal/vmwareutil"
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   error
+	}{
+		{
+			name:   "not powered on",
+			output: "Error: The virtual machine is not powered on: /vm/test.vmx\n",
+			want:   ErrNotPoweredOn,
+		},
+		{
+			name:   "tools not running",
+			output: "Error: The VMware Tools are not running in the virtual machine: /vm/test.vmx\n",
+			want:   ErrToolsNotRunning,
+		},
+		{
+			name:   "invalid credentials",
+			output: "Error: Invalid user name or password for the guest OS\n",
+			want:   ErrInvalidCredentials,
+		},
+		{
+			name:   "snapshot not found",
+			output: "Error: A snapshot with the given name does not exist\n",
+			want:   ErrSnapshotNotFound,
+		},
+		{
+			name:   "snapshot not unique",
+			output: "Error: The name does not uniquely identify one snapshot\n",
+			want:   ErrSnapshotNotUnique,
+		},
+		{
+			name:   "vm locked",
+			output: "Error: This virtual machine appears to be in use.\n",
+			want:   ErrVMLocked,
+		},
+		{
+			name:   "file not found",
+			output: "Error: A file was not found\n",
+			want:   ErrFileNotFound,
+		},
+		{
+			name:   "vm not found",
+			output: "Error: Cannot open VM: /vm/test.vmx, The virtual machine cannot be found\n",
+			want:   ErrVMNotFound,
+		},
+		{
+			name:   "powered on",
+			output: "Error: The virtual machine should not be powered on. It is already running.\n",
+			want:   ErrPoweredOn,
+		},
+		{
+			name:   "file exists",
+			output: "Error: The file already exists\n",
+			want:   ErrFileExists,
+		},
+		{
+			name:   "not supported",
+			output: "Error: The operation is not supported\n",
+			want:   ErrNotSupported,
+		},
+		{
+			name:   "unknown",
+			output: "Error: Unrecognized command: foo\n",
+			want:   nil,
+		},
+		{
+			name:   "invalid snapshot name",
+			output: "Error: Invalid snapshot name: a/b\n",
+			want:   nil,
+		},
+		{
+			name:   "process not running",
+			output: "Error: The process is not running in the guest\n",
+			want:   nil,
+		},
+		{
+			name:   "snapshot exists",
+			output: "Error: The snapshot already exists\n",
+			want:   nil,
+		},
+		{
+			name:   "not supported feature",
+			output: "Error: Shared folders are not supported for this guest\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseError(tt.output); got != tt.want {
+				t.Errorf("parseError(%q) = %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{
+		Result: runner.Result{
+			Stdout:   "Error: Invalid user name or password for the guest OS\n",
+			Stderr:   "warning\n",
+			ExitCode: 255,
+		},
+	})
+
+	_, err := c.ListProcessesInGuest(context.Background(), "fusion", testVMX, "user", "secret")
+	if !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("errors.Is(%v, ErrInvalidCredentials) = false, want true", err)
+	}
+
+	var vmrunErr *Error
+	if !errors.As(err, &vmrunErr) {
+		t.Fatalf("errors.As(%v) = false, want true", err)
+	}
+	if vmrunErr.Command != "listprocessesinguest" {
+		t.Errorf("Command = %q, want %q", vmrunErr.Command, "listprocessesinguest")
+	}
+	wantArgs := []string{"-T", "fusion", "-gu", "user", "-gp", vmwareutil.Redacted, "listprocessesinguest", testVMX}
+	if !reflect.DeepEqual(vmrunErr.Args, wantArgs) {
+		t.Errorf("Args = %q, want %q", vmrunErr.Args, wantArgs)
+	}
+	if vmrunErr.ExitCode != 255 {
+		t.Errorf("ExitCode = %d, want %d", vmrunErr.ExitCode, 255)
+	}
+	if vmrunErr.Stderr != "warning\n" {
+		t.Errorf("Stderr = %q, want %q", vmrunErr.Stderr, "warning\n")
+	}
+	want := "vmrun listprocessesinguest: Error: Invalid user name or password for the guest OS"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
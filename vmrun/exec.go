@@ -0,0 +1,229 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Shell represents a shell of the guest OS which runs the ExecInGuest command.
+type Shell int
+
+const (
+	// ShellPOSIX runs the command with /bin/sh on a Linux, macOS or other POSIX guest.
+	ShellPOSIX Shell = iota
+	// ShellCmd runs the command with cmd.exe on a Windows guest.
+	ShellCmd
+	// ShellPowerShell runs the command with the Windows PowerShell on a Windows guest.
+	ShellPowerShell
+)
+
+// String implements a fmt.Stringer interface.
+func (s Shell) String() string {
+	switch s {
+	case ShellPOSIX:
+		return "sh"
+	case ShellCmd:
+		return "cmd"
+	case ShellPowerShell:
+		return "powershell"
+	default:
+		return "Shell(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+// program returns the guest path of the s shell program.
+func (s Shell) program() string {
+	switch s {
+	case ShellCmd:
+		return `C:\Windows\System32\cmd.exe`
+	case ShellPowerShell:
+		return `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`
+	default:
+		return "/bin/sh"
+	}
+}
+
+// args returns the arguments of the s shell program which runs the command,
+// redirects its output to the stdout and stderr guest files, and writes its exit code to the code guest file.
+func (s Shell) args(command, stdout, stderr, code string) []string {
+	switch s {
+	case ShellCmd:
+		// the ERRORLEVEL after the command is expanded by the second parse of call, since %ERRORLEVEL% is expanded
+		// when the line is parsed. The delayed expansion is not enabled, which would expand "!" in the command.
+		line := fmt.Sprintf(`(%s) >%s 2>%s & >%s call echo %%^ERRORLEVEL%%`, command, s.quote(stdout), s.quote(stderr), s.quote(code))
+		return []string{"/C", line}
+	case ShellPowerShell:
+		// the redirection of Windows PowerShell writes UTF-16 unless the Out-File default encoding is changed.
+		line := fmt.Sprintf(`$PSDefaultParameterValues['Out-File:Encoding'] = 'utf8'; `+
+			`$global:LASTEXITCODE = $null; & { %s } > %s 2> %s; $ok = $?; `+
+			`$code = if ($LASTEXITCODE -ne $null) { $LASTEXITCODE } elseif ($ok) { 0 } else { 1 }; `+
+			`Set-Content -Path %s -Value $code`,
+			command, s.quote(stdout), s.quote(stderr), s.quote(code))
+		return []string{"-NoProfile", "-NonInteractive", "-Command", line}
+	default:
+		line := fmt.Sprintf(`(%s) >%s 2>%s; echo $? >%s`, command, s.quote(stdout), s.quote(stderr), s.quote(code))
+		return []string{"-c", line}
+	}
+}
+
+// quote quotes the arg for the s shell.
+func (s Shell) quote(arg string) string {
+	switch s {
+	case ShellCmd:
+		// cmd.exe has no escape in the double quotes, and the path can not contain a double quote.
+		return `"` + arg + `"`
+	case ShellPowerShell:
+		return "'" + strings.Replace(arg, "'", "''", -1) + "'"
+	default:
+		return "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+	}
+}
+
+// ExecResult represents a result of the command run by ExecInGuest.
+type ExecResult struct {
+	// ExitCode is the exit code of the command in the guest OS.
+	ExitCode int
+	// Stdout is the standard output of the command.
+	Stdout string
+	// Stderr is the standard error of the command.
+	Stderr string
+}
+
+// ExecInGuest run a command line with the shell in Guest OS, and returns its exit code, stdout and stderr.
+//
+// The command is started without waiting, and polled until it exits. Its output is redirected to temporary files in Guest OS,
+// which are copied to Host OS and deleted after the command exits. If ctx is done, the guest process is killed before
+// the temporary files are deleted, and ctx.Err() is returned.
+// The non-zero exit code of the command is not an error, but the error of deleting the temporary files is returned with the result.
+func ExecInGuest(app, vmx, username, password string, shell Shell, command string) (*ExecResult, error) {
+	return ExecInGuestContext(context.Background(), app, vmx, username, password, shell, command)
+}
+
+// ExecInGuestContext is like ExecInGuest but includes a context.
+func ExecInGuestContext(ctx context.Context, app, vmx, username, password string, shell Shell, command string) (*ExecResult, error) {
+	return DefaultClient.ExecInGuest(ctx, app, vmx, username, password, shell, command)
+}
+
+// ExecInGuest run a command line with the shell in Guest OS, and returns its exit code, stdout and stderr.
+//
+// The command is started without waiting, and polled until it exits. Its output is redirected to temporary files in Guest OS,
+// which are copied to Host OS and deleted after the command exits. If ctx is done, the guest process is killed before
+// the temporary files are deleted, and ctx.Err() is returned.
+// The non-zero exit code of the command is not an error, but the error of deleting the temporary files is returned with the result.
+func (c *Client) ExecInGuest(ctx context.Context, app, vmx, username, password string, shell Shell, command string) (_ *ExecResult, err error) {
+	files, err := c.newExecFiles(ctx, app, vmx, username, password)
+	defer files.remove(&err)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := c.runInGuest(ctx, app, vmx, username, password, shell, command, files, DefaultPollInterval, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs := make([]string, 2)
+	for i := range outputs {
+		data, err := files.read(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		outputs[i] = string(data)
+	}
+
+	return &ExecResult{
+		ExitCode: code,
+		Stdout:   outputs[0],
+		Stderr:   outputs[1],
+	}, nil
+}
+
+// parseExitCode parses the exit code written by the shell.
+func parseExitCode(shell Shell, s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("vmrun: invalid exit code of %s command: %q", shell, s)
+	}
+
+	return code, nil
+}
+
+// execFiles represents the guest temporary files of the stdout, stderr and exit code of the command run by ExecInGuest,
+// and the host directory which the guest files are copied to.
+type execFiles struct {
+	c        *Client
+	app      string
+	vmx      string
+	username string
+	password string
+	guest    []string
+	hostDir  string
+}
+
+// newExecFiles creates the guest temporary files and the host directory for ExecInGuest.
+//
+// The returned execFiles must be removed even if the error is returned.
+func (c *Client) newExecFiles(ctx context.Context, app, vmx, username, password string) (*execFiles, error) {
+	files := &execFiles{c: c, app: app, vmx: vmx, username: username, password: password}
+	for i := 0; i < 3; i++ {
+		f, err := c.CreateTempfileInGuest(ctx, app, vmx, username, password)
+		if err != nil {
+			return files, err
+		}
+		files.guest = append(files.guest, f)
+	}
+
+	hostDir, err := ioutil.TempDir("", "vmrun-exec")
+	if err != nil {
+		return files, err
+	}
+	files.hostDir = hostDir
+
+	return files, nil
+}
+
+// read copies the i-th guest file to the host and returns its content without the UTF-8 byte order mark.
+func (f *execFiles) read(ctx context.Context, i int) ([]byte, error) {
+	hostFile := filepath.Join(f.hostDir, strconv.Itoa(i))
+	if err := f.c.CopyFileFromGuestToHost(ctx, f.app, f.vmx, f.username, f.password, f.guest[i], hostFile); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(hostFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), nil
+}
+
+// remove deletes the guest files and the host directory, and stores the first error of them to *err
+// unless it has an error already.
+//
+// The guest files are deleted even if the context of the command is done, within the cleanup timeout.
+func (f *execFiles) remove(err *error) {
+	ctx, cancel := cleanupContext()
+	defer cancel()
+
+	var errs []error
+	for _, guest := range f.guest {
+		errs = append(errs, f.c.DeleteFileInGuest(ctx, f.app, f.vmx, f.username, f.password, guest))
+	}
+	if f.hostDir != "" {
+		errs = append(errs, os.RemoveAll(f.hostDir))
+	}
+	for _, removeErr := range errs {
+		if removeErr != nil && *err == nil {
+			*err = removeErr
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+)
+
+func TestShellArgsCmd(t *testing.T) {
+	// the delayed expansion must not be enabled, which removes "!" and expands !VAR! in the command.
+	got := ShellCmd.args("echo done! !VAR!", `C:\out`, `C:\err`, `C:\code`)
+	want := []string{"/C", `(echo done! !VAR!) >"C:\out" 2>"C:\err" & >"C:\code" call echo %^ERRORLEVEL%`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("args = %q, want %q", got, want)
+	}
+}
+
+// fakeExecGuest returns the runnertest.Func which emulates the guest commands used by ExecInGuest.
+func fakeExecGuest(t *testing.T, outputs map[string]string, program *[]string, deleted *[]string) runnertest.Func {
+	var seq int
+	return func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		// args: -T app -gu user -gp pass command vmx params...
+		params := args[8:]
+		switch cmd := args[6]; cmd {
+		case "CreateTempfileInGuest":
+			seq++
+			return &runner.Result{Stdout: fmt.Sprintf("/tmp/vmware%d\n", seq)}, nil
+		case "runProgramInGuest":
+			*program = params
+		case "listprocessesinguest":
+			// the process has exited before it is listed.
+			return &runner.Result{Stdout: "Process list: 1\npid=1, owner=root, cmd=/sbin/init\n"}, nil
+		case "CopyFileFromGuestToHost":
+			if err := ioutil.WriteFile(params[1], []byte(outputs[params[0]]), 0644); err != nil {
+				t.Fatal(err)
+			}
+		case "deleteFileInGuest":
+			*deleted = append(*deleted, params[0])
+		case "killprocessinguest":
+		default:
+			t.Fatalf("unexpected command %q", cmd)
+		}
+		return &runner.Result{}, nil
+	}
+}
+
+func TestClientExecInGuest(t *testing.T) {
+	tests := []struct {
+		name        string
+		shell       Shell
+		outputs     map[string]string
+		want        *ExecResult
+		wantProgram []string
+		wantErr     bool
+	}{
+		{
+			name:  "sh",
+			shell: ShellPOSIX,
+			outputs: map[string]string{
+				"/tmp/vmware1": "hello\n",
+				"/tmp/vmware2": "warning\n",
+				"/tmp/vmware3": "3\n",
+			},
+			want: &ExecResult{ExitCode: 3, Stdout: "hello\n", Stderr: "warning\n"},
+			wantProgram: []string{"-noWait", "/bin/sh", "-c",
+				`(echo hello) >'/tmp/vmware1' 2>'/tmp/vmware2'; echo $? >'/tmp/vmware3'`},
+		},
+		{
+			name:  "cmd",
+			shell: ShellCmd,
+			outputs: map[string]string{
+				"/tmp/vmware1": "hello\r\n",
+				"/tmp/vmware3": "0 \r\n",
+			},
+			want: &ExecResult{ExitCode: 0, Stdout: "hello\r\n"},
+			wantProgram: []string{"-noWait", `C:\Windows\System32\cmd.exe`, "/C",
+				`(echo hello) >"/tmp/vmware1" 2>"/tmp/vmware2" & >"/tmp/vmware3" call echo %^ERRORLEVEL%`},
+		},
+		{
+			name:  "powershell",
+			shell: ShellPowerShell,
+			outputs: map[string]string{
+				"/tmp/vmware1": "\xef\xbb\xbfhello\r\n",
+				"/tmp/vmware3": "\xef\xbb\xbf1\r\n",
+			},
+			want: &ExecResult{ExitCode: 1, Stdout: "hello\r\n"},
+			wantProgram: []string{"-noWait", `C:\Windows\System32\WindowsPowerShell\v1.0\powershell.exe`, "-NoProfile", "-NonInteractive", "-Command",
+				`$PSDefaultParameterValues['Out-File:Encoding'] = 'utf8'; $global:LASTEXITCODE = $null; ` +
+					`& { echo hello } > '/tmp/vmware1' 2> '/tmp/vmware2'; $ok = $?; ` +
+					`$code = if ($LASTEXITCODE -ne $null) { $LASTEXITCODE } elseif ($ok) { 0 } else { 1 }; ` +
+					`Set-Content -Path '/tmp/vmware3' -Value $code`},
+		},
+		{
+			name:    "invalid exit code",
+			shell:   ShellPOSIX,
+			outputs: map[string]string{"/tmp/vmware3": "x\n"},
+			wantProgram: []string{"-noWait", "/bin/sh", "-c",
+				`(echo hello) >'/tmp/vmware1' 2>'/tmp/vmware2'; echo $? >'/tmp/vmware3'`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var program, deleted []string
+			c := NewClient(fakeExecGuest(t, tt.outputs, &program, &deleted))
+
+			got, err := c.ExecInGuest(context.Background(), "fusion", testVMX, "user", "pass", tt.shell, "echo hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecInGuest error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ExecInGuest = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(program, tt.wantProgram) {
+				t.Fatalf("runProgramInGuest args = %q, want %q", program, tt.wantProgram)
+			}
+			wantDeleted := []string{"/tmp/vmware1", "/tmp/vmware2", "/tmp/vmware3"}
+			if !reflect.DeepEqual(deleted, wantDeleted) {
+				t.Fatalf("deleted guest files = %q, want %q", deleted, wantDeleted)
+			}
+		})
+	}
+}
+
+func TestClientExecInGuestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var program, deleted []string
+	var cleanup []string // the kill and delete commands in order
+	guest := fakeExecGuest(t, nil, &program, &deleted)
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch args[6] {
+		case "runProgramInGuest":
+			program = args[8:]
+		case "listprocessesinguest":
+			// the first poll cancels the command which is still running.
+			cancel()
+			return &runner.Result{Stdout: "Process list: 1\npid=42, owner=user, cmd=/bin/sh -c (sleep 60) >'/tmp/vmware1' 2>'/tmp/vmware2'; echo $? >'/tmp/vmware3'\n"}, nil
+		case "killprocessinguest", "deleteFileInGuest":
+			if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > cleanupTimeout {
+				t.Fatalf("%s deadline = %v, %v, want within %v", args[6], deadline, ok, cleanupTimeout)
+			}
+			cleanup = append(cleanup, args[6]+" "+args[8])
+		}
+		return guest(ctx, name, args...)
+	}))
+
+	if _, err := c.ExecInGuest(ctx, "fusion", testVMX, "user", "pass", ShellPOSIX, "sleep 60"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecInGuest error = %v, want %v", err, context.Canceled)
+	}
+	want := []string{"killprocessinguest 42", "deleteFileInGuest /tmp/vmware1", "deleteFileInGuest /tmp/vmware2", "deleteFileInGuest /tmp/vmware3"}
+	if !reflect.DeepEqual(cleanup, want) {
+		t.Fatalf("cleanup commands = %q, want %q", cleanup, want)
+	}
+}
+
+func TestClientExecInGuestDeleteError(t *testing.T) {
+	outputs := map[string]string{"/tmp/vmware1": "hello\n", "/tmp/vmware3": "0\n"}
+	var program, deleted []string
+	guest := fakeExecGuest(t, outputs, &program, &deleted)
+	c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+		if args[6] == "deleteFileInGuest" && args[8] == "/tmp/vmware2" {
+			return &runner.Result{Stdout: "Error: A file was not found\n", ExitCode: 255}, nil
+		}
+		return guest(ctx, name, args...)
+	}))
+
+	got, err := c.ExecInGuest(context.Background(), "fusion", testVMX, "user", "pass", ShellPOSIX, "echo hello")
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("ExecInGuest error = %v, want %v", err, ErrFileNotFound)
+	}
+	if want := (&ExecResult{Stdout: "hello\n"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ExecInGuest = %+v, want %+v", got, want)
+	}
+	if want := []string{"/tmp/vmware1", "/tmp/vmware3"}; !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("deleted guest files = %q, want %q", deleted, want)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"strings"
+)
+
+// guestSeparator returns the path separator of the guest path p.
+//
+// The guest OS is unknown from the host, so the path including a backslash is treated as the Windows path.
+func guestSeparator(p string) string {
+	if strings.Contains(p, `\`) {
+		return `\`
+	}
+
+	return "/"
+}
+
+// GuestJoin joins the guest directory path and name with the separator of dir.
+//
+// The dir including a backslash is treated as the Windows path.
+func GuestJoin(dir, name string) string {
+	sep := guestSeparator(dir)
+	if strings.HasSuffix(dir, sep) {
+		return dir + name
+	}
+
+	return dir + sep + name
+}
+
+// guestPathPrefixes returns the paths of all ancestors of p and p itself, from the top.
+//
+// The root directory such as "/" or `C:\` is not included.
+func guestPathPrefixes(p string) []string {
+	sep := guestSeparator(p)
+
+	var prefixes []string
+	for i := 1; i < len(p); i++ {
+		if p[i:i+1] != sep || p[i-1:i] == sep || strings.HasSuffix(p[:i], ":") {
+			continue
+		}
+		prefixes = append(prefixes, p[:i])
+	}
+	if p = strings.TrimRight(p, sep); p != "" && !strings.HasSuffix(p, ":") {
+		if len(prefixes) == 0 || prefixes[len(prefixes)-1] != p {
+			prefixes = append(prefixes, p)
+		}
+	}
+
+	return prefixes
+}
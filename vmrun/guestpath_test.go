@@ -0,0 +1,52 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGuestPathPrefixes(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{path: "/", want: nil},
+		{path: "/tmp", want: []string{"/tmp"}},
+		{path: "/tmp/a b/c.d/", want: []string{"/tmp", "/tmp/a b", "/tmp/a b/c.d"}},
+		{path: "tmp//a", want: []string{"tmp", "tmp//a"}},
+		{path: `C:\`, want: nil},
+		{path: `C:\Users\vmware\build`, want: []string{`C:\Users`, `C:\Users\vmware`, `C:\Users\vmware\build`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := guestPathPrefixes(tt.path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("guestPathPrefixes(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuestJoin(t *testing.T) {
+	tests := []struct {
+		dir  string
+		name string
+		want string
+	}{
+		{dir: "/tmp", name: "a.txt", want: "/tmp/a.txt"},
+		{dir: "/tmp/", name: "a.txt", want: "/tmp/a.txt"},
+		{dir: "/", name: "tmp", want: "/tmp"},
+		{dir: `C:\Temp`, name: "a.txt", want: `C:\Temp\a.txt`},
+		{dir: `C:\`, name: "Temp", want: `C:\Temp`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			if got := GuestJoin(tt.dir, tt.name); got != tt.want {
+				t.Errorf("GuestJoin(%q, %q) = %q, want %q", tt.dir, tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-vm/vmware/vmsd"
+)
+
+// SnapshotNode represents a snapshot in the snapshot tree of a VM.
+type SnapshotNode struct {
+	// Name is the display name of the snapshot, which may not be unique in the VM.
+	Name string
+	// Parent is the parent snapshot, or nil if the snapshot is a root.
+	Parent *SnapshotNode
+	// Children is the child snapshots in the listed order.
+	Children []*SnapshotNode
+}
+
+// Path returns the slash separated names from the root to s,
+// which vmrun accepts to identify the snapshot which name is not unique.
+func (s *SnapshotNode) Path() string {
+	names := []string{s.Name}
+	for p := s.Parent; p != nil; p = p.Parent {
+		names = append([]string{p.Name}, names...)
+	}
+
+	return strings.Join(names, "/")
+}
+
+// Ancestors returns the ancestors of s from the parent to the root.
+func (s *SnapshotNode) Ancestors() []*SnapshotNode {
+	var ancestors []*SnapshotNode
+	for p := s.Parent; p != nil; p = p.Parent {
+		ancestors = append(ancestors, p)
+	}
+
+	return ancestors
+}
+
+// Descendants returns the descendants of s in depth-first pre-order.
+func (s *SnapshotNode) Descendants() []*SnapshotNode {
+	var descendants []*SnapshotNode
+	for _, child := range s.Children {
+		descendants = append(descendants, child)
+		descendants = append(descendants, child.Descendants()...)
+	}
+
+	return descendants
+}
+
+// IsLeaf reports whether s has no children.
+func (s *SnapshotNode) IsLeaf() bool {
+	return len(s.Children) == 0
+}
+
+// SnapshotTree represents the snapshot tree of a VM.
+type SnapshotTree struct {
+	// Roots is the snapshots which have no parent.
+	Roots []*SnapshotNode
+	// Current is the snapshot which the VM state is based on, or nil if it is unknown.
+	//
+	// vmrun does not report the current snapshot, so it is read from the .vmsd file next to the .vmx file if it is accessible.
+	Current *SnapshotNode
+}
+
+// All returns all snapshots in t in depth-first pre-order.
+func (t *SnapshotTree) All() []*SnapshotNode {
+	var all []*SnapshotNode
+	for _, root := range t.Roots {
+		all = append(all, root)
+		all = append(all, root.Descendants()...)
+	}
+
+	return all
+}
+
+// Find returns the snapshots named name.
+func (t *SnapshotTree) Find(name string) []*SnapshotNode {
+	var found []*SnapshotNode
+	for _, s := range t.All() {
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+
+	return found
+}
+
+// Leaves returns the snapshots which have no children.
+func (t *SnapshotTree) Leaves() []*SnapshotNode {
+	var leaves []*SnapshotNode
+	for _, s := range t.All() {
+		if s.IsLeaf() {
+			leaves = append(leaves, s)
+		}
+	}
+
+	return leaves
+}
+
+// findPath returns the snapshot which names from the root are names.
+func (t *SnapshotTree) findPath(names []string) *SnapshotNode {
+	candidates := t.Roots
+	var found *SnapshotNode
+	for _, name := range names {
+		found = nil
+		for _, s := range candidates {
+			if s.Name == name {
+				found = s
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		candidates = found.Children
+	}
+
+	return found
+}
+
+// parseSnapshotTree parses the listSnapshots showTree command output such as:
+//
+//	Total snapshots: 4
+//	base
+//		with tools
+//			build 1.0
+//		clean
+//
+// The depth of a snapshot is determined by its indentation relative to the previous lines.
+func parseSnapshotTree(s string) *SnapshotTree {
+	type level struct {
+		indent   int
+		snapshot *SnapshotNode
+	}
+
+	tree := &SnapshotTree{}
+	var stack []level
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		name := strings.TrimLeft(line, " \t")
+		if name == "" || (i == 0 && strings.HasPrefix(line, "Total snapshots:")) {
+			continue
+		}
+		indent := len(line) - len(name)
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		snapshot := &SnapshotNode{Name: name}
+		if len(stack) == 0 {
+			tree.Roots = append(tree.Roots, snapshot)
+		} else {
+			parent := stack[len(stack)-1].snapshot
+			snapshot.Parent = parent
+			parent.Children = append(parent.Children, snapshot)
+		}
+		stack = append(stack, level{indent: indent, snapshot: snapshot})
+	}
+
+	return tree
+}
+
+// currentSnapshotPath returns the snapshot names from the root to the current snapshot,
+// which is read from the .vmsd file of the vmx. It returns nil if the .vmsd file is not readable.
+func currentSnapshotPath(vmx string) []string {
+	f, err := vmsd.ReadFile(vmsd.Path(vmx))
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for s := f.Current(); s != nil; {
+		names = append([]string{s.DisplayName}, names...)
+		if s.Parent == 0 || len(names) > len(f.Snapshots()) {
+			break
+		}
+		if s, err = f.Snapshot(s.Parent); err != nil {
+			return nil
+		}
+	}
+
+	return names
+}
+
+// ListSnapshotTree list all snapshots in a VM as the parent/child tree.
+func ListSnapshotTree(app, vmx string) (*SnapshotTree, error) {
+	return ListSnapshotTreeContext(context.Background(), app, vmx)
+}
+
+// ListSnapshotTreeContext is like ListSnapshotTree but includes a context.
+func ListSnapshotTreeContext(ctx context.Context, app, vmx string) (*SnapshotTree, error) {
+	return DefaultClient.ListSnapshotTree(ctx, app, vmx)
+}
+
+// ListSnapshotTree list all snapshots in a VM as the parent/child tree.
+func (c *Client) ListSnapshotTree(ctx context.Context, app, vmx string) (*SnapshotTree, error) {
+	stdout, err := c.vmrun(ctx, classSnapshot, app, "listSnapshots", vmx, "showTree")
+	if err != nil {
+		return nil, err
+	}
+
+	tree := parseSnapshotTree(stdout)
+	if names := currentSnapshotPath(vmx); len(names) > 0 {
+		tree.Current = tree.findPath(names)
+	}
+
+	return tree, nil
+}
+
+// List of the errors returned by CreateSnapshot before running vmrun.
+var (
+	// ErrSnapshotExists is returned when the snapshot name already exists in the VM.
+	ErrSnapshotExists = errors.New("vmrun: snapshot already exists")
+	// ErrInvalidSnapshotName is returned when the snapshot name is empty or contains a slash,
+	// which vmrun treats as the path separator of the snapshot tree.
+	ErrInvalidSnapshotName = errors.New("vmrun: invalid snapshot name")
+)
+
+// SnapshotMode represents the behavior of CreateSnapshot when the snapshot name already exists.
+type SnapshotMode int
+
+const (
+	// SnapshotFailIfExists returns ErrSnapshotExists if the name already exists.
+	SnapshotFailIfExists SnapshotMode = iota
+	// SnapshotReplace creates the new snapshot under a temporary name, deletes the existing snapshot,
+	// and renames the new one in the .vmsd file, so the existing snapshot is kept if the new one can not be created.
+	// The children of the existing snapshot are kept. The .vmsd file must be accessible from the host which runs vmrun.
+	SnapshotReplace
+	// SnapshotIfAbsent creates the snapshot only if the name does not exist.
+	SnapshotIfAbsent
+)
+
+// SnapshotOptions represents the options of CreateSnapshot.
+type SnapshotOptions struct {
+	// Mode is the behavior when the snapshot name already exists.
+	Mode SnapshotMode
+	// Description is written into the .vmsd file after the snapshot is created, if not empty.
+	// The .vmsd file must be accessible from the host which runs vmrun.
+	Description string
+}
+
+// CreateSnapshot create a snapshot of a VM with the unique name, and reports whether the snapshot is created.
+func CreateSnapshot(app, vmx, snapshotName string, opts *SnapshotOptions) (bool, error) {
+	return CreateSnapshotContext(context.Background(), app, vmx, snapshotName, opts)
+}
+
+// CreateSnapshotContext is like CreateSnapshot but includes a context.
+func CreateSnapshotContext(ctx context.Context, app, vmx, snapshotName string, opts *SnapshotOptions) (bool, error) {
+	return DefaultClient.CreateSnapshot(ctx, app, vmx, snapshotName, opts)
+}
+
+// CreateSnapshot create a snapshot of a VM with the unique name, and reports whether the snapshot is created.
+func (c *Client) CreateSnapshot(ctx context.Context, app, vmx, snapshotName string, opts *SnapshotOptions) (bool, error) {
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+	if snapshotName == "" || strings.Contains(snapshotName, "/") {
+		return false, ErrInvalidSnapshotName
+	}
+
+	names, _, err := c.ListSnapshots(ctx, app, vmx)
+	if err != nil {
+		return false, err
+	}
+	var n int
+	for _, name := range names {
+		if name == snapshotName {
+			n++
+		}
+	}
+
+	if n > 0 {
+		switch opts.Mode {
+		case SnapshotIfAbsent:
+			return false, nil
+		case SnapshotReplace:
+			if n > 1 {
+				return false, ErrSnapshotNotUnique
+			}
+			return c.replaceSnapshot(ctx, app, vmx, snapshotName, names, opts.Description)
+		default:
+			return false, ErrSnapshotExists
+		}
+	}
+
+	if err := c.Snapshot(ctx, app, vmx, snapshotName); err != nil {
+		return false, err
+	}
+	if opts.Description != "" {
+		if err := setSnapshotDescription(vmx, snapshotName, opts.Description); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// replaceSnapshot creates the new snapshot under a temporary name which is not in names, deletes the old snapshot
+// of the name, and renames the new snapshot to the name in the .vmsd file.
+//
+// If the old snapshot can not be deleted, or the new snapshot can not be renamed, the new snapshot is left
+// under the temporary name.
+func (c *Client) replaceSnapshot(ctx context.Context, app, vmx, name string, names []string, description string) (bool, error) {
+	temp := tempSnapshotName(names, name)
+	if err := c.Snapshot(ctx, app, vmx, temp); err != nil {
+		return false, err
+	}
+	if err := c.DeleteSnapshot(ctx, app, vmx, name, false); err != nil {
+		return true, err
+	}
+
+	path := vmsd.Path(vmx)
+	f, err := vmsd.ReadFile(path)
+	if err != nil {
+		return true, err
+	}
+	var s *vmsd.Snapshot
+	for _, snapshot := range f.Snapshots() {
+		if snapshot.DisplayName == temp {
+			s = snapshot
+		}
+	}
+	if s == nil {
+		return true, ErrSnapshotNotFound
+	}
+	if err := f.SetDisplayName(s.UID, name); err != nil {
+		return true, err
+	}
+	if description != "" {
+		if err := f.SetDescription(s.UID, description); err != nil {
+			return true, err
+		}
+	}
+
+	return true, f.WriteFile(path)
+}
+
+// tempSnapshotName returns the temporary name of the snapshot which replaces the name snapshot, such as "ci.new".
+func tempSnapshotName(names []string, name string) string {
+	exists := make(map[string]bool)
+	for _, n := range names {
+		exists[n] = true
+	}
+	temp := name + ".new"
+	for i := 2; exists[temp]; i++ {
+		temp = name + ".new" + strconv.Itoa(i)
+	}
+
+	return temp
+}
+
+// setSnapshotDescription writes the description of the current snapshot named name into the .vmsd file of the vmx.
+func setSnapshotDescription(vmx, name, description string) error {
+	path := vmsd.Path(vmx)
+	f, err := vmsd.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// the new snapshot becomes the current snapshot.
+	s := f.Current()
+	if s == nil || s.DisplayName != name {
+		return ErrSnapshotNotFound
+	}
+	if err := f.SetDescription(s.UID, description); err != nil {
+		return err
+	}
+
+	return f.WriteFile(path)
+}
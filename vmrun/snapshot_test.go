@@ -0,0 +1,280 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+	"github.com/go-vm/vmware/vmsd"
+)
+
+const testShowTree = "Total snapshots: 5\n" +
+	"base\n" +
+	"\twith tools\n" +
+	"\t\tbuild 1.0\n" +
+	"\t\tbuild-1.1.rc\n" +
+	"\tclean\n"
+
+// snapshotPaths returns the paths of snapshots.
+func snapshotPaths(snapshots []*SnapshotNode) []string {
+	var paths []string
+	for _, s := range snapshots {
+		paths = append(paths, s.Path())
+	}
+	return paths
+}
+
+func TestParseSnapshotTree(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			name: "tabs",
+			s:    testShowTree,
+			want: []string{"base", "base/with tools", "base/with tools/build 1.0", "base/with tools/build-1.1.rc", "base/clean"},
+		},
+		{
+			name: "spaces and multiple roots",
+			s:    "Total snapshots: 4\r\na\r\n  b\r\n    c\r\nd\r\n",
+			want: []string{"a", "a/b", "a/b/c", "d"},
+		},
+		{
+			name: "empty",
+			s:    "Total snapshots: 0\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := parseSnapshotTree(tt.s)
+			if got := snapshotPaths(tree.All()); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseSnapshotTree(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotTraversal(t *testing.T) {
+	tree := parseSnapshotTree(testShowTree)
+
+	found := tree.Find("build 1.0")
+	if len(found) != 1 {
+		t.Fatalf("Find = %d snapshots, want 1", len(found))
+	}
+	build := found[0]
+	if got, want := snapshotPaths(build.Ancestors()), []string{"base/with tools", "base"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Ancestors = %q, want %q", got, want)
+	}
+	if !build.IsLeaf() {
+		t.Errorf("IsLeaf = false, want true")
+	}
+	if got, want := snapshotPaths(tree.Roots[0].Descendants()), []string{"base/with tools", "base/with tools/build 1.0", "base/with tools/build-1.1.rc", "base/clean"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Descendants = %q, want %q", got, want)
+	}
+	if got, want := snapshotPaths(tree.Leaves()), []string{"base/with tools/build 1.0", "base/with tools/build-1.1.rc", "base/clean"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Leaves = %q, want %q", got, want)
+	}
+}
+
+func TestClientListSnapshotTree(t *testing.T) {
+	dir := t.TempDir()
+	vmx := filepath.Join(dir, "test.vmx")
+	vmsd := `.encoding = "UTF-8"
+snapshot.lastUID = "4"
+snapshot.current = "3"
+snapshot0.uid = "1"
+snapshot0.displayName = "base"
+snapshot1.uid = "2"
+snapshot1.parent = "1"
+snapshot1.displayName = "with tools"
+snapshot2.uid = "3"
+snapshot2.parent = "2"
+snapshot2.displayName = "build-1.1.rc"
+snapshot.numSnapshots = "3"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.vmsd"), []byte(vmsd), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := newTestClient(runnertest.Step{
+		Args:   []string{"-T", "fusion", "listSnapshots", vmx, "showTree"},
+		Result: runner.Result{Stdout: testShowTree},
+	})
+
+	tree, err := c.ListSnapshotTree(context.Background(), "fusion", vmx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Current == nil || tree.Current.Path() != "base/with tools/build-1.1.rc" {
+		t.Fatalf("Current = %+v, want base/with tools/build-1.1.rc", tree.Current)
+	}
+}
+
+func TestParseListSnapshots(t *testing.T) {
+	s := "Total snapshots: 3\nbase\nwith tools 1.0\nbuild-1.1.rc\n"
+	want := []string{"base", "with tools 1.0", "build-1.1.rc"}
+	if got := parseListSnapshots(s); !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseListSnapshots(%q) = %q, want %q", s, got, want)
+	}
+}
+
+func TestClientCreateSnapshot(t *testing.T) {
+	list := func(names ...string) runnertest.Step {
+		stdout := fmt.Sprintf("Total snapshots: %d\n", len(names))
+		for _, name := range names {
+			stdout += name + "\n"
+		}
+		return runnertest.Step{Args: []string{"-T", "fusion", "listSnapshots", testVMX}, Result: runner.Result{Stdout: stdout}}
+	}
+	create := runnertest.Step{Args: []string{"-T", "fusion", "snapshot", testVMX, "ci 1.0"}}
+
+	tests := []struct {
+		name    string
+		mode    SnapshotMode
+		snap    string
+		steps   []runnertest.Step
+		want    bool
+		wantErr error
+	}{
+		{name: "create", mode: SnapshotFailIfExists, snap: "ci 1.0", steps: []runnertest.Step{list("base"), create}, want: true},
+		{name: "fail if exists", mode: SnapshotFailIfExists, snap: "ci 1.0", steps: []runnertest.Step{list("base", "ci 1.0")}, wantErr: ErrSnapshotExists},
+		{name: "if absent exists", mode: SnapshotIfAbsent, snap: "ci 1.0", steps: []runnertest.Step{list("ci 1.0")}},
+		{name: "if absent", mode: SnapshotIfAbsent, snap: "ci 1.0", steps: []runnertest.Step{list(), create}, want: true},
+		{name: "replace not unique", mode: SnapshotReplace, snap: "ci 1.0", steps: []runnertest.Step{list("ci 1.0", "ci 1.0")}, wantErr: ErrSnapshotNotUnique},
+		{name: "empty name", snap: "", wantErr: ErrInvalidSnapshotName},
+		{name: "slash name", snap: "a/b", wantErr: ErrInvalidSnapshotName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(tt.steps...)
+
+			got, err := c.CreateSnapshot(context.Background(), "fusion", testVMX, tt.snap, &SnapshotOptions{Mode: tt.mode})
+			if err != tt.wantErr {
+				t.Fatalf("CreateSnapshot error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("CreateSnapshot = %v, want %v", got, tt.want)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestClientCreateSnapshotDescription(t *testing.T) {
+	vmx := filepath.Join(t.TempDir(), "test.vmx")
+	// the .vmsd file after the snapshot command.
+	content := "snapshot.current = \"2\"\nsnapshot0.uid = \"1\"\nsnapshot0.displayName = \"base\"\n" +
+		"snapshot1.uid = \"2\"\nsnapshot1.parent = \"1\"\nsnapshot1.displayName = \"ci\"\n"
+	if err := ioutil.WriteFile(vmsd.Path(vmx), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ := newTestClient(
+		runnertest.Step{Args: []string{"-T", "fusion", "listSnapshots", vmx}, Result: runner.Result{Stdout: "Total snapshots: 1\nbase\n"}},
+		runnertest.Step{Args: []string{"-T", "fusion", "snapshot", vmx, "ci"}},
+	)
+	if _, err := c.CreateSnapshot(context.Background(), "fusion", vmx, "ci", &SnapshotOptions{Description: "built by \"ci\""}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := vmsd.ReadFile(vmsd.Path(vmx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := f.Current(); s.Description != `built by "ci"` {
+		t.Fatalf("Description = %q, want %q", s.Description, `built by "ci"`)
+	}
+}
+
+func TestClientCreateSnapshotReplace(t *testing.T) {
+	// the .vmsd file before and after the snapshot command, and after the deleteSnapshot command.
+	before := "snapshot.current = \"2\"\nsnapshot0.uid = \"1\"\nsnapshot0.displayName = \"base\"\n" +
+		"snapshot1.uid = \"2\"\nsnapshot1.parent = \"1\"\nsnapshot1.displayName = \"ci\"\n"
+	created := "snapshot.current = \"3\"\nsnapshot0.uid = \"1\"\nsnapshot0.displayName = \"base\"\n" +
+		"snapshot1.uid = \"2\"\nsnapshot1.parent = \"1\"\nsnapshot1.displayName = \"ci\"\n" +
+		"snapshot2.uid = \"3\"\nsnapshot2.parent = \"2\"\nsnapshot2.displayName = \"ci.new2\"\n"
+	deleted := "snapshot.current = \"3\"\nsnapshot0.uid = \"1\"\nsnapshot0.displayName = \"base\"\n" +
+		"snapshot1.uid = \"3\"\nsnapshot1.parent = \"1\"\nsnapshot1.displayName = \"ci.new2\"\n"
+	failed := runner.Result{Stdout: "Error: The virtual machine is not powered on: /vm/test.vmx\n", ExitCode: 255}
+
+	tests := []struct {
+		name       string
+		snapshot   runner.Result
+		want       bool
+		wantErr    error
+		wantDelete bool
+		wantVMSD   []string // display names in the .vmsd file
+	}{
+		{name: "replace", want: true, wantDelete: true, wantVMSD: []string{"base", "ci"}},
+		{name: "snapshot fails", snapshot: failed, wantErr: ErrNotPoweredOn, wantVMSD: []string{"base", "ci"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vmx := filepath.Join(t.TempDir(), "test.vmx")
+			if err := ioutil.WriteFile(vmsd.Path(vmx), []byte(before), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			steps := []runnertest.Step{
+				{Args: []string{"-T", "fusion", "listSnapshots", vmx}, Result: runner.Result{Stdout: "Total snapshots: 3\nbase\nci\nci.new\n"}},
+				{Args: []string{"-T", "fusion", "snapshot", vmx, "ci.new2"}, Result: tt.snapshot},
+			}
+			if tt.wantDelete {
+				steps = append(steps, runnertest.Step{Args: []string{"-T", "fusion", "deleteSnapshot", vmx, "ci"}})
+			}
+			fake := runnertest.NewFake(steps...)
+			c := NewClient(runnertest.Func(func(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+				result, err := fake.Run(ctx, name, args...)
+				content := map[string]string{"snapshot": created, "deleteSnapshot": deleted}[args[2]]
+				if err == nil && result.ExitCode == 0 && content != "" {
+					if err := ioutil.WriteFile(vmsd.Path(vmx), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return result, err
+			}))
+
+			got, err := c.CreateSnapshot(context.Background(), "fusion", vmx, "ci", &SnapshotOptions{Mode: SnapshotReplace, Description: "new"})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateSnapshot error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("CreateSnapshot = %v, want %v", got, tt.want)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := vmsd.ReadFile(vmsd.Path(vmx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, s := range f.Snapshots() {
+				names = append(names, s.DisplayName)
+			}
+			if !reflect.DeepEqual(names, tt.wantVMSD) {
+				t.Fatalf("snapshots = %q, want %q", names, tt.wantVMSD)
+			}
+			if tt.want {
+				if s := f.Current(); s.DisplayName != "ci" || s.Description != "new" {
+					t.Fatalf("Current = %+v, want the new snapshot ci", s)
+				}
+			}
+		})
+	}
+}
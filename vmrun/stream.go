@@ -0,0 +1,211 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPollInterval is the default interval of polling the guest process by StreamInGuest.
+const DefaultPollInterval = time.Second
+
+// StreamOptions represents the options of StreamInGuest.
+type StreamOptions struct {
+	// Stdout receives the standard output of the command while it runs. If nil, the output is discarded.
+	Stdout io.Writer
+	// Stderr receives the standard error of the command while it runs. If nil, the output is discarded.
+	Stderr io.Writer
+	// PollInterval is the interval of polling the guest process and its output. If zero, DefaultPollInterval is used.
+	PollInterval time.Duration
+}
+
+// StreamInGuest run a command line with the shell in Guest OS, writes its stdout and stderr to the writers of opts
+// while it runs, and returns its exit code.
+//
+// The command is started without waiting, and its output redirected to temporary files in Guest OS is tailed
+// every poll interval until the process exits and its exit code is written.
+// If ctx is done, the guest process is killed and ctx.Err() is returned.
+// The non-zero exit code of the command is not an error.
+func StreamInGuest(app, vmx, username, password string, shell Shell, command string, opts *StreamOptions) (int, error) {
+	return StreamInGuestContext(context.Background(), app, vmx, username, password, shell, command, opts)
+}
+
+// StreamInGuestContext is like StreamInGuest but includes a context.
+func StreamInGuestContext(ctx context.Context, app, vmx, username, password string, shell Shell, command string, opts *StreamOptions) (int, error) {
+	return DefaultClient.StreamInGuest(ctx, app, vmx, username, password, shell, command, opts)
+}
+
+// StreamInGuest run a command line with the shell in Guest OS, writes its stdout and stderr to the writers of opts
+// while it runs, and returns its exit code.
+//
+// The command is started without waiting, and its output redirected to temporary files in Guest OS is tailed
+// every poll interval until the process exits and its exit code is written.
+// If ctx is done, the guest process is killed and ctx.Err() is returned.
+// The non-zero exit code of the command is not an error.
+func (c *Client) StreamInGuest(ctx context.Context, app, vmx, username, password string, shell Shell, command string, opts *StreamOptions) (_ int, err error) {
+	if opts == nil {
+		opts = &StreamOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	files, err := c.newExecFiles(ctx, app, vmx, username, password)
+	defer files.remove(&err)
+	if err != nil {
+		return 0, err
+	}
+
+	tails := []*tail{
+		{files: files, i: 0, w: opts.Stdout},
+		{files: files, i: 1, w: opts.Stderr},
+	}
+
+	return c.runInGuest(ctx, app, vmx, username, password, shell, command, files, interval, tails)
+}
+
+// runInGuest starts the command with the shell in guest OS without waiting, and polls the guest process every interval
+// until it exits and its exit code is written to files. The output appended to files is written to tails on each poll.
+//
+// If ctx is done, the guest process is killed before ctx.Err() is returned, so that files can be deleted.
+func (c *Client) runInGuest(ctx context.Context, app, vmx, username, password string, shell Shell, command string, files *execFiles, interval time.Duration, tails []*tail) (int, error) {
+	// the exit code file path is unique, so it identifies the shell process from its command line.
+	marker := files.guest[2]
+	args := shell.args(command, files.guest[0], files.guest[1], files.guest[2])
+	if err := c.RunProgramInGuest(ctx, app, vmx, username, password, NoWait, shell.program(), args...); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// the process may have been started before vmrun is killed.
+			return 0, c.killInGuest(app, vmx, username, password, "", marker, ctxErr)
+		}
+		return 0, err
+	}
+
+	var (
+		pid  string
+		code []byte
+	)
+	for {
+		procs, err := c.ListProcessesInGuest(ctx, app, vmx, username, password)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return 0, c.killInGuest(app, vmx, username, password, pid, marker, ctxErr)
+			}
+			return 0, err
+		}
+		if pid == "" {
+			pid = findProcess(procs, marker)
+		}
+		running := pid != "" && hasProcess(procs, pid)
+		if !running {
+			// the process which is not listed yet may be starting, or may have already exited,
+			// so it is finished only when the exit code is written.
+			code, err = files.read(ctx, 2)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return 0, c.killInGuest(app, vmx, username, password, pid, marker, ctxErr)
+				}
+				return 0, err
+			}
+			running = pid == "" && len(bytes.TrimSpace(code)) == 0
+		}
+
+		for _, t := range tails {
+			if err := t.flush(ctx); err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return 0, c.killInGuest(app, vmx, username, password, pid, marker, ctxErr)
+				}
+				return 0, err
+			}
+		}
+		if !running {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, c.killInGuest(app, vmx, username, password, pid, marker, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+
+	return parseExitCode(shell, string(code))
+}
+
+// killInGuest kills the pid guest process, or the process which command line contains marker if pid is not found yet,
+// and returns err.
+//
+// The process is killed even if the context of the command is done, within the cleanup timeout.
+func (c *Client) killInGuest(app, vmx, username, password, pid, marker string, err error) error {
+	ctx, cancel := cleanupContext()
+	defer cancel()
+
+	if pid == "" {
+		procs, listErr := c.ListProcessesInGuest(ctx, app, vmx, username, password)
+		if listErr != nil {
+			return err
+		}
+		pid = findProcess(procs, marker)
+	}
+	if n, convErr := strconv.Atoi(pid); convErr == nil {
+		_ = c.KillProcessInGuest(ctx, app, vmx, username, password, n)
+	}
+
+	return err
+}
+
+// findProcess returns the pid of the process which command line contains marker.
+func findProcess(procs []ListProcessesInGuestInfo, marker string) string {
+	for _, p := range procs {
+		if strings.Contains(p.Cmd, marker) {
+			return p.Pid
+		}
+	}
+
+	return ""
+}
+
+// hasProcess reports whether procs has the pid process.
+func hasProcess(procs []ListProcessesInGuestInfo, pid string) bool {
+	for _, p := range procs {
+		if p.Pid == pid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tail represents the output guest file of StreamInGuest which is written to w incrementally.
+type tail struct {
+	files  *execFiles
+	i      int
+	w      io.Writer
+	offset int
+}
+
+// flush writes the output appended since the last flush to t.w.
+func (t *tail) flush(ctx context.Context) error {
+	if t.w == nil {
+		return nil
+	}
+
+	data, err := t.files.read(ctx, t.i)
+	if err != nil {
+		return err
+	}
+	if len(data) <= t.offset {
+		return nil
+	}
+	n, err := t.w.Write(data[t.offset:])
+	t.offset += n
+
+	return err
+}
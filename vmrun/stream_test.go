@@ -0,0 +1,172 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+)
+
+// streamGuest emulates the guest commands used by StreamInGuest.
+// The guest process is not listed by the first delay listprocessesinguest commands, then runs until the command is called
+// delay+polls times, and appends a line to the stdout on each poll. The exit code is written when the process exits.
+type streamGuest struct {
+	t       *testing.T
+	delay   int
+	polls   int
+	owner   string
+	temps   int
+	listed  int
+	killed  []string
+	deleted []string
+	program []string
+}
+
+func (g *streamGuest) run(ctx context.Context, name string, args ...string) (*runner.Result, error) {
+	// args: -T app -gu user -gp pass command vmx params...
+	params := args[8:]
+	switch cmd := args[6]; cmd {
+	case "CreateTempfileInGuest":
+		g.temps++
+		return &runner.Result{Stdout: fmt.Sprintf("/tmp/vmware%d\n", g.temps)}, nil
+	case "runProgramInGuest":
+		g.program = params
+	case "listprocessesinguest":
+		g.listed++
+		stdout := "Process list: 2\npid=1, owner=root, cmd=/sbin/init\n"
+		owner := g.owner
+		if owner == "" {
+			owner = "user"
+		}
+		if g.listed > g.delay && g.listed <= g.delay+g.polls {
+			stdout += "pid=42, owner=" + owner + ", cmd=/bin/sh -c (make) >'/tmp/vmware1' 2>'/tmp/vmware2'; echo $? >'/tmp/vmware3'\n"
+		}
+		return &runner.Result{Stdout: stdout}, nil
+	case "CopyFileFromGuestToHost":
+		var data string
+		switch params[0] {
+		case "/tmp/vmware1":
+			for i := 1; i <= g.listed; i++ {
+				data += fmt.Sprintf("line %d\n", i)
+			}
+		case "/tmp/vmware3":
+			if g.listed > g.delay+g.polls {
+				data = "2\n"
+			}
+		}
+		if err := ioutil.WriteFile(params[1], []byte(data), 0644); err != nil {
+			g.t.Fatal(err)
+		}
+	case "killprocessinguest":
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > cleanupTimeout {
+			g.t.Fatalf("killprocessinguest deadline = %v, %v, want within %v", deadline, ok, cleanupTimeout)
+		}
+		g.killed = append(g.killed, params[0])
+	case "deleteFileInGuest":
+		g.deleted = append(g.deleted, params[0])
+	default:
+		g.t.Fatalf("unexpected command %q", cmd)
+	}
+
+	return &runner.Result{}, nil
+}
+
+func TestClientStreamInGuest(t *testing.T) {
+	tests := []struct {
+		name       string
+		g          *streamGuest
+		wantStdout string
+	}{
+		{
+			name:       "posix",
+			g:          &streamGuest{polls: 2},
+			wantStdout: "line 1\nline 2\nline 3\n",
+		},
+		{
+			name:       "windows owner",
+			g:          &streamGuest{polls: 2, owner: `WIN10\vagrant`},
+			wantStdout: "line 1\nline 2\nline 3\n",
+		},
+		{
+			name:       "not listed yet",
+			g:          &streamGuest{delay: 2, polls: 2},
+			wantStdout: "line 1\nline 2\nline 3\nline 4\nline 5\n",
+		},
+		{
+			name:       "exited before listed",
+			g:          &streamGuest{delay: 2},
+			wantStdout: "line 1\nline 2\nline 3\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.g
+			g.t = t
+			c := NewClient(runnertest.Func(g.run))
+
+			var stdout, stderr bytes.Buffer
+			opts := &StreamOptions{Stdout: &stdout, Stderr: &stderr, PollInterval: time.Millisecond}
+			code, err := c.StreamInGuest(context.Background(), "fusion", testVMX, "user", "pass", ShellPOSIX, "make", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != 2 {
+				t.Fatalf("StreamInGuest = %d, want %d", code, 2)
+			}
+			if stdout.String() != tt.wantStdout {
+				t.Fatalf("stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if stderr.Len() != 0 {
+				t.Fatalf("stderr = %q, want empty", stderr.String())
+			}
+			if g.program[0] != "-noWait" {
+				t.Fatalf("runProgramInGuest args = %q, want -noWait", g.program)
+			}
+			if want := []string{"/tmp/vmware1", "/tmp/vmware2", "/tmp/vmware3"}; !reflect.DeepEqual(g.deleted, want) {
+				t.Fatalf("deleted guest files = %q, want %q", g.deleted, want)
+			}
+			if len(g.killed) != 0 {
+				t.Fatalf("killed = %q, want none", g.killed)
+			}
+		})
+	}
+}
+
+// cancelWriter cancels the context on the first write.
+type cancelWriter struct {
+	cancel context.CancelFunc
+}
+
+func (w cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return len(p), nil
+}
+
+func TestClientStreamInGuestCancel(t *testing.T) {
+	g := &streamGuest{t: t, polls: 100}
+	c := NewClient(runnertest.Func(g.run))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &StreamOptions{Stdout: cancelWriter{cancel: cancel}, PollInterval: time.Hour}
+	if _, err := c.StreamInGuest(ctx, "fusion", testVMX, "user", "pass", ShellPOSIX, "make", opts); err != context.Canceled {
+		t.Fatalf("StreamInGuest error = %v, want %v", err, context.Canceled)
+	}
+	if want := []string{"42"}; !reflect.DeepEqual(g.killed, want) {
+		t.Fatalf("killed = %q, want %q", g.killed, want)
+	}
+	if len(g.deleted) != 3 {
+		t.Fatalf("deleted guest files = %q, want 3 files", g.deleted)
+	}
+}
@@ -5,42 +5,14 @@
 package vmrun
 
 import (
-	"bytes"
-	"fmt"
-	"os/exec"
-	"path/filepath"
+	"context"
+	"errors"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
-	"syscall"
-
-	"github.com/go-vm/vmware/internal/vmwareutil"
 )
 
-var vmrunPath = vmwareutil.LookPath("vmrun")
-
-// vmrun run the vmrun command with the app name and args, return the stdout result and cmd error.
-func vmrun(app string, arg ...string) (string, error) {
-	// vmrun with nogui on VMware Fusion through at least 8.0.1 doesn't work right
-	// if the umask is set to not allow world-readable permissions
-	_ = syscall.Umask(022)
-
-	cmd := exec.Command(vmrunPath, "-T", app)
-	cmd.Args = append(cmd.Args, arg...)
-
-	var stdout bytes.Buffer
-	cmd.Stdout = &stdout
-
-	err := cmd.Run()
-	if err != nil {
-		if runErr := err.(*exec.ExitError); runErr != nil {
-			return "", fmt.Errorf(stdout.String())
-		}
-	}
-
-	return stdout.String(), err
-}
-
 // POWER COMMANDS           PARAMETERS           DESCRIPTION
 // --------------           ----------           -----------
 // start                    Path to vmx file     Start a VM or Team
@@ -61,12 +33,22 @@ func vmrun(app string, arg ...string) (string, error) {
 
 // Start start a VM or Team.
 func Start(app, vmx string, gui bool) error {
+	return StartContext(context.Background(), app, vmx, gui)
+}
+
+// StartContext is like Start but includes a context.
+func StartContext(ctx context.Context, app, vmx string, gui bool) error {
+	return DefaultClient.Start(ctx, app, vmx, gui)
+}
+
+// Start start a VM or Team.
+func (c *Client) Start(ctx context.Context, app, vmx string, gui bool) error {
 	flag := "nogui"
 	if gui {
 		flag = "gui"
 	}
 
-	if _, err := vmrun(app, "start", vmx, flag); err != nil {
+	if _, err := c.vmrun(ctx, classPower, app, "start", vmx, flag); err != nil {
 		return err
 	}
 
@@ -75,12 +57,22 @@ func Start(app, vmx string, gui bool) error {
 
 // Stop stop a VM or Team.
 func Stop(app, vmx string, hard bool) error {
+	return StopContext(context.Background(), app, vmx, hard)
+}
+
+// StopContext is like Stop but includes a context.
+func StopContext(ctx context.Context, app, vmx string, hard bool) error {
+	return DefaultClient.Stop(ctx, app, vmx, hard)
+}
+
+// Stop stop a VM or Team.
+func (c *Client) Stop(ctx context.Context, app, vmx string, hard bool) error {
 	flag := "soft"
 	if hard {
 		flag = "hard"
 	}
 
-	if _, err := vmrun(app, "stop", vmx, flag); err != nil {
+	if _, err := c.vmrun(ctx, classPower, app, "stop", vmx, flag); err != nil {
 		return err
 	}
 
@@ -89,12 +81,22 @@ func Stop(app, vmx string, hard bool) error {
 
 // Reset reset a VM or Team.
 func Reset(app, vmx string, hard bool) error {
+	return ResetContext(context.Background(), app, vmx, hard)
+}
+
+// ResetContext is like Reset but includes a context.
+func ResetContext(ctx context.Context, app, vmx string, hard bool) error {
+	return DefaultClient.Reset(ctx, app, vmx, hard)
+}
+
+// Reset reset a VM or Team.
+func (c *Client) Reset(ctx context.Context, app, vmx string, hard bool) error {
 	flag := "soft"
 	if hard {
 		flag = "hard"
 	}
 
-	if _, err := vmrun(app, "reset", vmx, flag); err != nil {
+	if _, err := c.vmrun(ctx, classPower, app, "reset", vmx, flag); err != nil {
 		return err
 	}
 
@@ -103,12 +105,22 @@ func Reset(app, vmx string, hard bool) error {
 
 // Suspend Suspend a VM or Team.
 func Suspend(app, vmx string, hard bool) error {
+	return SuspendContext(context.Background(), app, vmx, hard)
+}
+
+// SuspendContext is like Suspend but includes a context.
+func SuspendContext(ctx context.Context, app, vmx string, hard bool) error {
+	return DefaultClient.Suspend(ctx, app, vmx, hard)
+}
+
+// Suspend Suspend a VM or Team.
+func (c *Client) Suspend(ctx context.Context, app, vmx string, hard bool) error {
 	flag := "soft"
 	if hard {
 		flag = "hard"
 	}
 
-	if _, err := vmrun(app, "suspend", vmx, flag); err != nil {
+	if _, err := c.vmrun(ctx, classPower, app, "suspend", vmx, flag); err != nil {
 		return err
 	}
 
@@ -117,7 +129,17 @@ func Suspend(app, vmx string, hard bool) error {
 
 // Pause pause a VM.
 func Pause(app, vmx string) error {
-	if _, err := vmrun(app, "pause", vmx); err != nil {
+	return PauseContext(context.Background(), app, vmx)
+}
+
+// PauseContext is like Pause but includes a context.
+func PauseContext(ctx context.Context, app, vmx string) error {
+	return DefaultClient.Pause(ctx, app, vmx)
+}
+
+// Pause pause a VM.
+func (c *Client) Pause(ctx context.Context, app, vmx string) error {
+	if _, err := c.vmrun(ctx, classPower, app, "pause", vmx); err != nil {
 		return err
 	}
 
@@ -126,7 +148,17 @@ func Pause(app, vmx string) error {
 
 // Unpause unpause a VM.
 func Unpause(app, vmx string) error {
-	if _, err := vmrun(app, "unpause", vmx); err != nil {
+	return UnpauseContext(context.Background(), app, vmx)
+}
+
+// UnpauseContext is like Unpause but includes a context.
+func UnpauseContext(ctx context.Context, app, vmx string) error {
+	return DefaultClient.Unpause(ctx, app, vmx)
+}
+
+// Unpause unpause a VM.
+func (c *Client) Unpause(ctx context.Context, app, vmx string) error {
+	if _, err := c.vmrun(ctx, classPower, app, "unpause", vmx); err != nil {
 		return err
 	}
 
@@ -148,28 +180,59 @@ func Unpause(app, vmx string) error {
 // revertToSnapshot         Path to vmx file     Set VM state to a snapshot
 //                          Snapshot name
 
-var listSnapshotsRe = regexp.MustCompile(`[^Total snapshots: \d](\w+)`)
-
 // ListSnapshots list all snapshots in a VM.
 func ListSnapshots(app, vmx string) ([]string, int, error) {
-	stdout, err := vmrun(app, "listSnapshots", vmx)
+	return ListSnapshotsContext(context.Background(), app, vmx)
+}
+
+// ListSnapshotsContext is like ListSnapshots but includes a context.
+func ListSnapshotsContext(ctx context.Context, app, vmx string) ([]string, int, error) {
+	return DefaultClient.ListSnapshots(ctx, app, vmx)
+}
+
+// ListSnapshots list all snapshots in a VM.
+func (c *Client) ListSnapshots(ctx context.Context, app, vmx string) ([]string, int, error) {
+	stdout, err := c.vmrun(ctx, classSnapshot, app, "listSnapshots", vmx)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	list := listSnapshotsRe.FindAllString(stdout, -1)
-	// TODO(zchee): need re-append with TrimSpace?
-	var snapshotList []string
-	for _, snapshotName := range list {
-		snapshotList = append(snapshotList, strings.TrimSpace(snapshotName))
-	}
+	snapshotList := parseListSnapshots(stdout)
 
 	return snapshotList, len(snapshotList), nil
 }
 
+// parseListSnapshots parses the listSnapshots command output such as:
+//
+//	Total snapshots: 2
+//	base
+//	with tools 1.0
+func parseListSnapshots(s string) []string {
+	var names []string
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || (i == 0 && strings.HasPrefix(line, "Total snapshots:")) {
+			continue
+		}
+		names = append(names, line)
+	}
+
+	return names
+}
+
 // Snapshot create a snapshot of a VM.
 func Snapshot(app, vmx, snapshotName string) error {
-	if _, err := vmrun(app, "snapshot", vmx, snapshotName); err != nil {
+	return SnapshotContext(context.Background(), app, vmx, snapshotName)
+}
+
+// SnapshotContext is like Snapshot but includes a context.
+func SnapshotContext(ctx context.Context, app, vmx, snapshotName string) error {
+	return DefaultClient.Snapshot(ctx, app, vmx, snapshotName)
+}
+
+// Snapshot create a snapshot of a VM.
+func (c *Client) Snapshot(ctx context.Context, app, vmx, snapshotName string) error {
+	if _, err := c.vmrun(ctx, classSnapshot, app, "snapshot", vmx, snapshotName); err != nil {
 		return err
 	}
 
@@ -178,12 +241,22 @@ func Snapshot(app, vmx, snapshotName string) error {
 
 // DeleteSnapshot remove a snapshot from a VM.
 func DeleteSnapshot(app, vmx, snapshotName string, deleteChildren bool) error {
+	return DeleteSnapshotContext(context.Background(), app, vmx, snapshotName, deleteChildren)
+}
+
+// DeleteSnapshotContext is like DeleteSnapshot but includes a context.
+func DeleteSnapshotContext(ctx context.Context, app, vmx, snapshotName string, deleteChildren bool) error {
+	return DefaultClient.DeleteSnapshot(ctx, app, vmx, snapshotName, deleteChildren)
+}
+
+// DeleteSnapshot remove a snapshot from a VM.
+func (c *Client) DeleteSnapshot(ctx context.Context, app, vmx, snapshotName string, deleteChildren bool) error {
 	args := []string{"deleteSnapshot", vmx, snapshotName}
 	if deleteChildren {
 		args = append(args, "andDeleteChildren")
 	}
 
-	if _, err := vmrun(app, args...); err != nil {
+	if _, err := c.vmrun(ctx, classSnapshot, app, args...); err != nil {
 		return err
 	}
 
@@ -192,7 +265,17 @@ func DeleteSnapshot(app, vmx, snapshotName string, deleteChildren bool) error {
 
 // RevertToSnapshot set VM state to a snapshot.
 func RevertToSnapshot(app, vmx, snapshotName string) error {
-	if _, err := vmrun(app, "revertToSnapshot", vmx, snapshotName); err != nil {
+	return RevertToSnapshotContext(context.Background(), app, vmx, snapshotName)
+}
+
+// RevertToSnapshotContext is like RevertToSnapshot but includes a context.
+func RevertToSnapshotContext(ctx context.Context, app, vmx, snapshotName string) error {
+	return DefaultClient.RevertToSnapshot(ctx, app, vmx, snapshotName)
+}
+
+// RevertToSnapshot set VM state to a snapshot.
+func (c *Client) RevertToSnapshot(ctx context.Context, app, vmx, snapshotName string) error {
+	if _, err := c.vmrun(ctx, classSnapshot, app, "revertToSnapshot", vmx, snapshotName); err != nil {
 		return err
 	}
 
@@ -318,7 +401,17 @@ func (r RunInGuestConfig) String() string {
 
 // RunProgramInGuest run a program in Guest OS.
 func RunProgramInGuest(app, vmx string, username, password string, config RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
-	args := []string{"-gu", username, "-gp", password, "runProgramInGuest", vmx}
+	return RunProgramInGuestContext(context.Background(), app, vmx, username, password, config, cmdPath, cmdArgs...)
+}
+
+// RunProgramInGuestContext is like RunProgramInGuest but includes a context.
+func RunProgramInGuestContext(ctx context.Context, app, vmx string, username, password string, config RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
+	return DefaultClient.RunProgramInGuest(ctx, app, vmx, username, password, config, cmdPath, cmdArgs...)
+}
+
+// RunProgramInGuest run a program in Guest OS.
+func (c *Client) RunProgramInGuest(ctx context.Context, app, vmx string, username, password string, config RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
+	args := c.guestArgs(username, password, "runProgramInGuest", vmx)
 
 	if config&NoWait > 0 {
 		args = append(args, NoWait.String())
@@ -333,7 +426,7 @@ func RunProgramInGuest(app, vmx string, username, password string, config RunInG
 	args = append(args, cmdPath)
 	args = append(args, cmdArgs...)
 
-	if _, err := vmrun(app, args...); err != nil {
+	if _, err := c.vmrun(ctx, classGuest, app, args...); err != nil {
 		return err
 	}
 
@@ -342,29 +435,76 @@ func RunProgramInGuest(app, vmx string, username, password string, config RunInG
 
 // FileExistsInGuest check if a file exists in Guest OS.
 func FileExistsInGuest(app, vmx string, username, password string, filename string) bool {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "fileExistsInGuest", vmx, filename); err != nil {
-		return false
+	exist, _ := FileExistsInGuestContext(context.Background(), app, vmx, username, password, filename)
+	return exist
+}
+
+// FileExistsInGuestContext is like FileExistsInGuest but includes a context.
+//
+// Unlike FileExistsInGuest, it reports the error that is not the file does not exist.
+func FileExistsInGuestContext(ctx context.Context, app, vmx string, username, password string, filename string) (bool, error) {
+	return DefaultClient.FileExistsInGuest(ctx, app, vmx, username, password, filename)
+}
+
+// FileExistsInGuest check if a file exists in Guest OS.
+func (c *Client) FileExistsInGuest(ctx context.Context, app, vmx string, username, password string, filename string) (bool, error) {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "fileExistsInGuest", vmx, filename)...); err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
 	}
 
-	return true
+	return true, nil
 }
 
 // DirectoryExistsInGuest check if a directory exists in Guest OS.
 func DirectoryExistsInGuest(app, vmx string, username, password string, dir string) bool {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "directoryExistsInGuest", vmx, dir); err != nil {
-		return false
+	exist, _ := DirectoryExistsInGuestContext(context.Background(), app, vmx, username, password, dir)
+	return exist
+}
+
+// DirectoryExistsInGuestContext is like DirectoryExistsInGuest but includes a context.
+//
+// Unlike DirectoryExistsInGuest, it reports the error that is not the directory does not exist.
+func DirectoryExistsInGuestContext(ctx context.Context, app, vmx string, username, password string, dir string) (bool, error) {
+	return DefaultClient.DirectoryExistsInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// DirectoryExistsInGuest check if a directory exists in Guest OS.
+func (c *Client) DirectoryExistsInGuest(ctx context.Context, app, vmx string, username, password string, dir string) (bool, error) {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "directoryExistsInGuest", vmx, dir)...); err != nil {
+		if isNotExist(err) {
+			return false, nil
+		}
+		return false, err
 	}
 
-	return true
+	return true, nil
+}
+
+// isNotExist reports whether the err is the "does not exist" result of fileExistsInGuest or directoryExistsInGuest command.
+func isNotExist(err error) bool {
+	return errors.Is(err, ErrFileNotFound)
 }
 
 // SetSharedFolderState modify a Host-Guest shared folder.
 func SetSharedFolderState(app, vmx string, shareName, hostPath string, writable bool) error {
+	return SetSharedFolderStateContext(context.Background(), app, vmx, shareName, hostPath, writable)
+}
+
+// SetSharedFolderStateContext is like SetSharedFolderState but includes a context.
+func SetSharedFolderStateContext(ctx context.Context, app, vmx string, shareName, hostPath string, writable bool) error {
+	return DefaultClient.SetSharedFolderState(ctx, app, vmx, shareName, hostPath, writable)
+}
+
+// SetSharedFolderState modify a Host-Guest shared folder.
+func (c *Client) SetSharedFolderState(ctx context.Context, app, vmx string, shareName, hostPath string, writable bool) error {
 	flag := "readonly"
 	if writable {
 		flag = "writable"
 	}
-	if _, err := vmrun(app, "setSharedFolderState", vmx, shareName, hostPath, flag); err != nil {
+	if _, err := c.vmrun(ctx, classGuest, app, "setSharedFolderState", vmx, shareName, hostPath, flag); err != nil {
 		return err
 	}
 
@@ -373,7 +513,17 @@ func SetSharedFolderState(app, vmx string, shareName, hostPath string, writable
 
 // AddSharedFolder add a Host-Guest shared folder.
 func AddSharedFolder(app, vmx string, shareName, newHostPath string) error {
-	if _, err := vmrun(app, "addSharedFolder", vmx, shareName, newHostPath); err != nil {
+	return AddSharedFolderContext(context.Background(), app, vmx, shareName, newHostPath)
+}
+
+// AddSharedFolderContext is like AddSharedFolder but includes a context.
+func AddSharedFolderContext(ctx context.Context, app, vmx string, shareName, newHostPath string) error {
+	return DefaultClient.AddSharedFolder(ctx, app, vmx, shareName, newHostPath)
+}
+
+// AddSharedFolder add a Host-Guest shared folder.
+func (c *Client) AddSharedFolder(ctx context.Context, app, vmx string, shareName, newHostPath string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, "addSharedFolder", vmx, shareName, newHostPath); err != nil {
 		return err
 	}
 
@@ -382,7 +532,17 @@ func AddSharedFolder(app, vmx string, shareName, newHostPath string) error {
 
 // RemoveSharedFolder remove a Host-Guest shared folder.
 func RemoveSharedFolder(app, vmx, shareName string) error {
-	if _, err := vmrun(app, "removeSharedFolder", vmx, shareName); err != nil {
+	return RemoveSharedFolderContext(context.Background(), app, vmx, shareName)
+}
+
+// RemoveSharedFolderContext is like RemoveSharedFolder but includes a context.
+func RemoveSharedFolderContext(ctx context.Context, app, vmx, shareName string) error {
+	return DefaultClient.RemoveSharedFolder(ctx, app, vmx, shareName)
+}
+
+// RemoveSharedFolder remove a Host-Guest shared folder.
+func (c *Client) RemoveSharedFolder(ctx context.Context, app, vmx, shareName string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, "removeSharedFolder", vmx, shareName); err != nil {
 		return err
 	}
 
@@ -394,12 +554,25 @@ func RemoveSharedFolder(app, vmx, shareName string) error {
 // The optional runtime argument means to share folders only until the virtual machine is powered off.
 // Otherwise, the setting persists at next power on.
 func EnableSharedFolders(app, vmx string, runtime bool) error {
+	return EnableSharedFoldersContext(context.Background(), app, vmx, runtime)
+}
+
+// EnableSharedFoldersContext is like EnableSharedFolders but includes a context.
+func EnableSharedFoldersContext(ctx context.Context, app, vmx string, runtime bool) error {
+	return DefaultClient.EnableSharedFolders(ctx, app, vmx, runtime)
+}
+
+// EnableSharedFolders enable shared folders in Guest.
+//
+// The optional runtime argument means to share folders only until the virtual machine is powered off.
+// Otherwise, the setting persists at next power on.
+func (c *Client) EnableSharedFolders(ctx context.Context, app, vmx string, runtime bool) error {
 	args := []string{"enableSharedFolders", vmx}
 	if runtime {
 		args = append(args, "runtime")
 	}
 
-	if _, err := vmrun(app, args...); err != nil {
+	if _, err := c.vmrun(ctx, classGuest, app, args...); err != nil {
 		return err
 	}
 
@@ -412,12 +585,26 @@ func EnableSharedFolders(app, vmx string, runtime bool) error {
 // The optional runtime argument means to stop sharing folders only until the virtual machine is powered off.
 // Otherwise, the setting persists at next power on.
 func DisableSharedFolders(app, vmx string, runtime bool) error {
+	return DisableSharedFoldersContext(context.Background(), app, vmx, runtime)
+}
+
+// DisableSharedFoldersContext is like DisableSharedFolders but includes a context.
+func DisableSharedFoldersContext(ctx context.Context, app, vmx string, runtime bool) error {
+	return DefaultClient.DisableSharedFolders(ctx, app, vmx, runtime)
+}
+
+// DisableSharedFolders disable shared folders in Guest.
+// Stops the guest virtual machine, specified by .vmx file, from sharing folders with its host.
+//
+// The optional runtime argument means to stop sharing folders only until the virtual machine is powered off.
+// Otherwise, the setting persists at next power on.
+func (c *Client) DisableSharedFolders(ctx context.Context, app, vmx string, runtime bool) error {
 	args := []string{"disableSharedFolders", vmx}
 	if runtime {
 		args = append(args, "runtime")
 	}
 
-	if _, err := vmrun(app, args...); err != nil {
+	if _, err := c.vmrun(ctx, classGuest, app, args...); err != nil {
 		return err
 	}
 
@@ -431,11 +618,22 @@ type ListProcessesInGuestInfo struct {
 	Cmd   string
 }
 
-var listProcessesInGuestRe = regexp.MustCompile(`pid=(\d+), owner=(\w+), cmd=([[:print:]]+)`)
+// listProcessesInGuestRe matches a process line, which owner may contain spaces and backslashes such as `NT AUTHORITY\SYSTEM`.
+var listProcessesInGuestRe = regexp.MustCompile(`pid=(\d+), owner=(.*?), cmd=([[:print:]]+)`)
 
 // ListProcessesInGuest List running processes in Guest OS.
 func ListProcessesInGuest(app, vmx, username, password string) ([]ListProcessesInGuestInfo, error) {
-	stdout, err := vmrun(app, "-gu", username, "-gp", password, "listprocessesinguest", vmx)
+	return ListProcessesInGuestContext(context.Background(), app, vmx, username, password)
+}
+
+// ListProcessesInGuestContext is like ListProcessesInGuest but includes a context.
+func ListProcessesInGuestContext(ctx context.Context, app, vmx, username, password string) ([]ListProcessesInGuestInfo, error) {
+	return DefaultClient.ListProcessesInGuest(ctx, app, vmx, username, password)
+}
+
+// ListProcessesInGuest List running processes in Guest OS.
+func (c *Client) ListProcessesInGuest(ctx context.Context, app, vmx, username, password string) ([]ListProcessesInGuestInfo, error) {
+	stdout, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "listprocessesinguest", vmx)...)
 	if err != nil {
 		return nil, err
 	}
@@ -456,7 +654,17 @@ func ListProcessesInGuest(app, vmx, username, password string) ([]ListProcessesI
 
 // KillProcessInGuest kill a process in Guest OS.
 func KillProcessInGuest(app, vmx, username, password string, pid int) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "killprocessinguest", vmx, strconv.Itoa(pid)); err != nil {
+	return KillProcessInGuestContext(context.Background(), app, vmx, username, password, pid)
+}
+
+// KillProcessInGuestContext is like KillProcessInGuest but includes a context.
+func KillProcessInGuestContext(ctx context.Context, app, vmx, username, password string, pid int) error {
+	return DefaultClient.KillProcessInGuest(ctx, app, vmx, username, password, pid)
+}
+
+// KillProcessInGuest kill a process in Guest OS.
+func (c *Client) KillProcessInGuest(ctx context.Context, app, vmx, username, password string, pid int) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "killprocessinguest", vmx, strconv.Itoa(pid))...); err != nil {
 		return err
 	}
 
@@ -465,7 +673,17 @@ func KillProcessInGuest(app, vmx, username, password string, pid int) error {
 
 // RunScriptInGuest run a script in Guest OS.
 func RunScriptInGuest(app, vmx, username, password string, config RunInGuestConfig, interpreter, script string) error {
-	args := []string{"-gu", username, "-gp", password, "runScriptInGuest", vmx}
+	return RunScriptInGuestContext(context.Background(), app, vmx, username, password, config, interpreter, script)
+}
+
+// RunScriptInGuestContext is like RunScriptInGuest but includes a context.
+func RunScriptInGuestContext(ctx context.Context, app, vmx, username, password string, config RunInGuestConfig, interpreter, script string) error {
+	return DefaultClient.RunScriptInGuest(ctx, app, vmx, username, password, config, interpreter, script)
+}
+
+// RunScriptInGuest run a script in Guest OS.
+func (c *Client) RunScriptInGuest(ctx context.Context, app, vmx, username, password string, config RunInGuestConfig, interpreter, script string) error {
+	args := c.guestArgs(username, password, "runScriptInGuest", vmx)
 
 	if config&NoWait > 0 {
 		args = append(args, NoWait.String())
@@ -479,7 +697,7 @@ func RunScriptInGuest(app, vmx, username, password string, config RunInGuestConf
 
 	args = append(args, interpreter, script)
 
-	if _, err := vmrun(app, args...); err != nil {
+	if _, err := c.vmrun(ctx, classGuest, app, args...); err != nil {
 		return err
 	}
 
@@ -488,40 +706,267 @@ func RunScriptInGuest(app, vmx, username, password string, config RunInGuestConf
 
 // DeleteFileInGuest delete a file in Guest OS.
 func DeleteFileInGuest(app, vmx, username, password, filename string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "deleteFileInGuest", vmx, filename); err != nil {
+	return DeleteFileInGuestContext(context.Background(), app, vmx, username, password, filename)
+}
+
+// DeleteFileInGuestContext is like DeleteFileInGuest but includes a context.
+func DeleteFileInGuestContext(ctx context.Context, app, vmx, username, password, filename string) error {
+	return DefaultClient.DeleteFileInGuest(ctx, app, vmx, username, password, filename)
+}
+
+// DeleteFileInGuest delete a file in Guest OS.
+func (c *Client) DeleteFileInGuest(ctx context.Context, app, vmx, username, password, filename string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "deleteFileInGuest", vmx, filename)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateDirectoryInGuest create a directory in Guest OS.
+func CreateDirectoryInGuest(app, vmx, username, password, dir string) error {
+	return CreateDirectoryInGuestContext(context.Background(), app, vmx, username, password, dir)
+}
+
+// CreateDirectoryInGuestContext is like CreateDirectoryInGuest but includes a context.
+func CreateDirectoryInGuestContext(ctx context.Context, app, vmx, username, password, dir string) error {
+	return DefaultClient.CreateDirectoryInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// CreateDirectoryInGuest create a directory in Guest OS.
+func (c *Client) CreateDirectoryInGuest(ctx context.Context, app, vmx, username, password, dir string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "createDirectoryInGuest", vmx, dir)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteDirectoryInGuest delete a directory in Guest OS.
+//
+// The files and subdirectories in the dir are also deleted.
+func DeleteDirectoryInGuest(app, vmx, username, password, dir string) error {
+	return DeleteDirectoryInGuestContext(context.Background(), app, vmx, username, password, dir)
+}
+
+// DeleteDirectoryInGuestContext is like DeleteDirectoryInGuest but includes a context.
+func DeleteDirectoryInGuestContext(ctx context.Context, app, vmx, username, password, dir string) error {
+	return DefaultClient.DeleteDirectoryInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// DeleteDirectoryInGuest delete a directory in Guest OS.
+//
+// The files and subdirectories in the dir are also deleted.
+func (c *Client) DeleteDirectoryInGuest(ctx context.Context, app, vmx, username, password, dir string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "deleteDirectoryInGuest", vmx, dir)...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// MkdirAllInGuest create a directory in Guest OS, along with any necessary parents like "mkdir -p".
+//
+// It returns nil if dir is already a directory.
+func MkdirAllInGuest(app, vmx, username, password, dir string) error {
+	return MkdirAllInGuestContext(context.Background(), app, vmx, username, password, dir)
+}
+
+// MkdirAllInGuestContext is like MkdirAllInGuest but includes a context.
+func MkdirAllInGuestContext(ctx context.Context, app, vmx, username, password, dir string) error {
+	return DefaultClient.MkdirAllInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// MkdirAllInGuest create a directory in Guest OS, along with any necessary parents like "mkdir -p".
+//
+// It returns nil if dir is already a directory.
+func (c *Client) MkdirAllInGuest(ctx context.Context, app, vmx, username, password, dir string) error {
+	prefixes := guestPathPrefixes(dir)
+
+	// find the deepest existing directory, most of parents are usually exist
+	i := len(prefixes)
+	for ; i > 0; i-- {
+		exist, err := c.DirectoryExistsInGuest(ctx, app, vmx, username, password, prefixes[i-1])
+		if err != nil {
+			return err
+		}
+		if exist {
+			break
+		}
+	}
+
+	for _, p := range prefixes[i:] {
+		if err := c.CreateDirectoryInGuest(ctx, app, vmx, username, password, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveAllInGuest remove a file or a directory and any children it contains in Guest OS like "rm -rf".
+//
+// It returns nil if the path does not exist.
+func RemoveAllInGuest(app, vmx, username, password, path string) error {
+	return RemoveAllInGuestContext(context.Background(), app, vmx, username, password, path)
+}
+
+// RemoveAllInGuestContext is like RemoveAllInGuest but includes a context.
+func RemoveAllInGuestContext(ctx context.Context, app, vmx, username, password, path string) error {
+	return DefaultClient.RemoveAllInGuest(ctx, app, vmx, username, password, path)
+}
+
+// RemoveAllInGuest remove a file or a directory and any children it contains in Guest OS like "rm -rf".
+//
+// It returns nil if the path does not exist.
+func (c *Client) RemoveAllInGuest(ctx context.Context, app, vmx, username, password, path string) error {
+	isDir, err := c.DirectoryExistsInGuest(ctx, app, vmx, username, password, path)
+	if err != nil {
+		return err
+	}
+	if !isDir {
+		err := c.DeleteFileInGuest(ctx, app, vmx, username, password, path)
+		if isNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	err = c.DeleteDirectoryInGuest(ctx, app, vmx, username, password, path)
+	if !errors.Is(err, ErrDirectoryNotEmpty) {
+		return err
+	}
+
+	// some guests refuse to delete the non-empty directory, remove the children first
+	names, err := c.ListDirectoryInGuest(ctx, app, vmx, username, password, path)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := c.RemoveAllInGuest(ctx, app, vmx, username, password, GuestJoin(path, name)); err != nil {
+			return err
+		}
+	}
+
+	return c.DeleteDirectoryInGuest(ctx, app, vmx, username, password, path)
+}
+
 // CreateTempfileInGuest create a temporary file in Guest OS.
 func CreateTempfileInGuest(app, vmx, username, password string) (string, error) {
-	stdout, err := vmrun(app, "-gu", username, "-gp", password, "CreateTempfileInGuest", vmx)
+	return CreateTempfileInGuestContext(context.Background(), app, vmx, username, password)
+}
+
+// CreateTempfileInGuestContext is like CreateTempfileInGuest but includes a context.
+func CreateTempfileInGuestContext(ctx context.Context, app, vmx, username, password string) (string, error) {
+	return DefaultClient.CreateTempfileInGuest(ctx, app, vmx, username, password)
+}
+
+// CreateTempfileInGuest create a temporary file in Guest OS.
+func (c *Client) CreateTempfileInGuest(ctx context.Context, app, vmx, username, password string) (string, error) {
+	stdout, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "CreateTempfileInGuest", vmx)...)
 	if err != nil {
 		return "", err
 	}
 
-	return filepath.Clean(stdout), nil
+	return strings.TrimSpace(stdout), nil
 }
 
-var listDirectoryInGuestRe = regexp.MustCompile(`[^Directory list: \d](\w+)`)
-
 // ListDirectoryInGuest list a directory in Guest OS.
 func ListDirectoryInGuest(app, vmx, username, password, dir string) ([]string, error) {
-	stdout, err := vmrun(app, "-gu", username, "-gp", password, "listDirectoryInGuest", vmx, dir)
+	return ListDirectoryInGuestContext(context.Background(), app, vmx, username, password, dir)
+}
+
+// ListDirectoryInGuestContext is like ListDirectoryInGuest but includes a context.
+func ListDirectoryInGuestContext(ctx context.Context, app, vmx, username, password, dir string) ([]string, error) {
+	return DefaultClient.ListDirectoryInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// ListDirectoryInGuest list a directory in Guest OS.
+func (c *Client) ListDirectoryInGuest(ctx context.Context, app, vmx, username, password, dir string) ([]string, error) {
+	stdout, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "listDirectoryInGuest", vmx, dir)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseListDirectory(stdout), nil
+}
+
+// parseListDirectory parses the listDirectoryInGuest command output such as:
+//
+//	Directory list: 3
+//	hosts.d
+//	my file.txt
+//	.profile
+//
+// The entry names are returned verbatim, because they may have leading or trailing spaces.
+func parseListDirectory(s string) []string {
+	var names []string
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" || (i == 0 && strings.HasPrefix(line, "Directory list:")) {
+			continue
+		}
+		names = append(names, line)
+	}
+
+	return names
+}
+
+// GuestDirEntry represents an entry of a directory in Guest OS.
+type GuestDirEntry struct {
+	// Name is the base name of the entry.
+	Name string
+	// IsDir reports whether the entry is a directory.
+	IsDir bool
+}
+
+// ReadDirInGuest list a directory in Guest OS, and returns its entries sorted by name.
+//
+// Each entry is probed with the directoryExistsInGuest command to distinguish files from directories.
+func ReadDirInGuest(app, vmx, username, password, dir string) ([]GuestDirEntry, error) {
+	return ReadDirInGuestContext(context.Background(), app, vmx, username, password, dir)
+}
+
+// ReadDirInGuestContext is like ReadDirInGuest but includes a context.
+func ReadDirInGuestContext(ctx context.Context, app, vmx, username, password, dir string) ([]GuestDirEntry, error) {
+	return DefaultClient.ReadDirInGuest(ctx, app, vmx, username, password, dir)
+}
+
+// ReadDirInGuest list a directory in Guest OS, and returns its entries sorted by name.
+//
+// Each entry is probed with the directoryExistsInGuest command to distinguish files from directories.
+func (c *Client) ReadDirInGuest(ctx context.Context, app, vmx, username, password, dir string) ([]GuestDirEntry, error) {
+	names, err := c.ListDirectoryInGuest(ctx, app, vmx, username, password, dir)
 	if err != nil {
 		return nil, err
 	}
+	sort.Strings(names)
 
-	files := listDirectoryInGuestRe.FindAllString(stdout, -1)
+	entries := make([]GuestDirEntry, 0, len(names))
+	for _, name := range names {
+		isDir, err := c.DirectoryExistsInGuest(ctx, app, vmx, username, password, GuestJoin(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, GuestDirEntry{Name: name, IsDir: isDir})
+	}
 
-	return files, nil
+	return entries, nil
 }
 
 // CopyFileFromHostToGuest copy a file from host OS to guest OS.
 func CopyFileFromHostToGuest(app, vmx, username, password, hostFilepath, guestFilepath string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "CopyFileFromHostToGuest", vmx, hostFilepath, guestFilepath); err != nil {
+	return CopyFileFromHostToGuestContext(context.Background(), app, vmx, username, password, hostFilepath, guestFilepath)
+}
+
+// CopyFileFromHostToGuestContext is like CopyFileFromHostToGuest but includes a context.
+func CopyFileFromHostToGuestContext(ctx context.Context, app, vmx, username, password, hostFilepath, guestFilepath string) error {
+	return DefaultClient.CopyFileFromHostToGuest(ctx, app, vmx, username, password, hostFilepath, guestFilepath)
+}
+
+// CopyFileFromHostToGuest copy a file from host OS to guest OS.
+func (c *Client) CopyFileFromHostToGuest(ctx context.Context, app, vmx, username, password, hostFilepath, guestFilepath string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "CopyFileFromHostToGuest", vmx, hostFilepath, guestFilepath)...); err != nil {
 		return err
 	}
 
@@ -530,7 +975,17 @@ func CopyFileFromHostToGuest(app, vmx, username, password, hostFilepath, guestFi
 
 // CopyFileFromGuestToHost copy a file from guest OS to host OS.
 func CopyFileFromGuestToHost(app, vmx, username, password, guestFilepath, hostFilepath string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "CopyFileFromGuestToHost", vmx, guestFilepath, hostFilepath); err != nil {
+	return CopyFileFromGuestToHostContext(context.Background(), app, vmx, username, password, guestFilepath, hostFilepath)
+}
+
+// CopyFileFromGuestToHostContext is like CopyFileFromGuestToHost but includes a context.
+func CopyFileFromGuestToHostContext(ctx context.Context, app, vmx, username, password, guestFilepath, hostFilepath string) error {
+	return DefaultClient.CopyFileFromGuestToHost(ctx, app, vmx, username, password, guestFilepath, hostFilepath)
+}
+
+// CopyFileFromGuestToHost copy a file from guest OS to host OS.
+func (c *Client) CopyFileFromGuestToHost(ctx context.Context, app, vmx, username, password, guestFilepath, hostFilepath string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "CopyFileFromGuestToHost", vmx, guestFilepath, hostFilepath)...); err != nil {
 		return err
 	}
 
@@ -539,7 +994,17 @@ func CopyFileFromGuestToHost(app, vmx, username, password, guestFilepath, hostFi
 
 // RenameFileInGuest rename a file in Guest OS.
 func RenameFileInGuest(app, vmx, username, password, src, dst string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "renameFileInGuest", vmx, src, dst); err != nil {
+	return RenameFileInGuestContext(context.Background(), app, vmx, username, password, src, dst)
+}
+
+// RenameFileInGuestContext is like RenameFileInGuest but includes a context.
+func RenameFileInGuestContext(ctx context.Context, app, vmx, username, password, src, dst string) error {
+	return DefaultClient.RenameFileInGuest(ctx, app, vmx, username, password, src, dst)
+}
+
+// RenameFileInGuest rename a file in Guest OS.
+func (c *Client) RenameFileInGuest(ctx context.Context, app, vmx, username, password, src, dst string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "renameFileInGuest", vmx, src, dst)...); err != nil {
 		return err
 	}
 
@@ -548,7 +1013,17 @@ func RenameFileInGuest(app, vmx, username, password, src, dst string) error {
 
 // CaptureScreen capture the screen of the VM to a local file.
 func CaptureScreen(app, vmx, username, password, dst string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "captureScreen", vmx, dst); err != nil {
+	return CaptureScreenContext(context.Background(), app, vmx, username, password, dst)
+}
+
+// CaptureScreenContext is like CaptureScreen but includes a context.
+func CaptureScreenContext(ctx context.Context, app, vmx, username, password, dst string) error {
+	return DefaultClient.CaptureScreen(ctx, app, vmx, username, password, dst)
+}
+
+// CaptureScreen capture the screen of the VM to a local file.
+func (c *Client) CaptureScreen(ctx context.Context, app, vmx, username, password, dst string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "captureScreen", vmx, dst)...); err != nil {
 		return err
 	}
 
@@ -583,7 +1058,17 @@ func (v VariableMode) String() string {
 
 // WriteVariable write a variable in the VM state.
 func WriteVariable(app, vmx, username, password string, mode VariableMode, env, value string) error {
-	if _, err := vmrun(app, "-gu", username, "-gp", password, "writeVariable", vmx, mode.String(), env, value); err != nil {
+	return WriteVariableContext(context.Background(), app, vmx, username, password, mode, env, value)
+}
+
+// WriteVariableContext is like WriteVariable but includes a context.
+func WriteVariableContext(ctx context.Context, app, vmx, username, password string, mode VariableMode, env, value string) error {
+	return DefaultClient.WriteVariable(ctx, app, vmx, username, password, mode, env, value)
+}
+
+// WriteVariable write a variable in the VM state.
+func (c *Client) WriteVariable(ctx context.Context, app, vmx, username, password string, mode VariableMode, env, value string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "writeVariable", vmx, mode.String(), env, value)...); err != nil {
 		return err
 	}
 
@@ -592,7 +1077,17 @@ func WriteVariable(app, vmx, username, password string, mode VariableMode, env,
 
 // ReadVariable read a variable in the VM state.
 func ReadVariable(app, vmx, username, password string, mode VariableMode, env string) (string, error) {
-	stdout, err := vmrun(app, "-gu", username, "-gp", password, "readVariable", vmx, mode.String(), env)
+	return ReadVariableContext(context.Background(), app, vmx, username, password, mode, env)
+}
+
+// ReadVariableContext is like ReadVariable but includes a context.
+func ReadVariableContext(ctx context.Context, app, vmx, username, password string, mode VariableMode, env string) (string, error) {
+	return DefaultClient.ReadVariable(ctx, app, vmx, username, password, mode, env)
+}
+
+// ReadVariable read a variable in the VM state.
+func (c *Client) ReadVariable(ctx context.Context, app, vmx, username, password string, mode VariableMode, env string) (string, error) {
+	stdout, err := c.vmrun(ctx, classGuest, app, c.guestArgs(username, password, "readVariable", vmx, mode.String(), env)...)
 	if err != nil {
 		return "", err
 	}
@@ -602,15 +1097,245 @@ func ReadVariable(app, vmx, username, password string, mode VariableMode, env st
 
 // GetGuestIPAddress gets the IP address of the guest.
 func GetGuestIPAddress(app, vmx string, wait bool) (string, error) {
+	return GetGuestIPAddressContext(context.Background(), app, vmx, wait)
+}
+
+// GetGuestIPAddressContext is like GetGuestIPAddress but includes a context.
+//
+// The wait of true blocks until the guest reports an IP address, so the caller should pass ctx with a deadline.
+func GetGuestIPAddressContext(ctx context.Context, app, vmx string, wait bool) (string, error) {
+	return DefaultClient.GetGuestIPAddress(ctx, app, vmx, wait)
+}
+
+// GetGuestIPAddress gets the IP address of the guest.
+func (c *Client) GetGuestIPAddress(ctx context.Context, app, vmx string, wait bool) (string, error) {
 	args := []string{"getGuestIPAddress", vmx}
 	if wait {
 		args = append(args, "-wait")
 	}
 
-	stdout, err := vmrun(app, args...)
+	stdout, err := c.vmrun(ctx, classGuest, app, args...)
 	if err != nil {
 		return "", err
 	}
 
 	return stdout, nil
 }
+
+// GENERAL COMMANDS         PARAMETERS           DESCRIPTION
+// ----------------         ----------           -----------
+// list                                          List all running VMs
+//
+// upgradevm                Path to vmx file     Upgrade VM file format, virtual hw
+//
+// installTools             Path to vmx file     Install Tools in Guest
+//
+// checkToolsState          Path to vmx file     Check the current Tools state
+//
+// deleteVM                 Path to vmx file     Delete a VM
+//
+// clone                    Path to vmx file     Create a copy of the VM
+//                          Path to destination vmx file
+//                          full|linked
+//                          [-snapshot=Snapshot Name]
+//                          [-cloneName=Name]
+
+// List list all running VMs, return the .vmx file paths of them.
+func List(app string) ([]string, error) {
+	return ListContext(context.Background(), app)
+}
+
+// ListContext is like List but includes a context.
+func ListContext(ctx context.Context, app string) ([]string, error) {
+	return DefaultClient.List(ctx, app)
+}
+
+// List list all running VMs, return the .vmx file paths of them.
+func (c *Client) List(ctx context.Context, app string) ([]string, error) {
+	stdout, err := c.vmrun(ctx, classGeneral, app, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseList(stdout), nil
+}
+
+// parseList parses the list command output such as:
+//
+//	Total running VMs: 2
+//	/path/to/a.vmx
+//	/path/to/b.vmx
+func parseList(s string) []string {
+	var vmxs []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "Total running VMs:") {
+			continue
+		}
+		vmxs = append(vmxs, line)
+	}
+
+	return vmxs
+}
+
+// UpgradeVM upgrade VM file format, virtual hw.
+func UpgradeVM(app, vmx string) error {
+	return UpgradeVMContext(context.Background(), app, vmx)
+}
+
+// UpgradeVMContext is like UpgradeVM but includes a context.
+func UpgradeVMContext(ctx context.Context, app, vmx string) error {
+	return DefaultClient.UpgradeVM(ctx, app, vmx)
+}
+
+// UpgradeVM upgrade VM file format, virtual hw.
+func (c *Client) UpgradeVM(ctx context.Context, app, vmx string) error {
+	if _, err := c.vmrun(ctx, classGeneral, app, "upgradevm", vmx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// InstallTools install Tools in Guest.
+func InstallTools(app, vmx string) error {
+	return InstallToolsContext(context.Background(), app, vmx)
+}
+
+// InstallToolsContext is like InstallTools but includes a context.
+func InstallToolsContext(ctx context.Context, app, vmx string) error {
+	return DefaultClient.InstallTools(ctx, app, vmx)
+}
+
+// InstallTools install Tools in Guest.
+func (c *Client) InstallTools(ctx context.Context, app, vmx string) error {
+	if _, err := c.vmrun(ctx, classGuest, app, "installTools", vmx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ToolsState represents a state of the VMware Tools in Guest.
+type ToolsState int
+
+const (
+	// ToolsUnknown is the VMware Tools are not installed, or the state is unknown.
+	ToolsUnknown ToolsState = iota
+	// ToolsInstalled is the VMware Tools are installed but not running.
+	ToolsInstalled
+	// ToolsRunning is the VMware Tools are running.
+	ToolsRunning
+)
+
+// String implements a fmt.Stringer interface.
+func (t ToolsState) String() string {
+	switch t {
+	case ToolsInstalled:
+		return "installed"
+	case ToolsRunning:
+		return "running"
+	default:
+		return "unknown"
+	}
+}
+
+// CheckToolsState check the current Tools state.
+func CheckToolsState(app, vmx string) (ToolsState, error) {
+	return CheckToolsStateContext(context.Background(), app, vmx)
+}
+
+// CheckToolsStateContext is like CheckToolsState but includes a context.
+func CheckToolsStateContext(ctx context.Context, app, vmx string) (ToolsState, error) {
+	return DefaultClient.CheckToolsState(ctx, app, vmx)
+}
+
+// CheckToolsState check the current Tools state.
+func (c *Client) CheckToolsState(ctx context.Context, app, vmx string) (ToolsState, error) {
+	stdout, err := c.vmrun(ctx, classGeneral, app, "checkToolsState", vmx)
+	if err != nil {
+		return ToolsUnknown, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(stdout)) {
+	case "installed":
+		return ToolsInstalled, nil
+	case "running":
+		return ToolsRunning, nil
+	default:
+		return ToolsUnknown, nil
+	}
+}
+
+// DeleteVM delete a VM.
+func DeleteVM(app, vmx string) error {
+	return DeleteVMContext(context.Background(), app, vmx)
+}
+
+// DeleteVMContext is like DeleteVM but includes a context.
+func DeleteVMContext(ctx context.Context, app, vmx string) error {
+	return DefaultClient.DeleteVM(ctx, app, vmx)
+}
+
+// DeleteVM delete a VM.
+func (c *Client) DeleteVM(ctx context.Context, app, vmx string) error {
+	if _, err := c.vmrun(ctx, classGeneral, app, "deleteVM", vmx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CloneOptions represents a clone command options.
+type CloneOptions struct {
+	// Linked creates a linked clone instead of a full clone.
+	Linked bool
+	// Snapshot is the snapshot name to clone from. If empty, the current state is cloned.
+	Snapshot string
+	// Name is the display name of the clone.
+	Name string
+}
+
+// args returns the clone command arguments of o.
+func (o *CloneOptions) args() []string {
+	if o == nil {
+		return []string{"full"}
+	}
+
+	args := []string{"full"}
+	if o.Linked {
+		args[0] = "linked"
+	}
+	if o.Snapshot != "" {
+		args = append(args, "-snapshot="+o.Snapshot)
+	}
+	if o.Name != "" {
+		args = append(args, "-cloneName="+o.Name)
+	}
+
+	return args
+}
+
+// Clone create a copy of the VM to the dst .vmx file path.
+//
+// The nil opts creates a full clone of the current state.
+func Clone(app, vmx, dst string, opts *CloneOptions) error {
+	return CloneContext(context.Background(), app, vmx, dst, opts)
+}
+
+// CloneContext is like Clone but includes a context.
+func CloneContext(ctx context.Context, app, vmx, dst string, opts *CloneOptions) error {
+	return DefaultClient.Clone(ctx, app, vmx, dst, opts)
+}
+
+// Clone create a copy of the VM to the dst .vmx file path.
+//
+// The nil opts creates a full clone of the current state.
+func (c *Client) Clone(ctx context.Context, app, vmx, dst string, opts *CloneOptions) error {
+	args := append([]string{"clone", vmx, dst}, opts.args()...)
+	if _, err := c.vmrun(ctx, classSnapshot, app, args...); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,402 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmrun
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-vm/vmware/runner"
+	"github.com/go-vm/vmware/runner/runnertest"
+)
+
+const testVMX = "/vm/test.vmx"
+
+func newTestClient(steps ...runnertest.Step) (*Client, *runnertest.Fake) {
+	fake := runnertest.NewFake(steps...)
+	return &Client{Path: "vmrun", Runner: fake}, fake
+}
+
+func TestClientArgs(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		run      func(c *Client) error
+		wantArgs []string
+	}{
+		{
+			name:     "start nogui",
+			run:      func(c *Client) error { return c.Start(ctx, "fusion", testVMX, false) },
+			wantArgs: []string{"-T", "fusion", "start", testVMX, "nogui"},
+		},
+		{
+			name:     "stop hard",
+			run:      func(c *Client) error { return c.Stop(ctx, "ws", testVMX, true) },
+			wantArgs: []string{"-T", "ws", "stop", testVMX, "hard"},
+		},
+		{
+			name:     "delete snapshot with children",
+			run:      func(c *Client) error { return c.DeleteSnapshot(ctx, "fusion", testVMX, "base", true) },
+			wantArgs: []string{"-T", "fusion", "deleteSnapshot", testVMX, "base", "andDeleteChildren"},
+		},
+		{
+			name: "run program in guest",
+			run: func(c *Client) error {
+				return c.RunProgramInGuest(ctx, "fusion", testVMX, "user", "pass", NoWait|Interactive, "/bin/ls", "-l")
+			},
+			wantArgs: []string{"-T", "fusion", "-gu", "user", "-gp", "pass", "runProgramInGuest", testVMX, "-noWait", "-interactive", "/bin/ls", "-l"},
+		},
+		{
+			name:     "get guest ip address with wait",
+			run:      func(c *Client) error { _, err := c.GetGuestIPAddress(ctx, "fusion", testVMX, true); return err },
+			wantArgs: []string{"-T", "fusion", "getGuestIPAddress", testVMX, "-wait"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(runnertest.Step{Args: tt.wantArgs})
+			if err := tt.run(c); err != nil {
+				t.Fatal(err)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestClientExitError(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{
+		Result: runner.Result{Stdout: "Error: The virtual machine is not powered on: /vm/test.vmx\n", ExitCode: 255},
+	})
+
+	if err := c.Pause(context.Background(), "fusion", testVMX); err == nil {
+		t.Fatal("Pause error = nil, want error")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{Block: true})
+	c.Timeouts = &Timeouts{Power: 10 * time.Millisecond}
+
+	if err := c.Start(context.Background(), "fusion", testVMX, false); err != context.DeadlineExceeded {
+		t.Fatalf("Start error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{Block: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetGuestIPAddress(ctx, "fusion", testVMX, true); err != context.Canceled {
+		t.Fatalf("GetGuestIPAddress error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestClientFileExistsInGuest(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  runner.Result
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "exist",
+			result: runner.Result{Stdout: "The file exists.\n"},
+			want:   true,
+		},
+		{
+			name:   "not exist",
+			result: runner.Result{Stdout: "The file does not exist.\n", ExitCode: 255},
+			want:   false,
+		},
+		{
+			name:    "tools not running",
+			result:  runner.Result{Stdout: "Error: The VMware Tools are not running in the virtual machine: /vm/test.vmx\n", ExitCode: 255},
+			want:    false,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(runnertest.Step{Result: tt.result})
+
+			got, err := c.FileExistsInGuest(context.Background(), "fusion", testVMX, "user", "pass", "/etc/hosts")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FileExistsInGuest error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("FileExistsInGuest = %v, want %v", got, tt.want)
+			}
+
+			wantArgs := []string{"-T", "fusion", "-gu", "user", "-gp", "pass", "fileExistsInGuest", testVMX, "/etc/hosts"}
+			if calls := fake.Calls(); !reflect.DeepEqual(calls[0].Args, wantArgs) {
+				t.Fatalf("args = %q, want %q", calls[0].Args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestClientList(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{
+		Args:   []string{"-T", "fusion", "list"},
+		Result: runner.Result{Stdout: "Total running VMs: 2\n/vm/a.vmx\n/Virtual Machines/b b.vmwarevm/b b.vmx\n"},
+	})
+
+	got, err := c.List(context.Background(), "fusion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/vm/a.vmx", "/Virtual Machines/b b.vmwarevm/b b.vmx"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("List = %q, want %q", got, want)
+	}
+}
+
+func TestClientListProcessesInGuest(t *testing.T) {
+	c, _ := newTestClient(runnertest.Step{
+		Args: []string{"-T", "ws", "-gu", "user", "-gp", "pass", "listprocessesinguest", testVMX},
+		Result: runner.Result{Stdout: "Process list: 3\n" +
+			"pid=4, owner=NT AUTHORITY\\SYSTEM, cmd=System\n" +
+			"pid=1204, owner=WIN10\\vagrant, cmd=\"C:\\Windows\\System32\\cmd.exe\" /c dir, cmd=x\n" +
+			"pid=1, owner=root, cmd=/sbin/init\n"},
+	})
+
+	got, err := c.ListProcessesInGuest(context.Background(), "ws", testVMX, "user", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ListProcessesInGuestInfo{
+		{Pid: "4", Owner: `NT AUTHORITY\SYSTEM`, Cmd: "System"},
+		{Pid: "1204", Owner: `WIN10\vagrant`, Cmd: `"C:\Windows\System32\cmd.exe" /c dir, cmd=x`},
+		{Pid: "1", Owner: "root", Cmd: "/sbin/init"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ListProcessesInGuest = %q, want %q", got, want)
+	}
+}
+
+func TestClientCheckToolsState(t *testing.T) {
+	tests := []struct {
+		stdout string
+		want   ToolsState
+	}{
+		{stdout: "running\n", want: ToolsRunning},
+		{stdout: "installed\n", want: ToolsInstalled},
+		{stdout: "unknown\n", want: ToolsUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			c, _ := newTestClient(runnertest.Step{
+				Args:   []string{"-T", "fusion", "checkToolsState", testVMX},
+				Result: runner.Result{Stdout: tt.stdout},
+			})
+
+			got, err := c.CheckToolsState(context.Background(), "fusion", testVMX)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("CheckToolsState = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientClone(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     *CloneOptions
+		wantArgs []string
+	}{
+		{
+			name:     "full",
+			opts:     nil,
+			wantArgs: []string{"-T", "fusion", "clone", testVMX, "/vm/clone.vmx", "full"},
+		},
+		{
+			name:     "linked",
+			opts:     &CloneOptions{Linked: true, Snapshot: "golden image", Name: "clone 1"},
+			wantArgs: []string{"-T", "fusion", "clone", testVMX, "/vm/clone.vmx", "linked", "-snapshot=golden image", "-cloneName=clone 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(runnertest.Step{Args: tt.wantArgs})
+			if err := c.Clone(context.Background(), "fusion", testVMX, "/vm/clone.vmx", tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestClientMkdirAllInGuest(t *testing.T) {
+	auth := []string{"-T", "fusion", "-gu", "user", "-gp", "pass"}
+	args := func(arg ...string) []string { return append(append([]string(nil), auth...), arg...) }
+	notExist := runner.Result{Stdout: "The directory does not exist.\n", ExitCode: 255}
+
+	c, fake := newTestClient(
+		runnertest.Step{Args: args("directoryExistsInGuest", testVMX, "/tmp/a/b"), Result: notExist},
+		runnertest.Step{Args: args("directoryExistsInGuest", testVMX, "/tmp/a"), Result: notExist},
+		runnertest.Step{Args: args("directoryExistsInGuest", testVMX, "/tmp"), Result: runner.Result{Stdout: "The directory exists.\n"}},
+		runnertest.Step{Args: args("createDirectoryInGuest", testVMX, "/tmp/a")},
+		runnertest.Step{Args: args("createDirectoryInGuest", testVMX, "/tmp/a/b")},
+	)
+
+	if err := c.MkdirAllInGuest(context.Background(), "fusion", testVMX, "user", "pass", "/tmp/a/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRemoveAllInGuest(t *testing.T) {
+	auth := []string{"-T", "fusion", "-gu", "user", "-gp", "pass"}
+	args := func(arg ...string) []string { return append(append([]string(nil), auth...), arg...) }
+
+	tests := []struct {
+		name  string
+		steps []runnertest.Step
+	}{
+		{
+			name: "not exist",
+			steps: []runnertest.Step{
+				{Args: args("directoryExistsInGuest", testVMX, "/tmp/a"), Result: runner.Result{Stdout: "The directory does not exist.\n", ExitCode: 255}},
+				{Args: args("deleteFileInGuest", testVMX, "/tmp/a"), Result: runner.Result{Stdout: "Error: A file was not found\n", ExitCode: 255}},
+			},
+		},
+		{
+			name: "directory",
+			steps: []runnertest.Step{
+				{Args: args("directoryExistsInGuest", testVMX, "/tmp/a"), Result: runner.Result{Stdout: "The directory exists.\n"}},
+				{Args: args("deleteDirectoryInGuest", testVMX, "/tmp/a")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(tt.steps...)
+			if err := c.RemoveAllInGuest(context.Background(), "fusion", testVMX, "user", "pass", "/tmp/a"); err != nil {
+				t.Fatal(err)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		auth     *Auth
+		run      func(c *Client) error
+		wantArgs []string
+	}{
+		{
+			name:     "encrypted vm",
+			auth:     &Auth{VMPassword: "secret"},
+			run:      func(c *Client) error { return c.Start(ctx, "fusion", testVMX, false) },
+			wantArgs: []string{"-T", "fusion", "-vp", "secret", "start", testVMX, "nogui"},
+		},
+		{
+			name: "remote host",
+			auth: &Auth{HostName: "https://esx.example.com/sdk", HostPort: 443, HostUser: "root", HostPassword: "hostpass"},
+			run:  func(c *Client) error { return c.Snapshot(ctx, "ws", testVMX, "base") },
+			wantArgs: []string{"-T", "ws", "-h", "https://esx.example.com/sdk", "-P", "443", "-u", "root", "-p", "hostpass",
+				"snapshot", testVMX, "base"},
+		},
+		{
+			name: "default guest credentials",
+			auth: &Auth{GuestUser: "user", GuestPassword: "pass", VMPassword: "secret"},
+			run:  func(c *Client) error { return c.DeleteFileInGuest(ctx, "fusion", testVMX, "", "", "/tmp/a") },
+			wantArgs: []string{"-T", "fusion", "-vp", "secret", "-gu", "user", "-gp", "pass",
+				"deleteFileInGuest", testVMX, "/tmp/a"},
+		},
+		{
+			name: "explicit guest credentials",
+			auth: &Auth{GuestUser: "user", GuestPassword: "pass"},
+			run:  func(c *Client) error { return c.DeleteFileInGuest(ctx, "fusion", testVMX, "root", "toor", "/tmp/a") },
+			wantArgs: []string{"-T", "fusion", "-gu", "root", "-gp", "toor",
+				"deleteFileInGuest", testVMX, "/tmp/a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, fake := newTestClient(runnertest.Step{Args: tt.wantArgs})
+			if err := tt.run(c.WithAuth(tt.auth)); err != nil {
+				t.Fatal(err)
+			}
+			if err := fake.Done(); err != nil {
+				t.Fatal(err)
+			}
+			if c.Auth != nil {
+				t.Fatalf("WithAuth modified the receiver Auth = %+v", c.Auth)
+			}
+		})
+	}
+}
+
+func TestParseListDirectory(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want []string
+	}{
+		{
+			name: "posix",
+			s:    "Directory list: 4\nhosts.d\nmy file.txt\n.profile\nfoo-bar_1\n",
+			want: []string{"hosts.d", "my file.txt", ".profile", "foo-bar_1"},
+		},
+		{
+			name: "windows",
+			s:    "Directory list: 2\r\nProgram Files (x86)\r\ndesktop.ini\r\n",
+			want: []string{"Program Files (x86)", "desktop.ini"},
+		},
+		{
+			name: "empty",
+			s:    "Directory list: 0\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseListDirectory(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseListDirectory(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientReadDirInGuest(t *testing.T) {
+	auth := []string{"-T", "fusion", "-gu", "user", "-gp", "pass"}
+	args := func(arg ...string) []string { return append(append([]string(nil), auth...), arg...) }
+
+	c, fake := newTestClient(
+		runnertest.Step{Args: args("listDirectoryInGuest", testVMX, "/etc"), Result: runner.Result{Stdout: "Directory list: 2\nhosts.d\nhosts\n"}},
+		runnertest.Step{Args: args("directoryExistsInGuest", testVMX, "/etc/hosts"), Result: runner.Result{Stdout: "The directory does not exist.\n", ExitCode: 255}},
+		runnertest.Step{Args: args("directoryExistsInGuest", testVMX, "/etc/hosts.d"), Result: runner.Result{Stdout: "The directory exists.\n"}},
+	)
+
+	got, err := c.ReadDirInGuest(context.Background(), "fusion", testVMX, "user", "pass", "/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []GuestDirEntry{{Name: "hosts"}, {Name: "hosts.d", IsDir: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ReadDirInGuest = %+v, want %+v", got, want)
+	}
+	if err := fake.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vmsd implements the reader and writer of the .vmsd snapshot metadata file of a VM.
+//
+// The .vmsd file is a list of "key = "value"" lines next to the .vmx file, such as:
+//
+//	.encoding = "UTF-8"
+//	snapshot.lastUID = "2"
+//	snapshot.current = "2"
+//	snapshot0.uid = "2"
+//	snapshot0.filename = "test-Snapshot2.vmsn"
+//	snapshot0.displayName = "base"
+//	snapshot0.createTimeHigh = "378745"
+//	snapshot0.createTimeLow = "-1412375611"
+//	snapshot0.numDisks = "1"
+//	snapshot0.disk0.fileName = "test.vmdk"
+//	snapshot0.disk0.node = "scsi0:0"
+//	snapshot.numSnapshots = "1"
+//
+// The file is read and written without the vmrun command, so it works for powered off VMs and without VMware installed.
+package vmsd
@@ -0,0 +1,285 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmsd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-vm/vmware/internal/configfile"
+)
+
+// ErrSnapshotNotFound is returned when the snapshot is not found in the .vmsd file.
+var ErrSnapshotNotFound = errors.New("vmsd: snapshot not found")
+
+// Path returns the .vmsd file path of the vmx file, which has the same base name.
+func Path(vmx string) string {
+	return strings.TrimSuffix(vmx, filepath.Ext(vmx)) + ".vmsd"
+}
+
+// File represents a .vmsd file.
+//
+// The unknown keys, comments, the order of lines and the line endings are kept, and only the modified entries
+// are re-encoded on write, so a File which is not modified is written back byte for byte.
+type File struct {
+	cf *configfile.File
+}
+
+// Parse parses the .vmsd file content from r.
+func Parse(r io.Reader) (*File, error) {
+	cf, err := configfile.Parse(r, "vmsd")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{cf: cf}, nil
+}
+
+// ReadFile reads and parses the name .vmsd file.
+func ReadFile(name string) (*File, error) {
+	cf, err := configfile.ReadFile(name, "vmsd")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{cf: cf}, nil
+}
+
+// WriteTo writes f to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return f.cf.WriteTo(w)
+}
+
+// WriteFile writes f to the name file atomically, by renaming the temporary file in the same directory.
+func (f *File) WriteFile(name string) error {
+	return f.cf.WriteFile(name)
+}
+
+// Get returns the value of the key, which is case insensitive.
+func (f *File) Get(key string) (string, bool) {
+	return f.cf.Get(key)
+}
+
+// Set sets the value of the key, appending it if the key does not exist.
+func (f *File) Set(key, value string) {
+	f.cf.Set(key, value)
+}
+
+// Delete deletes the key, including its duplicates.
+func (f *File) Delete(key string) {
+	f.cf.Delete(key)
+}
+
+// Keys returns the keys in the file order.
+func (f *File) Keys() []string {
+	return f.cf.Keys()
+}
+
+// getInt returns the integer value of the key, or 0 if it is missing or invalid.
+func (f *File) getInt(key string) int {
+	v, _ := f.Get(key)
+	n, _ := strconv.Atoi(v)
+
+	return n
+}
+
+// Disk represents a virtual disk recorded by a snapshot.
+type Disk struct {
+	// FileName is the .vmdk file name which the snapshot is based on, relative to the VM directory.
+	FileName string
+	// Node is the device node of the disk, such as "scsi0:0".
+	Node string
+}
+
+// Snapshot represents a snapshot recorded in the .vmsd file.
+type Snapshot struct {
+	// Index is the N of the "snapshotN." key prefix, which is not stable across the snapshot deletion.
+	Index int
+	// UID is the unique ID of the snapshot in the VM.
+	UID int
+	// Parent is the UID of the parent snapshot, or 0 if the snapshot is a root.
+	Parent int
+	// DisplayName is the snapshot name shown by vmrun.
+	DisplayName string
+	// Description is the snapshot description.
+	Description string
+	// CreateTime is the creation time of the snapshot.
+	CreateTime time.Time
+	// Filename is the .vmsn file name of the snapshot state, relative to the VM directory.
+	Filename string
+	// Disks is the virtual disks which the snapshot is based on.
+	Disks []Disk
+	// Clones is the .vmx file paths of the linked clones created from the snapshot.
+	Clones []string
+}
+
+// MemoryFiles returns the .vmsn file name and the .vmem file name of the snapshot memory, relative to the VM directory.
+//
+// The .vmem file exists only if the snapshot of the powered on VM includes the memory.
+func (s *Snapshot) MemoryFiles() []string {
+	if s.Filename == "" {
+		return nil
+	}
+
+	return []string{s.Filename, strings.TrimSuffix(s.Filename, filepath.Ext(s.Filename)) + ".vmem"}
+}
+
+// deltaDiskRe matches the name of the delta disk created by a snapshot, such as "test-000001.vmdk".
+var deltaDiskRe = regexp.MustCompile(`-\d{6}(-s\d{3})?\.vmdk$`)
+
+// Size returns the total size of the memory files and the delta disks of the snapshot in the dir VM directory,
+// which approximates the disk space reclaimed by deleting the snapshot.
+//
+// The base disk of the root snapshot is not counted, and the missing files are ignored.
+func (s *Snapshot) Size(dir string) (int64, error) {
+	names := s.MemoryFiles()
+	for _, d := range s.Disks {
+		if deltaDiskRe.MatchString(d.FileName) {
+			names = append(names, d.FileName)
+		}
+	}
+
+	var size int64
+	for _, name := range names {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		info, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+// snapshotIndexRe matches the uid key of a snapshot.
+var snapshotIndexRe = regexp.MustCompile(`(?i)^snapshot(\d+)\.uid$`)
+
+// Snapshots returns the snapshots in the index order.
+func (f *File) Snapshots() []*Snapshot {
+	var indexes []int
+	for _, key := range f.Keys() {
+		if m := snapshotIndexRe.FindStringSubmatch(key); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			indexes = append(indexes, n)
+		}
+	}
+	sort.Ints(indexes)
+
+	snapshots := make([]*Snapshot, 0, len(indexes))
+	for _, n := range indexes {
+		snapshots = append(snapshots, f.snapshot(n))
+	}
+
+	return snapshots
+}
+
+// snapshot returns the snapshot of the n index.
+func (f *File) snapshot(n int) *Snapshot {
+	prefix := "snapshot" + strconv.Itoa(n) + "."
+	get := func(key string) string {
+		v, _ := f.Get(prefix + key)
+		return v
+	}
+
+	s := &Snapshot{
+		Index:       n,
+		UID:         f.getInt(prefix + "uid"),
+		Parent:      f.getInt(prefix + "parent"),
+		DisplayName: get("displayName"),
+		Description: get("description"),
+		Filename:    get("filename"),
+	}
+
+	high, errHigh := strconv.ParseInt(get("createTimeHigh"), 10, 64)
+	low, errLow := strconv.ParseInt(get("createTimeLow"), 10, 64)
+	if errHigh == nil && errLow == nil {
+		// the creation time is the microseconds since the Unix epoch split into the 32 bit integers.
+		usec := high<<32 | int64(uint32(low))
+		s.CreateTime = time.Unix(usec/1e6, usec%1e6*1e3)
+	}
+
+	for i := 0; i < f.getInt(prefix+"numDisks"); i++ {
+		disk := "disk" + strconv.Itoa(i) + "."
+		s.Disks = append(s.Disks, Disk{FileName: get(disk + "fileName"), Node: get(disk + "node")})
+	}
+	for i := 0; i < f.getInt(prefix+"numClones"); i++ {
+		s.Clones = append(s.Clones, get("clone"+strconv.Itoa(i)))
+	}
+
+	return s
+}
+
+// Snapshot returns the snapshot of the uid.
+func (f *File) Snapshot(uid int) (*Snapshot, error) {
+	for _, s := range f.Snapshots() {
+		if s.UID == uid {
+			return s, nil
+		}
+	}
+
+	return nil, ErrSnapshotNotFound
+}
+
+// Current returns the current snapshot which the VM state is based on, or nil if the VM has no current snapshot.
+func (f *File) Current() *Snapshot {
+	uid := f.getInt("snapshot.current")
+	if uid == 0 {
+		return nil
+	}
+	s, err := f.Snapshot(uid)
+	if err != nil {
+		return nil
+	}
+
+	return s
+}
+
+// Children returns the child snapshots of the uid snapshot in the index order.
+// The uid 0 returns the root snapshots.
+func (f *File) Children(uid int) []*Snapshot {
+	var children []*Snapshot
+	for _, s := range f.Snapshots() {
+		if s.Parent == uid {
+			children = append(children, s)
+		}
+	}
+
+	return children
+}
+
+// SetDescription sets the description of the uid snapshot.
+func (f *File) SetDescription(uid int, description string) error {
+	s, err := f.Snapshot(uid)
+	if err != nil {
+		return err
+	}
+	f.Set("snapshot"+strconv.Itoa(s.Index)+".description", description)
+
+	return nil
+}
+
+// SetDisplayName sets the display name of the uid snapshot, which renames the snapshot shown by vmrun.
+func (f *File) SetDisplayName(uid int, name string) error {
+	s, err := f.Snapshot(uid)
+	if err != nil {
+		return err
+	}
+	f.Set("snapshot"+strconv.Itoa(s.Index)+".displayName", name)
+
+	return nil
+}
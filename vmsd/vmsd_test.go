@@ -0,0 +1,223 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmsd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVMSD = `.encoding = "UTF-8"
+snapshot.lastUID = "3"
+snapshot.current = "3"
+snapshot0.uid = "1"
+snapshot0.filename = "test-Snapshot1.vmsn"
+snapshot0.displayName = "base"
+snapshot0.description = "clean |22install|22"
+snapshot0.createTimeHigh = "378745"
+snapshot0.createTimeLow = "-1412375611"
+snapshot0.numDisks = "1"
+snapshot0.disk0.fileName = "test.vmdk"
+snapshot0.disk0.node = "scsi0:0"
+snapshot0.numClones = "1"
+snapshot0.clone0 = "/vm/clone/clone.vmx"
+snapshot1.uid = "3"
+snapshot1.parent = "1"
+snapshot1.filename = "test-Snapshot3.vmsn"
+snapshot1.displayName = "with tools 1.0"
+snapshot1.createTimeHigh = "378800"
+snapshot1.createTimeLow = "12345"
+snapshot1.numDisks = "1"
+snapshot1.disk0.fileName = "test-000001.vmdk"
+snapshot1.disk0.node = "scsi0:0"
+snapshot.numSnapshots = "2"
+snapshot.mru0.uid = "3"
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse(strings.NewReader(testVMSD))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots := f.Snapshots()
+	if len(snapshots) != 2 {
+		t.Fatalf("Snapshots = %d, want 2", len(snapshots))
+	}
+
+	want := &Snapshot{
+		Index:       0,
+		UID:         1,
+		DisplayName: "base",
+		Description: `clean "install"`,
+		CreateTime:  time.Date(2021, 7, 19, 13, 11, 11, 115205000, time.UTC).Local(),
+		Filename:    "test-Snapshot1.vmsn",
+		Disks:       []Disk{{FileName: "test.vmdk", Node: "scsi0:0"}},
+		Clones:      []string{"/vm/clone/clone.vmx"},
+	}
+	if !reflect.DeepEqual(snapshots[0], want) {
+		t.Errorf("Snapshots()[0] = %+v, want %+v", snapshots[0], want)
+	}
+
+	current := f.Current()
+	if current == nil || current.DisplayName != "with tools 1.0" || current.Parent != 1 {
+		t.Errorf("Current = %+v, want with tools 1.0", current)
+	}
+	if children := f.Children(1); len(children) != 1 || children[0].UID != 3 {
+		t.Errorf("Children(1) = %+v, want the uid 3 snapshot", children)
+	}
+	if roots := f.Children(0); len(roots) != 1 || roots[0].UID != 1 {
+		t.Errorf("Children(0) = %+v, want the uid 1 snapshot", roots)
+	}
+	if _, err := f.Snapshot(2); err != ErrSnapshotNotFound {
+		t.Errorf("Snapshot(2) error = %v, want %v", err, ErrSnapshotNotFound)
+	}
+}
+
+func TestFileWriteTo(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		edit func(f *File)
+		want string
+	}{
+		{
+			name: "unmodified",
+			in:   testVMSD,
+			edit: func(f *File) {},
+			want: testVMSD,
+		},
+		{
+			name: "crlf and comment",
+			in:   "# comment\r\n.encoding   =   \"UTF-8\"\r\n\r\nsnapshot0.uid = \"1\"\r\n",
+			edit: func(f *File) {
+				if err := f.SetDescription(1, "a|b\n"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "# comment\r\n.encoding   =   \"UTF-8\"\r\n\r\nsnapshot0.uid = \"1\"\r\nsnapshot0.description = \"a|7Cb|0A\"\r\n",
+		},
+		{
+			name: "without last line ending",
+			in:   "snapshot0.uid = \"1\"\nsnapshot0.description = \"old\"",
+			edit: func(f *File) {
+				if err := f.SetDescription(1, "new"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "snapshot0.uid = \"1\"\nsnapshot0.description = \"new\"",
+		},
+		{
+			name: "rename",
+			in:   "snapshot0.uid = \"1\"\nsnapshot0.displayName = \"ci.new\"\n",
+			edit: func(f *File) {
+				if err := f.SetDisplayName(1, "ci"); err != nil {
+					t.Fatal(err)
+				}
+				if err := f.SetDisplayName(2, "ci"); err != ErrSnapshotNotFound {
+					t.Fatalf("SetDisplayName error = %v, want %v", err, ErrSnapshotNotFound)
+				}
+			},
+			want: "snapshot0.uid = \"1\"\nsnapshot0.displayName = \"ci\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(f)
+
+			var buf bytes.Buffer
+			if _, err := f.WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Fatalf("WriteTo = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	want := `vmsd: line 1: invalid escape "|zz"`
+	if _, err := Parse(strings.NewReader("snapshot0.description = \"|zz\"\n")); err == nil || err.Error() != want {
+		t.Errorf("Parse error = %v, want %s", err, want)
+	}
+}
+
+func TestSnapshotSize(t *testing.T) {
+	dir := t.TempDir()
+	for name, size := range map[string]int{
+		"test-Snapshot3.vmsn": 10,
+		"test-Snapshot3.vmem": 100,
+		"test-000001.vmdk":    1000,
+		"test.vmdk":           10000,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := Parse(strings.NewReader(testVMSD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		uid  int
+		want int64
+	}{
+		{uid: 1, want: 0},
+		{uid: 3, want: 1110},
+	}
+	for _, tt := range tests {
+		s, err := f.Snapshot(tt.uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.Size(dir); err != nil || got != tt.want {
+			t.Errorf("Size of uid %d = %d, %v, want %d", tt.uid, got, err, tt.want)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "test.vmsd")
+	if err := ioutil.WriteFile(name, []byte(testVMSD), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.SetDescription(3, "tools installed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.WriteFile(name); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := f.Snapshot(3); s.Description != "tools installed" {
+		t.Fatalf("Description = %q, want %q", s.Description, "tools installed")
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Stat = %v, %v, want 0600 mode", info, err)
+	}
+	if got := Path("/vm/test.vmx"); got != "/vm/test.vmsd" {
+		t.Fatalf("Path = %q, want %q", got, "/vm/test.vmsd")
+	}
+}
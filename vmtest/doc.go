@@ -0,0 +1,6 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vmtest implements the conformance test suite and the in-memory fake of vmware.VM.
+package vmtest
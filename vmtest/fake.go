@@ -0,0 +1,668 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmtest
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-vm/vmware"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// state represents a power state of the Fake.
+type state int
+
+const (
+	poweredOff state = iota
+	poweredOn
+	suspended
+	paused
+)
+
+// snapshot represents a snapshot of the Fake.
+type snapshot struct {
+	name   string
+	parent *snapshot
+}
+
+// Fake represents an in-memory vmware.VM, which emulates the vmrun behavior on a POSIX guest.
+//
+// The zero value is not usable, use NewFake instead.
+type Fake struct {
+	// IPAddress is the guest IP address reported by GetGuestIPAddressContext.
+	IPAddress string
+	// Program is called by RunProgramInGuestContext if non-nil.
+	Program func(ctx context.Context, cmdPath string, cmdArgs []string) error
+	// Script is called by RunScriptInGuestContext if non-nil.
+	Script func(ctx context.Context, interpreter, script string) error
+
+	mu            sync.Mutex
+	state         state
+	snapshots     []*snapshot
+	current       *snapshot
+	files         map[string][]byte
+	dirs          map[string]bool
+	processes     []vmrun.ListProcessesInGuestInfo
+	nextPid       int
+	tempSeq       int
+	variables     map[vmrun.VariableMode]map[string]string
+	sharedFolders map[string]string
+	sharedEnabled bool
+}
+
+var _ vmware.VM = (*Fake)(nil)
+
+// NewFake returns the new powered off Fake, which guest has the "/" and "/tmp" directories.
+func NewFake() *Fake {
+	return &Fake{
+		IPAddress: "192.0.2.10",
+		files:     make(map[string][]byte),
+		dirs:      map[string]bool{"/": true, "/tmp": true},
+		processes: []vmrun.ListProcessesInGuestInfo{
+			{Pid: "1", Owner: "root", Cmd: "/sbin/init"},
+		},
+		nextPid:       100,
+		variables:     make(map[vmrun.VariableMode]map[string]string),
+		sharedFolders: make(map[string]string),
+	}
+}
+
+// fail returns the *vmrun.Error of the cmd command which wraps err.
+func fail(cmd string, err error) error {
+	return &vmrun.Error{
+		Command:  cmd,
+		ExitCode: 255,
+		Stdout:   "Error: " + strings.TrimPrefix(err.Error(), "vmrun: ") + "\n",
+		Err:      err,
+	}
+}
+
+// StartContext implements a vmware.PowerManager interface.
+func (f *Fake) StartContext(ctx context.Context, gui bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state == paused {
+		return fail("start", vmrun.ErrPoweredOn)
+	}
+	f.state = poweredOn
+
+	return nil
+}
+
+// stop stops the Fake if it is powered on.
+func (f *Fake) stop(cmd string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state == poweredOff {
+		return fail(cmd, vmrun.ErrNotPoweredOn)
+	}
+	f.state = poweredOff
+
+	return nil
+}
+
+// ShutDownContext implements a vmware.PowerManager interface.
+func (f *Fake) ShutDownContext(ctx context.Context) error {
+	return f.stop("stop")
+}
+
+// HaltContext implements a vmware.PowerManager interface.
+func (f *Fake) HaltContext(ctx context.Context) error {
+	return f.stop("stop")
+}
+
+// reset resets the Fake if it is powered on.
+func (f *Fake) reset() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state != poweredOn {
+		return fail("reset", vmrun.ErrNotPoweredOn)
+	}
+
+	return nil
+}
+
+// ResetContext implements a vmware.PowerManager interface.
+func (f *Fake) ResetContext(ctx context.Context) error {
+	return f.reset()
+}
+
+// RestartContext implements a vmware.PowerManager interface.
+func (f *Fake) RestartContext(ctx context.Context) error {
+	return f.reset()
+}
+
+// SuspendContext implements a vmware.PowerManager interface.
+func (f *Fake) SuspendContext(ctx context.Context, hard bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state != poweredOn {
+		return fail("suspend", vmrun.ErrNotPoweredOn)
+	}
+	f.state = suspended
+
+	return nil
+}
+
+// PauseContext implements a vmware.PowerManager interface.
+func (f *Fake) PauseContext(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state != poweredOn {
+		return fail("pause", vmrun.ErrNotPoweredOn)
+	}
+	f.state = paused
+
+	return nil
+}
+
+// UnpauseContext implements a vmware.PowerManager interface.
+func (f *Fake) UnpauseContext(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.state != paused {
+		return fail("unpause", vmrun.ErrNotPoweredOn)
+	}
+	f.state = poweredOn
+
+	return nil
+}
+
+// ListSnapshotsContext implements a vmware.SnapshotManager interface.
+func (f *Fake) ListSnapshotsContext(ctx context.Context) ([]string, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var names []string
+	for _, s := range f.snapshots {
+		names = append(names, s.name)
+	}
+
+	return names, len(names), nil
+}
+
+// SnapshotContext implements a vmware.SnapshotManager interface.
+//
+// Like vmrun, it allows the duplicated snapshot name.
+func (f *Fake) SnapshotContext(ctx context.Context, snapshotName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := &snapshot{name: snapshotName, parent: f.current}
+	f.snapshots = append(f.snapshots, s)
+	f.current = s
+
+	return nil
+}
+
+// findSnapshot returns the snapshot named name.
+func (f *Fake) findSnapshot(cmd, name string) (*snapshot, error) {
+	var found *snapshot
+	for _, s := range f.snapshots {
+		if s.name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fail(cmd, vmrun.ErrSnapshotNotUnique)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fail(cmd, vmrun.ErrSnapshotNotFound)
+	}
+
+	return found, nil
+}
+
+// isDescendant reports whether s is the descendant of ancestor.
+func isDescendant(s, ancestor *snapshot) bool {
+	for p := s.parent; p != nil; p = p.parent {
+		if p == ancestor {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeleteSnapshotContext implements a vmware.SnapshotManager interface.
+func (f *Fake) DeleteSnapshotContext(ctx context.Context, snapshotName string, deleteChildren bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	target, err := f.findSnapshot("deleteSnapshot", snapshotName)
+	if err != nil {
+		return err
+	}
+
+	var snapshots []*snapshot
+	for _, s := range f.snapshots {
+		switch {
+		case s == target:
+			continue
+		case deleteChildren && isDescendant(s, target):
+			continue
+		case s.parent == target:
+			s.parent = target.parent
+		}
+		snapshots = append(snapshots, s)
+	}
+	f.snapshots = snapshots
+
+	if f.current == target || (deleteChildren && f.current != nil && isDescendant(f.current, target)) {
+		f.current = target.parent
+	}
+
+	return nil
+}
+
+// RevertToSnapshotContext implements a vmware.SnapshotManager interface.
+func (f *Fake) RevertToSnapshotContext(ctx context.Context, snapshotName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.findSnapshot("revertToSnapshot", snapshotName)
+	if err != nil {
+		return err
+	}
+	f.current = s
+
+	return nil
+}
+
+// guest returns the error if the guest operations are unavailable.
+func (f *Fake) guest(cmd string) error {
+	if f.state != poweredOn {
+		return fail(cmd, vmrun.ErrNotPoweredOn)
+	}
+
+	return nil
+}
+
+// RunProgramInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) RunProgramInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, cmdPath string, cmdArgs ...string) error {
+	f.mu.Lock()
+	if err := f.guest("runProgramInGuest"); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	if config&vmrun.NoWait > 0 {
+		f.processes = append(f.processes, vmrun.ListProcessesInGuestInfo{
+			Pid:   strconv.Itoa(f.nextPid),
+			Owner: "user",
+			Cmd:   strings.Join(append([]string{cmdPath}, cmdArgs...), " "),
+		})
+		f.nextPid++
+	}
+	program := f.Program
+	f.mu.Unlock()
+
+	if program != nil {
+		return program(ctx, cmdPath, cmdArgs)
+	}
+
+	return nil
+}
+
+// RunScriptInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) RunScriptInGuestContext(ctx context.Context, config vmrun.RunInGuestConfig, interpreter, script string) error {
+	f.mu.Lock()
+	if err := f.guest("runScriptInGuest"); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	fn := f.Script
+	f.mu.Unlock()
+
+	if fn != nil {
+		return fn(ctx, interpreter, script)
+	}
+
+	return nil
+}
+
+// ListProcessesInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) ListProcessesInGuestContext(ctx context.Context) ([]vmrun.ListProcessesInGuestInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("listProcessesInGuest"); err != nil {
+		return nil, err
+	}
+
+	return append([]vmrun.ListProcessesInGuestInfo(nil), f.processes...), nil
+}
+
+// KillProcessInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) KillProcessInGuestContext(ctx context.Context, pid int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("killProcessInGuest"); err != nil {
+		return err
+	}
+	for i, p := range f.processes {
+		if p.Pid == strconv.Itoa(pid) {
+			f.processes = append(f.processes[:i], f.processes[i+1:]...)
+			return nil
+		}
+	}
+
+	return fail("killProcessInGuest", vmrun.ErrFileNotFound)
+}
+
+// FileExistsInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) FileExistsInGuestContext(ctx context.Context, filename string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("fileExistsInGuest"); err != nil {
+		return false, err
+	}
+	_, ok := f.files[path.Clean(filename)]
+
+	return ok, nil
+}
+
+// DirectoryExistsInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) DirectoryExistsInGuestContext(ctx context.Context, dir string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("directoryExistsInGuest"); err != nil {
+		return false, err
+	}
+
+	return f.dirs[path.Clean(dir)], nil
+}
+
+// DeleteFileInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) DeleteFileInGuestContext(ctx context.Context, filename string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("deleteFileInGuest"); err != nil {
+		return err
+	}
+	filename = path.Clean(filename)
+	if _, ok := f.files[filename]; !ok {
+		return fail("deleteFileInGuest", vmrun.ErrFileNotFound)
+	}
+	delete(f.files, filename)
+
+	return nil
+}
+
+// CreateDirectoryInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) CreateDirectoryInGuestContext(ctx context.Context, dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("createDirectoryInGuest"); err != nil {
+		return err
+	}
+	dir = path.Clean(dir)
+	if _, ok := f.files[dir]; ok || f.dirs[dir] {
+		return fail("createDirectoryInGuest", vmrun.ErrFileExists)
+	}
+	if !f.dirs[path.Dir(dir)] {
+		return fail("createDirectoryInGuest", vmrun.ErrFileNotFound)
+	}
+	f.dirs[dir] = true
+
+	return nil
+}
+
+// DeleteDirectoryInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) DeleteDirectoryInGuestContext(ctx context.Context, dir string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("deleteDirectoryInGuest"); err != nil {
+		return err
+	}
+	dir = path.Clean(dir)
+	if !f.dirs[dir] || dir == "/" {
+		return fail("deleteDirectoryInGuest", vmrun.ErrFileNotFound)
+	}
+
+	prefix := dir + "/"
+	for name := range f.files {
+		if strings.HasPrefix(name, prefix) {
+			delete(f.files, name)
+		}
+	}
+	for name := range f.dirs {
+		if name == dir || strings.HasPrefix(name, prefix) {
+			delete(f.dirs, name)
+		}
+	}
+
+	return nil
+}
+
+// CreateTempfileInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) CreateTempfileInGuestContext(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("CreateTempfileInGuest"); err != nil {
+		return "", err
+	}
+	f.tempSeq++
+	name := "/tmp/vmware" + strconv.Itoa(f.tempSeq)
+	f.files[name] = nil
+
+	return name, nil
+}
+
+// ListDirectoryInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) ListDirectoryInGuestContext(ctx context.Context, dir string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("listDirectoryInGuest"); err != nil {
+		return nil, err
+	}
+	dir = path.Clean(dir)
+	if !f.dirs[dir] {
+		return nil, fail("listDirectoryInGuest", vmrun.ErrFileNotFound)
+	}
+
+	var names []string
+	for name := range f.files {
+		if path.Dir(name) == dir {
+			names = append(names, path.Base(name))
+		}
+	}
+	for name := range f.dirs {
+		if name != dir && path.Dir(name) == dir {
+			names = append(names, path.Base(name))
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// CopyFileFromHostToGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) CopyFileFromHostToGuestContext(ctx context.Context, hostFilepath, guestFilepath string) error {
+	data, err := ioutil.ReadFile(hostFilepath)
+	if err != nil {
+		return fail("CopyFileFromHostToGuest", vmrun.ErrFileNotFound)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("CopyFileFromHostToGuest"); err != nil {
+		return err
+	}
+	guestFilepath = path.Clean(guestFilepath)
+	if !f.dirs[path.Dir(guestFilepath)] || f.dirs[guestFilepath] {
+		return fail("CopyFileFromHostToGuest", vmrun.ErrFileNotFound)
+	}
+	f.files[guestFilepath] = data
+
+	return nil
+}
+
+// CopyFileFromGuestToHostContext implements a vmware.GuestManager interface.
+func (f *Fake) CopyFileFromGuestToHostContext(ctx context.Context, guestFilepath, hostFilepath string) error {
+	f.mu.Lock()
+	if err := f.guest("CopyFileFromGuestToHost"); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	data, ok := f.files[path.Clean(guestFilepath)]
+	f.mu.Unlock()
+
+	if !ok {
+		return fail("CopyFileFromGuestToHost", vmrun.ErrFileNotFound)
+	}
+
+	return ioutil.WriteFile(hostFilepath, data, 0644)
+}
+
+// RenameFileInGuestContext implements a vmware.GuestManager interface.
+func (f *Fake) RenameFileInGuestContext(ctx context.Context, src, dst string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("renameFileInGuest"); err != nil {
+		return err
+	}
+	src, dst = path.Clean(src), path.Clean(dst)
+	data, ok := f.files[src]
+	if !ok || !f.dirs[path.Dir(dst)] {
+		return fail("renameFileInGuest", vmrun.ErrFileNotFound)
+	}
+	delete(f.files, src)
+	f.files[dst] = data
+
+	return nil
+}
+
+// CaptureScreenContext implements a vmware.GuestManager interface.
+func (f *Fake) CaptureScreenContext(ctx context.Context, dst string) error {
+	f.mu.Lock()
+	err := f.guest("captureScreen")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(dst, []byte("\x89PNG\r\n\x1a\n"), 0644)
+}
+
+// SetSharedFolderStateContext implements a vmware.SharedFolderManager interface.
+func (f *Fake) SetSharedFolderStateContext(ctx context.Context, shareName, hostPath string, writable bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.sharedFolders[shareName]; !ok {
+		return fail("setSharedFolderState", vmrun.ErrFileNotFound)
+	}
+	f.sharedFolders[shareName] = hostPath
+
+	return nil
+}
+
+// AddSharedFolderContext implements a vmware.SharedFolderManager interface.
+func (f *Fake) AddSharedFolderContext(ctx context.Context, shareName, newHostPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sharedFolders[shareName] = newHostPath
+
+	return nil
+}
+
+// RemoveSharedFolderContext implements a vmware.SharedFolderManager interface.
+func (f *Fake) RemoveSharedFolderContext(ctx context.Context, shareName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.sharedFolders[shareName]; !ok {
+		return fail("removeSharedFolder", vmrun.ErrFileNotFound)
+	}
+	delete(f.sharedFolders, shareName)
+
+	return nil
+}
+
+// EnableSharedFoldersContext implements a vmware.SharedFolderManager interface.
+func (f *Fake) EnableSharedFoldersContext(ctx context.Context, runtime bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sharedEnabled = true
+
+	return nil
+}
+
+// DisableSharedFoldersContext implements a vmware.SharedFolderManager interface.
+func (f *Fake) DisableSharedFoldersContext(ctx context.Context, runtime bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sharedEnabled = false
+
+	return nil
+}
+
+// WriteVariableContext implements a vmware.VariableManager interface.
+func (f *Fake) WriteVariableContext(ctx context.Context, mode vmrun.VariableMode, env, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("writeVariable"); err != nil {
+		return err
+	}
+	if f.variables[mode] == nil {
+		f.variables[mode] = make(map[string]string)
+	}
+	f.variables[mode][env] = value
+
+	return nil
+}
+
+// ReadVariableContext implements a vmware.VariableManager interface.
+//
+// Like vmrun, the undefined variable is read as an empty string.
+func (f *Fake) ReadVariableContext(ctx context.Context, mode vmrun.VariableMode, env string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("readVariable"); err != nil {
+		return "", err
+	}
+
+	return f.variables[mode][env], nil
+}
+
+// GetGuestIPAddressContext implements a vmware.VM interface.
+func (f *Fake) GetGuestIPAddressContext(ctx context.Context, wait bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.guest("getGuestIPAddress"); err != nil {
+		return "", err
+	}
+
+	return f.IPAddress, nil
+}
@@ -0,0 +1,13 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmtest
+
+import (
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	Run(t, NewFake(), nil)
+}
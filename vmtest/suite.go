@@ -0,0 +1,300 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-vm/vmware"
+	"github.com/go-vm/vmware/vmrun"
+)
+
+// Config represents the guest dependent configuration of the conformance test suite.
+type Config struct {
+	// GUI starts the VM with the GUI.
+	GUI bool
+	// GuestDir is the writable directory path in the guest. The default is "/tmp".
+	GuestDir string
+	// Program is the complete path of the program which exits successfully in the guest.
+	// The default is "/usr/bin/true".
+	Program string
+	// Interpreter is the script interpreter path in the guest. The default is "/bin/sh".
+	Interpreter string
+	// Script is the script text which exits successfully. The default is "exit 0".
+	Script string
+}
+
+// withDefaults returns the copy of c which empty fields are filled with the default values.
+func (c *Config) withDefaults() Config {
+	config := Config{
+		GuestDir:    "/tmp",
+		Program:     "/usr/bin/true",
+		Interpreter: "/bin/sh",
+		Script:      "exit 0",
+	}
+	if c == nil {
+		return config
+	}
+
+	config.GUI = c.GUI
+	if c.GuestDir != "" {
+		config.GuestDir = c.GuestDir
+	}
+	if c.Program != "" {
+		config.Program = c.Program
+	}
+	if c.Interpreter != "" {
+		config.Interpreter = c.Interpreter
+	}
+	if c.Script != "" {
+		config.Script = c.Script
+	}
+
+	return config
+}
+
+// Run runs the conformance test suite against vm.
+//
+// The vm must be powered off before Run, and is powered off after Run.
+// The snapshot tests are skipped if vm reports vmware.ErrUnsupported.
+func Run(t *testing.T, vm vmware.VM, config *Config) {
+	cfg := config.withDefaults()
+	ctx := context.Background()
+
+	hostDir, err := ioutil.TempDir("", "vmtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(hostDir)
+
+	t.Run("GuestWhenPoweredOff", func(t *testing.T) {
+		if _, err := vm.FileExistsInGuestContext(ctx, cfg.GuestDir); !errors.Is(err, vmrun.ErrNotPoweredOn) {
+			t.Fatalf("FileExistsInGuestContext error = %v, want %v", err, vmrun.ErrNotPoweredOn)
+		}
+	})
+
+	t.Run("Power", func(t *testing.T) {
+		if err := vm.StartContext(ctx, cfg.GUI); err != nil {
+			t.Fatalf("StartContext error = %v", err)
+		}
+		ip, err := vm.GetGuestIPAddressContext(ctx, true)
+		if err != nil {
+			t.Fatalf("GetGuestIPAddressContext error = %v", err)
+		}
+		if strings.TrimSpace(ip) == "" {
+			t.Fatal("GetGuestIPAddressContext returns empty address")
+		}
+		if err := vm.PauseContext(ctx); err != nil {
+			t.Fatalf("PauseContext error = %v", err)
+		}
+		if err := vm.UnpauseContext(ctx); err != nil {
+			t.Fatalf("UnpauseContext error = %v", err)
+		}
+		if err := vm.SuspendContext(ctx, false); err != nil {
+			t.Fatalf("SuspendContext error = %v", err)
+		}
+		if err := vm.StartContext(ctx, cfg.GUI); err != nil {
+			t.Fatalf("StartContext after SuspendContext error = %v", err)
+		}
+	})
+
+	t.Run("GuestFile", func(t *testing.T) {
+		exist, err := vm.DirectoryExistsInGuestContext(ctx, cfg.GuestDir)
+		if err != nil || !exist {
+			t.Fatalf("DirectoryExistsInGuestContext(%q) = %v, %v, want true", cfg.GuestDir, exist, err)
+		}
+
+		content := []byte("vmtest\n")
+		src := filepath.Join(hostDir, "src.txt")
+		if err := ioutil.WriteFile(src, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		guestFile := vmrun.GuestJoin(cfg.GuestDir, "vmtest-file.txt")
+		renamed := vmrun.GuestJoin(cfg.GuestDir, "vmtest-renamed.txt")
+
+		if err := vm.CopyFileFromHostToGuestContext(ctx, src, guestFile); err != nil {
+			t.Fatalf("CopyFileFromHostToGuestContext error = %v", err)
+		}
+		if exist, err := vm.FileExistsInGuestContext(ctx, guestFile); err != nil || !exist {
+			t.Fatalf("FileExistsInGuestContext(%q) = %v, %v, want true", guestFile, exist, err)
+		}
+		if exist, err := vm.DirectoryExistsInGuestContext(ctx, guestFile); err != nil || exist {
+			t.Fatalf("DirectoryExistsInGuestContext(%q) = %v, %v, want false", guestFile, exist, err)
+		}
+
+		names, err := vm.ListDirectoryInGuestContext(ctx, cfg.GuestDir)
+		if err != nil {
+			t.Fatalf("ListDirectoryInGuestContext error = %v", err)
+		}
+		found := false
+		for _, name := range names {
+			if name == "vmtest-file.txt" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("ListDirectoryInGuestContext(%q) = %q, want to contain %q", cfg.GuestDir, names, "vmtest-file.txt")
+		}
+
+		if err := vm.RenameFileInGuestContext(ctx, guestFile, renamed); err != nil {
+			t.Fatalf("RenameFileInGuestContext error = %v", err)
+		}
+		if exist, err := vm.FileExistsInGuestContext(ctx, guestFile); err != nil || exist {
+			t.Fatalf("FileExistsInGuestContext(%q) after rename = %v, %v, want false", guestFile, exist, err)
+		}
+
+		dst := filepath.Join(hostDir, "dst.txt")
+		if err := vm.CopyFileFromGuestToHostContext(ctx, renamed, dst); err != nil {
+			t.Fatalf("CopyFileFromGuestToHostContext error = %v", err)
+		}
+		got, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("copied content = %q, want %q", got, content)
+		}
+
+		if err := vm.DeleteFileInGuestContext(ctx, renamed); err != nil {
+			t.Fatalf("DeleteFileInGuestContext error = %v", err)
+		}
+		if err := vm.DeleteFileInGuestContext(ctx, renamed); !errors.Is(err, vmrun.ErrFileNotFound) {
+			t.Fatalf("DeleteFileInGuestContext of deleted file error = %v, want %v", err, vmrun.ErrFileNotFound)
+		}
+
+		temp, err := vm.CreateTempfileInGuestContext(ctx)
+		if err != nil {
+			t.Fatalf("CreateTempfileInGuestContext error = %v", err)
+		}
+		if exist, err := vm.FileExistsInGuestContext(ctx, temp); err != nil || !exist {
+			t.Fatalf("FileExistsInGuestContext(%q) = %v, %v, want true", temp, exist, err)
+		}
+		if err := vm.DeleteFileInGuestContext(ctx, temp); err != nil {
+			t.Fatalf("DeleteFileInGuestContext(%q) error = %v", temp, err)
+		}
+	})
+
+	t.Run("GuestDirectory", func(t *testing.T) {
+		dir := vmrun.GuestJoin(cfg.GuestDir, "vmtest-dir")
+		if err := vm.CreateDirectoryInGuestContext(ctx, dir); err != nil {
+			t.Fatalf("CreateDirectoryInGuestContext error = %v", err)
+		}
+		if exist, err := vm.DirectoryExistsInGuestContext(ctx, dir); err != nil || !exist {
+			t.Fatalf("DirectoryExistsInGuestContext(%q) = %v, %v, want true", dir, exist, err)
+		}
+
+		src := filepath.Join(hostDir, "child.txt")
+		if err := ioutil.WriteFile(src, []byte("child\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := vm.CopyFileFromHostToGuestContext(ctx, src, vmrun.GuestJoin(dir, "child.txt")); err != nil {
+			t.Fatalf("CopyFileFromHostToGuestContext error = %v", err)
+		}
+
+		if err := vm.DeleteDirectoryInGuestContext(ctx, dir); err != nil {
+			t.Fatalf("DeleteDirectoryInGuestContext error = %v", err)
+		}
+		if exist, err := vm.DirectoryExistsInGuestContext(ctx, dir); err != nil || exist {
+			t.Fatalf("DirectoryExistsInGuestContext(%q) after delete = %v, %v, want false", dir, exist, err)
+		}
+	})
+
+	t.Run("GuestProgram", func(t *testing.T) {
+		if err := vm.RunProgramInGuestContext(ctx, 0, cfg.Program); err != nil {
+			t.Fatalf("RunProgramInGuestContext(%q) error = %v", cfg.Program, err)
+		}
+		if err := vm.RunScriptInGuestContext(ctx, 0, cfg.Interpreter, cfg.Script); err != nil {
+			t.Fatalf("RunScriptInGuestContext(%q, %q) error = %v", cfg.Interpreter, cfg.Script, err)
+		}
+		processes, err := vm.ListProcessesInGuestContext(ctx)
+		if err != nil {
+			t.Fatalf("ListProcessesInGuestContext error = %v", err)
+		}
+		if len(processes) == 0 {
+			t.Fatal("ListProcessesInGuestContext returns no processes")
+		}
+		if err := vm.CaptureScreenContext(ctx, filepath.Join(hostDir, "screen.png")); err != nil {
+			t.Fatalf("CaptureScreenContext error = %v", err)
+		}
+	})
+
+	t.Run("Variable", func(t *testing.T) {
+		if err := vm.WriteVariableContext(ctx, vmrun.GuestVar, "vmtest.key", "value"); err != nil {
+			t.Fatalf("WriteVariableContext error = %v", err)
+		}
+		got, err := vm.ReadVariableContext(ctx, vmrun.GuestVar, "vmtest.key")
+		if err != nil {
+			t.Fatalf("ReadVariableContext error = %v", err)
+		}
+		if got != "value" {
+			t.Fatalf("ReadVariableContext = %q, want %q", got, "value")
+		}
+	})
+
+	t.Run("SharedFolder", func(t *testing.T) {
+		if err := vm.EnableSharedFoldersContext(ctx, true); err != nil {
+			t.Fatalf("EnableSharedFoldersContext error = %v", err)
+		}
+		if err := vm.AddSharedFolderContext(ctx, "vmtest", hostDir); err != nil {
+			t.Fatalf("AddSharedFolderContext error = %v", err)
+		}
+		if err := vm.SetSharedFolderStateContext(ctx, "vmtest", hostDir, false); err != nil {
+			t.Fatalf("SetSharedFolderStateContext error = %v", err)
+		}
+		if err := vm.RemoveSharedFolderContext(ctx, "vmtest"); err != nil {
+			t.Fatalf("RemoveSharedFolderContext error = %v", err)
+		}
+		if err := vm.DisableSharedFoldersContext(ctx, true); err != nil {
+			t.Fatalf("DisableSharedFoldersContext error = %v", err)
+		}
+	})
+
+	t.Run("PowerOff", func(t *testing.T) {
+		if err := vm.ShutDownContext(ctx); err != nil {
+			t.Fatalf("ShutDownContext error = %v", err)
+		}
+		if err := vm.ShutDownContext(ctx); !errors.Is(err, vmrun.ErrNotPoweredOn) {
+			t.Fatalf("ShutDownContext of powered off VM error = %v, want %v", err, vmrun.ErrNotPoweredOn)
+		}
+	})
+
+	t.Run("Snapshot", func(t *testing.T) {
+		before, _, err := vm.ListSnapshotsContext(ctx)
+		if errors.Is(err, vmware.ErrUnsupported) {
+			t.Skipf("snapshot is not supported: %v", err)
+		}
+		if err != nil {
+			t.Fatalf("ListSnapshotsContext error = %v", err)
+		}
+
+		const name = "vmtest-snapshot"
+		if err := vm.SnapshotContext(ctx, name); err != nil {
+			t.Fatalf("SnapshotContext error = %v", err)
+		}
+		after, n, err := vm.ListSnapshotsContext(ctx)
+		if err != nil {
+			t.Fatalf("ListSnapshotsContext error = %v", err)
+		}
+		if n != len(before)+1 || n != len(after) {
+			t.Fatalf("ListSnapshotsContext = %q, %d, want %d snapshots", after, n, len(before)+1)
+		}
+		if err := vm.RevertToSnapshotContext(ctx, name); err != nil {
+			t.Fatalf("RevertToSnapshotContext error = %v", err)
+		}
+		if err := vm.DeleteSnapshotContext(ctx, name, false); err != nil {
+			t.Fatalf("DeleteSnapshotContext error = %v", err)
+		}
+		if err := vm.DeleteSnapshotContext(ctx, name, false); !errors.Is(err, vmrun.ErrSnapshotNotFound) {
+			t.Fatalf("DeleteSnapshotContext of deleted snapshot error = %v, want %v", err, vmrun.ErrSnapshotNotFound)
+		}
+	})
+}
@@ -0,0 +1,24 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vmx implements the lossless reader and writer of the .vmx configuration file of a VM.
+//
+// The .vmx file is a list of "key = "value"" lines, such as:
+//
+//	.encoding = "UTF-8"
+//	config.version = "8"
+//	virtualHW.version = "16"
+//	displayName = "test"
+//	guestOS = "ubuntu-64"
+//	memsize = "2048"
+//	numvcpus = "2"
+//
+// The comments, the order of lines, the quoting and the line endings are kept,
+// so a File which is not modified is written back byte for byte.
+//
+// File.VirtualMachine and File.SetVirtualMachine convert the keys of the virtual hardware
+// to the typed VirtualMachine model and back.
+//
+// GuestOSInfo looks up the embedded catalog of the guestOS values and their recommended virtual hardware.
+package vmx
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	_ "embed" // for the guest OS catalog
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownGuestOS is returned by GuestOSInfo when the guest OS identifier is not in the catalog.
+var ErrUnknownGuestOS = errors.New("vmx: unknown guest OS")
+
+// GuestOS represents a guest OS identifier of the guestOS key and its recommended virtual hardware.
+type GuestOS struct {
+	// ID is the value of the guestOS key, such as "ubuntu-64".
+	ID string
+	// DisplayName is the name shown by VMware, such as "Ubuntu 64-bit".
+	DisplayName string
+	// Family is the OS family, which is "windows", "linux", "darwin", "freebsd", "solaris" or "other".
+	Family string
+	// Bits is 32 or 64.
+	Bits int
+
+	// Firmware is the recommended firmware.
+	Firmware Firmware
+	// NICDevice is the recommended virtualDev of the network adapters, such as "e1000e" or "vmxnet3".
+	NICDevice string
+	// DiskBus is the recommended controller type of the boot disk.
+	DiskBus Bus
+	// SCSIController is the recommended virtualDev of the SCSI controllers, such as "lsilogic" or "pvscsi".
+	SCSIController string
+	// Memory is the recommended memory size in MB.
+	Memory int
+	// DiskSize is the recommended boot disk size in MB.
+	DiskSize int
+}
+
+// guestOSTSV is the tab-separated guest OS catalog.
+//
+//go:embed guestos.tsv
+var guestOSTSV string
+
+var (
+	guestOSOnce sync.Once
+	guestOSes   map[string]GuestOS // lower case ID -> GuestOS
+)
+
+// parseGuestOS parses the tab-separated catalog, which lines are the fields of GuestOS in the declaration order.
+// The empty lines and the lines starting with "#" are ignored.
+func parseGuestOS(s string) (map[string]GuestOS, error) {
+	m := make(map[string]GuestOS)
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 10 {
+			return nil, fmt.Errorf("vmx: guest OS catalog line %d: %d fields, want 10", i+1, len(fields))
+		}
+		bits, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("vmx: guest OS catalog line %d: %v", i+1, err)
+		}
+		memory, err := strconv.Atoi(fields[8])
+		if err != nil {
+			return nil, fmt.Errorf("vmx: guest OS catalog line %d: %v", i+1, err)
+		}
+		diskSize, err := strconv.Atoi(fields[9])
+		if err != nil {
+			return nil, fmt.Errorf("vmx: guest OS catalog line %d: %v", i+1, err)
+		}
+		bus := Bus(fields[6])
+		if _, ok := busLimits[bus]; !ok {
+			return nil, fmt.Errorf("vmx: guest OS catalog line %d: unknown bus %q", i+1, fields[6])
+		}
+
+		m[strings.ToLower(fields[0])] = GuestOS{
+			ID:             fields[0],
+			DisplayName:    fields[1],
+			Family:         fields[2],
+			Bits:           bits,
+			Firmware:       Firmware(fields[4]),
+			NICDevice:      fields[5],
+			DiskBus:        bus,
+			SCSIController: fields[7],
+			Memory:         memory,
+			DiskSize:       diskSize,
+		}
+	}
+
+	return m, nil
+}
+
+// loadGuestOS returns the embedded catalog, which is parsed only once.
+func loadGuestOS() map[string]GuestOS {
+	guestOSOnce.Do(func() {
+		m, err := parseGuestOS(guestOSTSV)
+		if err != nil {
+			panic(err)
+		}
+		guestOSes = m
+	})
+
+	return guestOSes
+}
+
+// GuestOSInfo returns the guest OS of the id in the embedded catalog. The id is case-insensitive, as VMware reads it.
+// It returns ErrUnknownGuestOS if the id is not in the catalog.
+func GuestOSInfo(id string) (GuestOS, error) {
+	g, ok := loadGuestOS()[strings.ToLower(id)]
+	if !ok {
+		return GuestOS{}, ErrUnknownGuestOS
+	}
+
+	return g, nil
+}
+
+// GuestOSList returns all guest OSes in the embedded catalog, sorted by the ID.
+func GuestOSList() []GuestOS {
+	m := loadGuestOS()
+	list := make([]GuestOS, 0, len(m))
+	for _, g := range m {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
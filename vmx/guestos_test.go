@@ -0,0 +1,92 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGuestOSInfo(t *testing.T) {
+	tests := []struct {
+		id      string
+		want    GuestOS
+		wantErr error
+	}{
+		{
+			id: "ubuntu-64",
+			want: GuestOS{ID: "ubuntu-64", DisplayName: "Ubuntu 64-bit", Family: "linux", Bits: 64,
+				Firmware: FirmwareBIOS, NICDevice: "vmxnet3", DiskBus: BusSCSI, SCSIController: "lsilogic", Memory: 2048, DiskSize: 20480},
+		},
+		{
+			id: "Darwin19-64",
+			want: GuestOS{ID: "darwin19-64", DisplayName: "macOS 10.15", Family: "darwin", Bits: 64,
+				Firmware: FirmwareEFI, NICDevice: "e1000e", DiskBus: BusSATA, Memory: 4096, DiskSize: 81920},
+		},
+		{
+			id: "windows9-64",
+			want: GuestOS{ID: "windows9-64", DisplayName: "Windows 10 x64", Family: "windows", Bits: 64,
+				Firmware: FirmwareEFI, NICDevice: "e1000e", DiskBus: BusNVMe, Memory: 2048, DiskSize: 61440},
+		},
+		{id: "ubuntu64", wantErr: ErrUnknownGuestOS},
+		{id: "", wantErr: ErrUnknownGuestOS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := GuestOSInfo(tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GuestOSInfo(%q) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GuestOSInfo(%q) = %+v, want %+v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuestOSList(t *testing.T) {
+	list := GuestOSList()
+	if len(list) == 0 {
+		t.Fatal("GuestOSList() is empty")
+	}
+
+	for i, g := range list {
+		if i > 0 && list[i-1].ID >= g.ID {
+			t.Errorf("GuestOSList() is not sorted: %q before %q", list[i-1].ID, g.ID)
+		}
+		if g.Bits != 32 && g.Bits != 64 {
+			t.Errorf("%s: Bits = %d", g.ID, g.Bits)
+		}
+		if g.Firmware != FirmwareBIOS && g.Firmware != FirmwareEFI {
+			t.Errorf("%s: Firmware = %q", g.ID, g.Firmware)
+		}
+		if (g.DiskBus == BusSCSI) != (g.SCSIController != "") {
+			t.Errorf("%s: DiskBus = %q but SCSIController = %q", g.ID, g.DiskBus, g.SCSIController)
+		}
+		if g.Memory <= 0 || g.Memory%4 != 0 {
+			t.Errorf("%s: Memory = %d", g.ID, g.Memory)
+		}
+	}
+}
+
+func TestParseGuestOS(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"fields", "ubuntu-64\tUbuntu 64-bit\tlinux\t64\n"},
+		{"bits", "ubuntu-64\tUbuntu 64-bit\tlinux\tx\tbios\te1000\tscsi\tlsilogic\t1024\t8192\n"},
+		{"bus", "ubuntu-64\tUbuntu 64-bit\tlinux\t64\tbios\te1000\tusb\t\t1024\t8192\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseGuestOS(tt.in); err == nil {
+				t.Errorf("parseGuestOS(%q) succeeded, want error", tt.in)
+			}
+		})
+	}
+}
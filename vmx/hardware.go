@@ -0,0 +1,719 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNoFreeSlot is returned when all slots of the bus or the device kind are used.
+var ErrNoFreeSlot = errors.New("vmx: no free slot")
+
+// Bus represents a virtual disk controller type.
+type Bus string
+
+const (
+	// BusIDE is an IDE controller, such as "ide1:0".
+	BusIDE Bus = "ide"
+	// BusSCSI is a SCSI controller, such as "scsi0:0".
+	BusSCSI Bus = "scsi"
+	// BusSATA is a SATA controller, such as "sata0:1".
+	BusSATA Bus = "sata"
+	// BusNVMe is a NVMe controller, such as "nvme0:0".
+	BusNVMe Bus = "nvme"
+)
+
+// busLimit represents the number of the controllers and the units of a bus.
+type busLimit struct {
+	controllers int
+	units       int
+	reserved    int // the unit used by the controller itself, or -1
+}
+
+// busLimits is the limits of each bus supported by VMware.
+var busLimits = map[Bus]busLimit{
+	BusIDE:  {controllers: 2, units: 2, reserved: -1},
+	BusSCSI: {controllers: 4, units: 16, reserved: 7},
+	BusSATA: {controllers: 4, units: 30, reserved: -1},
+	BusNVMe: {controllers: 4, units: 15, reserved: -1},
+}
+
+// List of the limits of the device kinds.
+const (
+	// MaxNICs is the number of the ethernetN slots.
+	MaxNICs = 10
+	// MaxSerials is the number of the serialN slots.
+	MaxSerials = 4
+)
+
+// Node represents a slot of a disk controller which a disk or CD-ROM is attached to.
+type Node struct {
+	Bus        Bus
+	Controller int
+	Unit       int
+}
+
+// String returns the key prefix of n without the trailing dot, such as "scsi0:1".
+func (n Node) String() string {
+	return string(n.Bus) + strconv.Itoa(n.Controller) + ":" + strconv.Itoa(n.Unit)
+}
+
+// Valid reports whether n is a slot supported by its bus.
+func (n Node) Valid() bool {
+	limit, ok := busLimits[n.Bus]
+	if !ok {
+		return false
+	}
+
+	return n.Controller >= 0 && n.Controller < limit.controllers &&
+		n.Unit >= 0 && n.Unit < limit.units && n.Unit != limit.reserved
+}
+
+// less reports whether n is ordered before m.
+func (n Node) less(m Node) bool {
+	if n.Bus != m.Bus {
+		return n.Bus < m.Bus
+	}
+	if n.Controller != m.Controller {
+		return n.Controller < m.Controller
+	}
+
+	return n.Unit < m.Unit
+}
+
+var nodeRe = regexp.MustCompile(`(?i)^(ide|scsi|sata|nvme)(\d+):(\d+)$`)
+
+// ParseNode parses the node such as "sata0:1".
+func ParseNode(s string) (Node, error) {
+	m := nodeRe.FindStringSubmatch(s)
+	if m == nil {
+		return Node{}, fmt.Errorf("vmx: invalid node %q", s)
+	}
+	controller, _ := strconv.Atoi(m[2])
+	unit, _ := strconv.Atoi(m[3])
+
+	return Node{Bus: Bus(strings.ToLower(m[1])), Controller: controller, Unit: unit}, nil
+}
+
+// Firmware represents the firmware of a VM.
+type Firmware string
+
+const (
+	// FirmwareBIOS is the legacy BIOS, which is the default if the firmware key is missing.
+	FirmwareBIOS Firmware = "bios"
+	// FirmwareEFI is the UEFI firmware.
+	FirmwareEFI Firmware = "efi"
+)
+
+// CPU represents the virtual CPUs of a VM.
+type CPU struct {
+	// Count is the number of the virtual CPUs, which is the numvcpus key.
+	Count int
+	// CoresPerSocket is the number of the cores per socket, which is the cpuid.coresPerSocket key.
+	CoresPerSocket int
+}
+
+// Disk represents a virtual disk, which is the "<node>." keys.
+type Disk struct {
+	Node Node
+	// FileName is the .vmdk file path, which is relative to the VM directory unless absolute.
+	FileName string
+	// Mode is the disk mode, such as "persistent" or "independent-nonpersistent". Empty is the default.
+	Mode string
+}
+
+// CDROM represents a virtual CD-ROM drive, which is the "<node>." keys with the cdrom device type.
+type CDROM struct {
+	Node Node
+	// DeviceType is "cdrom-image" for an ISO image or "cdrom-raw" for a host drive.
+	DeviceType string
+	// FileName is the ISO image path or the host drive name.
+	FileName string
+	// StartConnected connects the drive when the VM powers on.
+	StartConnected bool
+}
+
+// NIC represents a virtual network adapter, which is the "ethernetN." keys.
+type NIC struct {
+	// Index is the N of the ethernetN key prefix.
+	Index int
+	// ConnectionType is the network, such as "nat", "bridged", "hostonly" or "custom".
+	ConnectionType string
+	// VirtualDev is the adapter type, such as "e1000", "e1000e" or "vmxnet3".
+	VirtualDev string
+	// VNet is the virtual network of the custom ConnectionType, such as "vmnet2".
+	VNet string
+	// AddressType is "generated" or "static". Empty is the default generated address.
+	AddressType string
+	// Address is the MAC address of the static AddressType.
+	Address string
+	// StartConnected connects the adapter when the VM powers on.
+	StartConnected bool
+}
+
+// Serial represents a virtual serial port, which is the "serialN." keys.
+type Serial struct {
+	// Index is the N of the serialN key prefix.
+	Index int
+	// FileType is the backend, such as "file", "pipe", "device" or "network".
+	FileType string
+	// FileName is the backend path, such as the output file or the named pipe.
+	FileName string
+	// StartConnected connects the port when the VM powers on.
+	StartConnected bool
+}
+
+// SharedFolder represents a Host-Guest shared folder, which is the "sharedFolderN." keys.
+type SharedFolder struct {
+	// Index is the N of the sharedFolderN key prefix.
+	Index int
+	// GuestName is the share name shown in the guest.
+	GuestName string
+	// HostPath is the shared host directory.
+	HostPath string
+	// Writable allows the guest to write the folder.
+	Writable bool
+	// Enabled shares the folder.
+	Enabled bool
+}
+
+// USB represents the USB controllers of a VM.
+type USB struct {
+	// Present adds the USB 2.0 controller, which is the usb.present key.
+	Present bool
+	// XHCI adds the USB 3.x controller, which is the usb_xhci.present key.
+	XHCI bool
+}
+
+// VirtualMachine represents the virtual hardware of a VM.
+//
+// It is read from and written to a File by File.VirtualMachine and File.SetVirtualMachine,
+// which keep the keys not represented by the model. Only the present devices are listed.
+type VirtualMachine struct {
+	DisplayName string
+	// GuestOS is the guest OS identifier, such as "ubuntu-64".
+	GuestOS string
+	// HWVersion is the virtual hardware version, which is the virtualHW.version key.
+	HWVersion int
+	CPU       CPU
+	// Memory is the memory size in MB, which is the memsize key.
+	Memory int
+	// Firmware is the firmware. Empty is the default BIOS.
+	Firmware      Firmware
+	Disks         []Disk
+	CDROMs        []CDROM
+	NICs          []NIC
+	Serials       []Serial
+	SharedFolders []SharedFolder
+	USB           USB
+}
+
+// usedNodes returns the nodes used by the disks and the CD-ROMs of vm.
+func (vm *VirtualMachine) usedNodes() map[Node]bool {
+	used := make(map[Node]bool)
+	for _, d := range vm.Disks {
+		used[d.Node] = true
+	}
+	for _, c := range vm.CDROMs {
+		used[c.Node] = true
+	}
+
+	return used
+}
+
+// FreeNode returns the first free slot of the bus, in the order of the controllers and then the units.
+func (vm *VirtualMachine) FreeNode(bus Bus) (Node, error) {
+	limit, ok := busLimits[bus]
+	if !ok {
+		return Node{}, fmt.Errorf("vmx: unknown bus %q", bus)
+	}
+
+	used := vm.usedNodes()
+	for controller := 0; controller < limit.controllers; controller++ {
+		for unit := 0; unit < limit.units; unit++ {
+			n := Node{Bus: bus, Controller: controller, Unit: unit}
+			if unit != limit.reserved && !used[n] {
+				return n, nil
+			}
+		}
+	}
+
+	return Node{}, ErrNoFreeSlot
+}
+
+// AddDisk attaches the fileName virtual disk to the next free slot of the bus.
+func (vm *VirtualMachine) AddDisk(bus Bus, fileName string) (Disk, error) {
+	n, err := vm.FreeNode(bus)
+	if err != nil {
+		return Disk{}, err
+	}
+	d := Disk{Node: n, FileName: fileName}
+	vm.Disks = append(vm.Disks, d)
+
+	return d, nil
+}
+
+// AddCDROM attaches the fileName ISO image to the next free slot of the bus.
+func (vm *VirtualMachine) AddCDROM(bus Bus, fileName string) (CDROM, error) {
+	n, err := vm.FreeNode(bus)
+	if err != nil {
+		return CDROM{}, err
+	}
+	c := CDROM{Node: n, DeviceType: "cdrom-image", FileName: fileName, StartConnected: true}
+	vm.CDROMs = append(vm.CDROMs, c)
+
+	return c, nil
+}
+
+// RemoveDisk detaches the disk of the node, and reports whether it is found.
+func (vm *VirtualMachine) RemoveDisk(n Node) bool {
+	for i, d := range vm.Disks {
+		if d.Node == n {
+			vm.Disks = append(vm.Disks[:i], vm.Disks[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveCDROM detaches the CD-ROM of the node, and reports whether it is found.
+func (vm *VirtualMachine) RemoveCDROM(n Node) bool {
+	for i, c := range vm.CDROMs {
+		if c.Node == n {
+			vm.CDROMs = append(vm.CDROMs[:i], vm.CDROMs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// freeIndex returns the smallest index in [0, max) which is not used.
+func freeIndex(used map[int]bool, max int) (int, error) {
+	for i := 0; i < max; i++ {
+		if !used[i] {
+			return i, nil
+		}
+	}
+
+	return 0, ErrNoFreeSlot
+}
+
+// AddNIC adds the network adapter to the next free ethernetN slot.
+func (vm *VirtualMachine) AddNIC(connectionType, virtualDev string) (NIC, error) {
+	used := make(map[int]bool)
+	for _, n := range vm.NICs {
+		used[n.Index] = true
+	}
+	i, err := freeIndex(used, MaxNICs)
+	if err != nil {
+		return NIC{}, err
+	}
+	n := NIC{Index: i, ConnectionType: connectionType, VirtualDev: virtualDev, StartConnected: true}
+	vm.NICs = append(vm.NICs, n)
+
+	return n, nil
+}
+
+// RemoveNIC removes the ethernetN network adapter of the index, and reports whether it is found.
+func (vm *VirtualMachine) RemoveNIC(index int) bool {
+	for i, n := range vm.NICs {
+		if n.Index == index {
+			vm.NICs = append(vm.NICs[:i], vm.NICs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddSerial adds the serial port to the next free serialN slot.
+func (vm *VirtualMachine) AddSerial(fileType, fileName string) (Serial, error) {
+	used := make(map[int]bool)
+	for _, s := range vm.Serials {
+		used[s.Index] = true
+	}
+	i, err := freeIndex(used, MaxSerials)
+	if err != nil {
+		return Serial{}, err
+	}
+	s := Serial{Index: i, FileType: fileType, FileName: fileName, StartConnected: true}
+	vm.Serials = append(vm.Serials, s)
+
+	return s, nil
+}
+
+// RemoveSerial removes the serialN port of the index, and reports whether it is found.
+func (vm *VirtualMachine) RemoveSerial(index int) bool {
+	for i, s := range vm.Serials {
+		if s.Index == index {
+			vm.Serials = append(vm.Serials[:i], vm.Serials[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddSharedFolder adds the enabled shared folder to the next free sharedFolderN slot.
+func (vm *VirtualMachine) AddSharedFolder(guestName, hostPath string, writable bool) SharedFolder {
+	used := make(map[int]bool)
+	for _, s := range vm.SharedFolders {
+		used[s.Index] = true
+	}
+	// the number of the shared folders is not limited.
+	i, _ := freeIndex(used, len(vm.SharedFolders)+1)
+	s := SharedFolder{Index: i, GuestName: guestName, HostPath: hostPath, Writable: writable, Enabled: true}
+	vm.SharedFolders = append(vm.SharedFolders, s)
+
+	return s
+}
+
+// RemoveSharedFolder removes the shared folder of the guestName, and reports whether it is found.
+func (vm *VirtualMachine) RemoveSharedFolder(guestName string) bool {
+	for i, s := range vm.SharedFolders {
+		if s.GuestName == guestName {
+			vm.SharedFolders = append(vm.SharedFolders[:i], vm.SharedFolders[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// getBool returns the boolean value of the key, which is "TRUE" or "FALSE" in any case.
+func (f *File) getBool(key string) bool {
+	v, _ := f.Get(key)
+	return strings.EqualFold(v, "TRUE")
+}
+
+// getInt returns the integer value of the key, or 0 if it is missing or invalid.
+func (f *File) getInt(key string) int {
+	v, _ := f.Get(key)
+	n, _ := strconv.Atoi(v)
+
+	return n
+}
+
+// setString sets the value of the key, or deletes the key if value is empty.
+func (f *File) setString(key, value string) {
+	if value == "" {
+		f.Delete(key)
+		return
+	}
+	f.Set(key, value)
+}
+
+// setBool sets the boolean value of the key, keeping the existing spelling of the same value.
+// The missing key is not added for false, which is the default.
+func (f *File) setBool(key string, value bool) {
+	if v, ok := f.Get(key); (ok && strings.EqualFold(v, "TRUE") == value) || (!ok && !value) {
+		return
+	}
+	f.Set(key, strings.ToUpper(strconv.FormatBool(value)))
+}
+
+// setInt sets the integer value of the key, or deletes the key if value is 0.
+// The existing spelling of the same value is kept.
+func (f *File) setInt(key string, value int) {
+	if value == 0 {
+		f.Delete(key)
+		return
+	}
+	if v, ok := f.Get(key); ok {
+		if n, err := strconv.Atoi(v); err == nil && n == value {
+			return
+		}
+	}
+	f.Set(key, strconv.Itoa(value))
+}
+
+// deletePrefix deletes the keys which start with prefix in any case.
+func (f *File) deletePrefix(prefix string) {
+	f.cf.DeletePrefix(prefix)
+}
+
+// isCDROMType reports whether the deviceType is a CD-ROM drive.
+func isCDROMType(deviceType string) bool {
+	t := strings.ToLower(deviceType)
+	return strings.HasPrefix(t, "cdrom") || t == "atapi-cdrom"
+}
+
+// List of the regexps which match the present key of the devices.
+var (
+	nodePresentRe   = regexp.MustCompile(`(?i)^((?:ide|scsi|sata|nvme)\d+:\d+)\.present$`)
+	nicPresentRe    = regexp.MustCompile(`(?i)^ethernet(\d+)\.present$`)
+	serialPresentRe = regexp.MustCompile(`(?i)^serial(\d+)\.present$`)
+	folderPresentRe = regexp.MustCompile(`(?i)^sharedFolder(\d+)\.present$`)
+)
+
+// presentDevices returns the present nodes and the present indexes of the nicPresentRe, serialPresentRe and
+// folderPresentRe devices in f.
+func (f *File) presentDevices() (nodes []Node, nics, serials, folders []int) {
+	for _, key := range f.Keys() {
+		if !f.getBool(key) {
+			continue
+		}
+		if m := nodePresentRe.FindStringSubmatch(key); m != nil {
+			if n, err := ParseNode(m[1]); err == nil {
+				nodes = append(nodes, n)
+			}
+			continue
+		}
+		for _, d := range []struct {
+			re      *regexp.Regexp
+			indexes *[]int
+		}{{nicPresentRe, &nics}, {serialPresentRe, &serials}, {folderPresentRe, &folders}} {
+			if m := d.re.FindStringSubmatch(key); m != nil {
+				i, _ := strconv.Atoi(m[1])
+				*d.indexes = append(*d.indexes, i)
+			}
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].less(nodes[j]) })
+	sort.Ints(nics)
+	sort.Ints(serials)
+	sort.Ints(folders)
+
+	return nodes, nics, serials, folders
+}
+
+// VirtualMachine returns the virtual hardware model of f.
+func (f *File) VirtualMachine() *VirtualMachine {
+	get := func(key string) string {
+		v, _ := f.Get(key)
+		return v
+	}
+
+	vm := &VirtualMachine{
+		DisplayName: get("displayName"),
+		GuestOS:     get("guestOS"),
+		HWVersion:   f.getInt("virtualHW.version"),
+		CPU: CPU{
+			Count:          f.getInt("numvcpus"),
+			CoresPerSocket: f.getInt("cpuid.coresPerSocket"),
+		},
+		Memory:   f.getInt("memsize"),
+		Firmware: Firmware(strings.ToLower(get("firmware"))),
+		USB: USB{
+			Present: f.getBool("usb.present"),
+			XHCI:    f.getBool("usb_xhci.present"),
+		},
+	}
+
+	nodes, nics, serials, folders := f.presentDevices()
+	for _, n := range nodes {
+		p := n.String() + "."
+		if deviceType := get(p + "deviceType"); isCDROMType(deviceType) {
+			vm.CDROMs = append(vm.CDROMs, CDROM{
+				Node:           n,
+				DeviceType:     deviceType,
+				FileName:       get(p + "fileName"),
+				StartConnected: f.getBool(p + "startConnected"),
+			})
+			continue
+		}
+		vm.Disks = append(vm.Disks, Disk{Node: n, FileName: get(p + "fileName"), Mode: get(p + "mode")})
+	}
+	for _, i := range nics {
+		p := "ethernet" + strconv.Itoa(i) + "."
+		vm.NICs = append(vm.NICs, NIC{
+			Index:          i,
+			ConnectionType: get(p + "connectionType"),
+			VirtualDev:     get(p + "virtualDev"),
+			VNet:           get(p + "vnet"),
+			AddressType:    get(p + "addressType"),
+			Address:        get(p + "address"),
+			StartConnected: f.getBool(p + "startConnected"),
+		})
+	}
+	for _, i := range serials {
+		p := "serial" + strconv.Itoa(i) + "."
+		vm.Serials = append(vm.Serials, Serial{
+			Index:          i,
+			FileType:       get(p + "fileType"),
+			FileName:       get(p + "fileName"),
+			StartConnected: f.getBool(p + "startConnected"),
+		})
+	}
+	for _, i := range folders {
+		p := "sharedFolder" + strconv.Itoa(i) + "."
+		vm.SharedFolders = append(vm.SharedFolders, SharedFolder{
+			Index:     i,
+			GuestName: get(p + "guestName"),
+			HostPath:  get(p + "hostPath"),
+			Writable:  f.getBool(p + "writeAccess"),
+			Enabled:   f.getBool(p + "enabled"),
+		})
+	}
+
+	return vm
+}
+
+// validate returns the error if vm has an invalid or duplicated slot.
+func (vm *VirtualMachine) validate() error {
+	nodes := vm.usedNodes()
+	if len(nodes) != len(vm.Disks)+len(vm.CDROMs) {
+		return errors.New("vmx: duplicate disk or CD-ROM node")
+	}
+	for n := range nodes {
+		if !n.Valid() {
+			return fmt.Errorf("vmx: invalid node %q", n)
+		}
+	}
+
+	// checkIndex returns the error if i is out of [0, max) or duplicated. The negative max is unlimited.
+	checkIndex := func(kind string, seen map[int]bool, i, max int) error {
+		if i < 0 || (max >= 0 && i >= max) {
+			return fmt.Errorf("vmx: invalid %s index %d", kind, i)
+		}
+		if seen[i] {
+			return fmt.Errorf("vmx: duplicate %s index %d", kind, i)
+		}
+		seen[i] = true
+		return nil
+	}
+	seen := make(map[int]bool)
+	for _, n := range vm.NICs {
+		if err := checkIndex("ethernet", seen, n.Index, MaxNICs); err != nil {
+			return err
+		}
+	}
+	seen = make(map[int]bool)
+	for _, s := range vm.Serials {
+		if err := checkIndex("serial", seen, s.Index, MaxSerials); err != nil {
+			return err
+		}
+	}
+	seen = make(map[int]bool)
+	for _, s := range vm.SharedFolders {
+		if err := checkIndex("sharedFolder", seen, s.Index, -1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetVirtualMachine writes the virtual hardware model vm to f.
+//
+// The keys of the devices removed from vm are deleted, and the keys not represented by the model are kept.
+// The controllers of the attached disks and CD-ROMs are made present.
+func (f *File) SetVirtualMachine(vm *VirtualMachine) error {
+	if err := vm.validate(); err != nil {
+		return err
+	}
+
+	f.setString("displayName", vm.DisplayName)
+	f.setString("guestOS", vm.GuestOS)
+	f.setInt("virtualHW.version", vm.HWVersion)
+	f.setInt("numvcpus", vm.CPU.Count)
+	f.setInt("cpuid.coresPerSocket", vm.CPU.CoresPerSocket)
+	f.setInt("memsize", vm.Memory)
+	if v, _ := f.Get("firmware"); !strings.EqualFold(v, string(vm.Firmware)) {
+		f.setString("firmware", string(vm.Firmware))
+	}
+	f.setBool("usb.present", vm.USB.Present)
+	f.setBool("usb_xhci.present", vm.USB.XHCI)
+
+	nodes, nics, serials, folders := f.presentDevices()
+	present := make(map[string]bool)
+	for _, n := range nodes {
+		present[n.String()+"."] = true
+	}
+	for _, kind := range []struct {
+		prefix  string
+		indexes []int
+	}{{"ethernet", nics}, {"serial", serials}, {"sharedFolder", folders}} {
+		for _, i := range kind.indexes {
+			present[kind.prefix+strconv.Itoa(i)+"."] = true
+		}
+	}
+
+	// device starts the device of the prefix, removing the keys left by the device which is not present.
+	wanted := make(map[string]bool)
+	device := func(prefix string) {
+		wanted[prefix] = true
+		if !present[prefix] {
+			f.deletePrefix(prefix)
+		}
+		f.setBool(prefix+"present", true)
+	}
+
+	controllers := make(map[string]bool)
+	for _, d := range vm.Disks {
+		p := d.Node.String() + "."
+		device(p)
+		if v, _ := f.Get(p + "deviceType"); isCDROMType(v) {
+			f.Delete(p + "deviceType")
+		}
+		f.setString(p+"fileName", d.FileName)
+		f.setString(p+"mode", d.Mode)
+		controllers[string(d.Node.Bus)+strconv.Itoa(d.Node.Controller)] = true
+	}
+	for _, c := range vm.CDROMs {
+		p := c.Node.String() + "."
+		device(p)
+		f.setString(p+"deviceType", c.DeviceType)
+		f.setString(p+"fileName", c.FileName)
+		f.setBool(p+"startConnected", c.StartConnected)
+		controllers[string(c.Node.Bus)+strconv.Itoa(c.Node.Controller)] = true
+	}
+	for _, n := range vm.NICs {
+		p := "ethernet" + strconv.Itoa(n.Index) + "."
+		device(p)
+		f.setString(p+"connectionType", n.ConnectionType)
+		f.setString(p+"virtualDev", n.VirtualDev)
+		f.setString(p+"vnet", n.VNet)
+		f.setString(p+"addressType", n.AddressType)
+		f.setString(p+"address", n.Address)
+		f.setBool(p+"startConnected", n.StartConnected)
+	}
+	for _, s := range vm.Serials {
+		p := "serial" + strconv.Itoa(s.Index) + "."
+		device(p)
+		f.setString(p+"fileType", s.FileType)
+		f.setString(p+"fileName", s.FileName)
+		f.setBool(p+"startConnected", s.StartConnected)
+	}
+	maxFolders := 0
+	for _, s := range vm.SharedFolders {
+		p := "sharedFolder" + strconv.Itoa(s.Index) + "."
+		device(p)
+		f.setBool(p+"enabled", s.Enabled)
+		f.setBool(p+"readAccess", true)
+		f.setBool(p+"writeAccess", s.Writable)
+		f.setString(p+"hostPath", s.HostPath)
+		f.setString(p+"guestName", s.GuestName)
+		if s.Index >= maxFolders {
+			maxFolders = s.Index + 1
+		}
+	}
+	if maxFolders > f.getInt("sharedFolder.maxNum") {
+		f.setInt("sharedFolder.maxNum", maxFolders)
+	}
+
+	for prefix := range present {
+		if !wanted[prefix] {
+			f.deletePrefix(prefix)
+		}
+	}
+	names := make([]string, 0, len(controllers))
+	for controller := range controllers {
+		names = append(names, controller)
+	}
+	sort.Strings(names)
+	for _, controller := range names {
+		f.setBool(controller+".present", true)
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testHardwareVMX = `.encoding = "UTF-8"
+config.version = "8"
+virtualHW.version = "16"
+displayName = "test"
+guestOS = "ubuntu-64"
+numvcpus = "2"
+cpuid.coresPerSocket = "2"
+memsize = "2048"
+firmware = "efi"
+scsi0.present = "TRUE"
+scsi0.virtualDev = "pvscsi"
+scsi0:0.present = "TRUE"
+scsi0:0.fileName = "test.vmdk"
+sata0.present = "TRUE"
+sata0:1.present = "TRUE"
+sata0:1.deviceType = "cdrom-image"
+sata0:1.fileName = "/iso/ubuntu.iso"
+sata0:1.startConnected = "TRUE"
+ethernet0.present = "TRUE"
+ethernet0.connectionType = "nat"
+ethernet0.virtualDev = "vmxnet3"
+ethernet0.addressType = "generated"
+ethernet0.generatedAddress = "00:0c:29:aa:bb:cc"
+ethernet1.present = "FALSE"
+serial0.present = "TRUE"
+serial0.fileType = "file"
+serial0.fileName = "serial.log"
+sharedFolder0.present = "TRUE"
+sharedFolder0.enabled = "TRUE"
+sharedFolder0.readAccess = "TRUE"
+sharedFolder0.writeAccess = "FALSE"
+sharedFolder0.hostPath = "/src"
+sharedFolder0.guestName = "src"
+sharedFolder.maxNum = "1"
+usb.present = "TRUE"
+`
+
+func parseTestVMX(t *testing.T, s string) *File {
+	t.Helper()
+
+	f, err := Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func writeString(t *testing.T, f *File) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestFileVirtualMachine(t *testing.T) {
+	got := parseTestVMX(t, testHardwareVMX).VirtualMachine()
+
+	want := &VirtualMachine{
+		DisplayName: "test",
+		GuestOS:     "ubuntu-64",
+		HWVersion:   16,
+		CPU:         CPU{Count: 2, CoresPerSocket: 2},
+		Memory:      2048,
+		Firmware:    FirmwareEFI,
+		Disks:       []Disk{{Node: Node{Bus: BusSCSI, Controller: 0, Unit: 0}, FileName: "test.vmdk"}},
+		CDROMs: []CDROM{{
+			Node:           Node{Bus: BusSATA, Controller: 0, Unit: 1},
+			DeviceType:     "cdrom-image",
+			FileName:       "/iso/ubuntu.iso",
+			StartConnected: true,
+		}},
+		NICs:          []NIC{{Index: 0, ConnectionType: "nat", VirtualDev: "vmxnet3", AddressType: "generated"}},
+		Serials:       []Serial{{Index: 0, FileType: "file", FileName: "serial.log"}},
+		SharedFolders: []SharedFolder{{Index: 0, GuestName: "src", HostPath: "/src", Enabled: true}},
+		USB:           USB{Present: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("VirtualMachine = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileSetVirtualMachine(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(t *testing.T, vm *VirtualMachine)
+		want     []string          // the lines added to testHardwareVMX in order
+		deleted  []string          // the key prefixes deleted from testHardwareVMX
+		replaced map[string]string // the lines of testHardwareVMX replaced in place
+	}{
+		{
+			name:   "unchanged",
+			modify: func(t *testing.T, vm *VirtualMachine) {},
+		},
+		{
+			name: "scalars",
+			modify: func(t *testing.T, vm *VirtualMachine) {
+				vm.Memory = 4096
+				vm.Firmware = ""
+				vm.USB.XHCI = true
+			},
+			want:     []string{`usb_xhci.present = "TRUE"`},
+			deleted:  []string{"firmware"},
+			replaced: map[string]string{`memsize = "2048"`: `memsize = "4096"`},
+		},
+		{
+			name: "add disk",
+			modify: func(t *testing.T, vm *VirtualMachine) {
+				d, err := vm.AddDisk(BusSCSI, "data.vmdk")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d.Node.String() != "scsi0:1" {
+					t.Fatalf("AddDisk node = %v, want scsi0:1", d.Node)
+				}
+			},
+			want: []string{`scsi0:1.present = "TRUE"`, `scsi0:1.fileName = "data.vmdk"`},
+		},
+		{
+			name: "add cdrom on new controller",
+			modify: func(t *testing.T, vm *VirtualMachine) {
+				c, err := vm.AddCDROM(BusIDE, "/iso/tools.iso")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c.Node.String() != "ide0:0" {
+					t.Fatalf("AddCDROM node = %v, want ide0:0", c.Node)
+				}
+			},
+			want: []string{
+				`ide0:0.present = "TRUE"`,
+				`ide0:0.deviceType = "cdrom-image"`,
+				`ide0:0.fileName = "/iso/tools.iso"`,
+				`ide0:0.startConnected = "TRUE"`,
+				`ide0.present = "TRUE"`,
+			},
+		},
+		{
+			name: "add nic to not present slot",
+			modify: func(t *testing.T, vm *VirtualMachine) {
+				n, err := vm.AddNIC("bridged", "e1000e")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n.Index != 1 {
+					t.Fatalf("AddNIC index = %d, want 1", n.Index)
+				}
+			},
+			want: []string{
+				`ethernet1.present = "TRUE"`,
+				`ethernet1.connectionType = "bridged"`,
+				`ethernet1.virtualDev = "e1000e"`,
+				`ethernet1.startConnected = "TRUE"`,
+			},
+			deleted: []string{"ethernet1."},
+		},
+		{
+			name: "remove devices",
+			modify: func(t *testing.T, vm *VirtualMachine) {
+				if !vm.RemoveNIC(0) || !vm.RemoveCDROM(Node{Bus: BusSATA, Unit: 1}) || !vm.RemoveSharedFolder("src") {
+					t.Fatal("remove = false, want true")
+				}
+				if vm.RemoveSerial(1) {
+					t.Fatal("RemoveSerial(1) = true, want false")
+				}
+			},
+			deleted: []string{"ethernet0.", "sata0:1.", "sharedFolder0."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := parseTestVMX(t, testHardwareVMX)
+			vm := f.VirtualMachine()
+			tt.modify(t, vm)
+			if err := f.SetVirtualMachine(vm); err != nil {
+				t.Fatal(err)
+			}
+
+			var want []string
+			for _, line := range strings.Split(strings.TrimSuffix(testHardwareVMX, "\n"), "\n") {
+				keep := true
+				for _, prefix := range tt.deleted {
+					if strings.HasPrefix(line, prefix) {
+						keep = false
+					}
+				}
+				if r, ok := tt.replaced[line]; ok {
+					line = r
+				}
+				if keep {
+					want = append(want, line)
+				}
+			}
+			want = append(want, tt.want...)
+			if got := writeString(t, f); got != strings.Join(want, "\n")+"\n" {
+				t.Fatalf("WriteTo =\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFileSetVirtualMachineError(t *testing.T) {
+	tests := []struct {
+		name string
+		vm   VirtualMachine
+	}{
+		{name: "reserved scsi unit", vm: VirtualMachine{Disks: []Disk{{Node: Node{Bus: BusSCSI, Unit: 7}}}}},
+		{name: "unknown bus", vm: VirtualMachine{Disks: []Disk{{Node: Node{Bus: "usb"}}}}},
+		{name: "duplicate node", vm: VirtualMachine{
+			Disks:  []Disk{{Node: Node{Bus: BusSATA}}},
+			CDROMs: []CDROM{{Node: Node{Bus: BusSATA}}},
+		}},
+		{name: "duplicate nic", vm: VirtualMachine{NICs: []NIC{{Index: 1}, {Index: 1}}}},
+		{name: "nic out of range", vm: VirtualMachine{NICs: []NIC{{Index: MaxNICs}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New().SetVirtualMachine(&tt.vm); err == nil {
+				t.Fatal("SetVirtualMachine error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestVirtualMachineFreeNode(t *testing.T) {
+	vm := &VirtualMachine{}
+	for i := 0; i < 7; i++ {
+		if _, err := vm.AddDisk(BusSCSI, "disk.vmdk"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := vm.FreeNode(BusSCSI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Node{Bus: BusSCSI, Unit: 8}); n != want {
+		t.Fatalf("FreeNode = %v, want %v", n, want)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := vm.AddCDROM(BusIDE, "a.iso"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := vm.FreeNode(BusIDE); err != ErrNoFreeSlot {
+		t.Fatalf("FreeNode error = %v, want %v", err, ErrNoFreeSlot)
+	}
+}
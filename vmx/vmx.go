@@ -0,0 +1,97 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	"io"
+	"os"
+
+	"github.com/go-vm/vmware/internal/configfile"
+)
+
+// EncodingKey is the key of the character encoding header of the .vmx file.
+const EncodingKey = configfile.EncodingKey
+
+// File represents a .vmx file.
+//
+// The values are kept as the byte strings in the encoding of the file, which is reported by Encoding.
+type File struct {
+	cf *configfile.File
+}
+
+// New returns the new empty File, which has the UTF-8 encoding header.
+func New() *File {
+	return &File{cf: configfile.New()}
+}
+
+// Parse parses the .vmx file content from r.
+func Parse(r io.Reader) (*File, error) {
+	cf, err := configfile.Parse(r, "vmx")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{cf: cf}, nil
+}
+
+// ReadFile reads and parses the name .vmx file.
+func ReadFile(name string) (*File, error) {
+	cf, err := configfile.ReadFile(name, "vmx")
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{cf: cf}, nil
+}
+
+// WriteTo writes f to w.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	return f.cf.WriteTo(w)
+}
+
+// WriteFile writes f to the name file atomically, by renaming the temporary file in the same directory.
+// The new file is created with 0600, and the existing file keeps its permissions.
+func (f *File) WriteFile(name string) error {
+	return f.cf.WriteFile(name)
+}
+
+// WriteFileMode is like WriteFile but the new file is created with perm.
+func (f *File) WriteFileMode(name string, perm os.FileMode) error {
+	return f.cf.WriteFileMode(name, perm)
+}
+
+// Encoding returns the character encoding of the file declared by the .encoding header,
+// or empty if the file has no header.
+func (f *File) Encoding() string {
+	return f.cf.Encoding()
+}
+
+// Get returns the value of the key, which is case insensitive.
+func (f *File) Get(key string) (string, bool) {
+	return f.cf.Get(key)
+}
+
+// Set sets the value of the key, appending it if the key does not exist.
+//
+// The existing key keeps its spelling. The .encoding header is inserted at the top of the file.
+func (f *File) Set(key, value string) {
+	f.cf.Set(key, value)
+}
+
+// Delete deletes the key, including its duplicates.
+func (f *File) Delete(key string) {
+	f.cf.Delete(key)
+}
+
+// Lines returns the 1-based line numbers of the key, which has more than one line if the key is duplicated.
+// The line numbers are the ones of the parsed file until f is modified.
+func (f *File) Lines(key string) []int {
+	return f.cf.Lines(key)
+}
+
+// Keys returns the keys in the file order, including the duplicated keys.
+func (f *File) Keys() []string {
+	return f.cf.Keys()
+}
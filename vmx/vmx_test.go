@@ -0,0 +1,50 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	in := ".encoding = \"UTF-8\"\r\ndisplayName = \"test |22vm|22\"\r\nnumvcpus=2"
+	f, err := Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.Get("DISPLAYNAME"); got != `test "vm"` {
+		t.Errorf("Get = %q, want %q", got, `test "vm"`)
+	}
+	if got := f.Encoding(); got != "UTF-8" {
+		t.Errorf("Encoding = %q, want %q", got, "UTF-8")
+	}
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != in {
+		t.Errorf("WriteTo = %q, want %q", buf.String(), in)
+	}
+
+	if _, err := Parse(strings.NewReader("memsize\n")); err == nil || !strings.HasPrefix(err.Error(), "vmx: ") {
+		t.Errorf("Parse error = %v, want vmx error", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	f := New()
+	f.Set("memsize", "1024")
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := ".encoding = \"UTF-8\"\nmemsize = \"1024\"\n"; buf.String() != want {
+		t.Fatalf("WriteTo = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmxlint
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-vm/vmware/docs"
+	"github.com/go-vm/vmware/vmx"
+)
+
+// extraKeys is the keys written by VMware which are missing from the embedded documents,
+// such as the NVMe controllers which are newer than the documents.
+var extraKeys = []string{
+	"ide%d.present",
+	"ide%d:%d.redo",
+	"scsi%d:%d.redo",
+	"sata%d:%d.redo",
+	"nvme%d.present",
+	"nvme%d.pciSlotNumber",
+	"nvme%d:%d.present",
+	"nvme%d:%d.fileName",
+	"nvme%d:%d.mode",
+	"nvme%d:%d.deviceType",
+	"nvme%d:%d.startConnected",
+	"nvme%d:%d.redo",
+}
+
+// freeFormPrefixes is the key prefixes which accept any name, such as the guestinfo variables.
+var freeFormPrefixes = []string{".", "guestinfo.", "answer."}
+
+// numberRe matches the device numbers in a key.
+var numberRe = regexp.MustCompile(`\d+`)
+
+// placeholderRe matches the device numbers and their placeholders in a key.
+var placeholderRe = regexp.MustCompile(`%[du]|\d+`)
+
+// normalize returns the lower case key which device numbers are replaced with "%d".
+func normalize(key string) string {
+	return placeholderRe.ReplaceAllString(strings.ToLower(key), "%d")
+}
+
+// Catalog represents the set of the known .vmx parameters and guestOS values.
+type Catalog struct {
+	keys    map[string]string // normalized key -> canonical spelling with "%d"
+	guestOS map[string]string // lower case guestOS value -> canonical spelling
+}
+
+// NewCatalog returns the new Catalog of the keys, which may contain "%d" as the device numbers such as "scsi%d:%d.fileName".
+// The guestOS values of the catalog are the IDs of vmx.GuestOSList.
+func NewCatalog(keys []string) *Catalog {
+	c := &Catalog{keys: make(map[string]string), guestOS: make(map[string]string)}
+	c.add(keys...)
+	for _, g := range vmx.GuestOSList() {
+		c.addGuestOS(g.ID)
+	}
+
+	return c
+}
+
+// addGuestOS adds the guestOS values which are not in c yet.
+func (c *Catalog) addGuestOS(ids ...string) {
+	for _, id := range ids {
+		if _, ok := c.guestOS[strings.ToLower(id)]; !ok {
+			c.guestOS[strings.ToLower(id)] = id
+		}
+	}
+}
+
+// KnownGuestOS reports whether the guestOS value id is in c. The id is case insensitive.
+func (c *Catalog) KnownGuestOS(id string) bool {
+	_, ok := c.guestOS[strings.ToLower(id)]
+
+	return ok
+}
+
+// SuggestGuestOS returns the known guestOS value closest to the unknown id, or empty if no value is close enough to be a typo.
+// The value which has the different numbers from id, such as "windows7-64" for "windows11-64", is not a typo but another version.
+func (c *Catalog) SuggestGuestOS(id string) string {
+	lower := strings.ToLower(id)
+	max := len(lower) / 4
+	if max > 2 {
+		max = 2
+	}
+	numbers := strings.Join(numberRe.FindAllString(lower, -1), ",")
+
+	best, bestDist := "", max+1
+	for candidate, canonical := range c.guestOS {
+		if strings.Join(numberRe.FindAllString(candidate, -1), ",") != numbers {
+			continue
+		}
+		d := distance(lower, candidate)
+		if d < bestDist || (d == bestDist && canonical < best) {
+			best, bestDist = canonical, d
+		}
+	}
+
+	return best
+}
+
+// add adds the keys which are not in c yet.
+func (c *Catalog) add(keys ...string) {
+	for _, key := range keys {
+		n := normalize(key)
+		if _, ok := c.keys[n]; !ok {
+			c.keys[n] = placeholderRe.ReplaceAllStringFunc(key, func(s string) string {
+				if strings.HasPrefix(s, "%") {
+					return "%d"
+				}
+				return s
+			})
+		}
+	}
+}
+
+// Known reports whether the key is in c or has a free form prefix. The key is case insensitive.
+func (c *Catalog) Known(key string) bool {
+	lower := strings.ToLower(key)
+	for _, prefix := range freeFormPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	_, ok := c.keys[normalize(key)]
+
+	return ok
+}
+
+// Suggest returns the known key closest to the unknown key with the device numbers of the key,
+// or empty if no known key is close enough to be a typo.
+func (c *Catalog) Suggest(key string) string {
+	n := normalize(key)
+	// a typo is at most one edit per four characters, and at most two edits.
+	max := len(n) / 4
+	if max > 2 {
+		max = 2
+	}
+	if max == 0 {
+		return ""
+	}
+
+	best, bestDist := "", max+1
+	for candidate, canonical := range c.keys {
+		if abs(len(candidate)-len(n)) > max {
+			continue
+		}
+		d := distance(n, candidate)
+		if d < bestDist || (d == bestDist && canonical < best) {
+			best, bestDist = canonical, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+
+	// restore the device numbers of the key.
+	numbers := numberRe.FindAllString(key, -1)
+	return placeholderRe.ReplaceAllStringFunc(best, func(s string) string {
+		if !strings.HasPrefix(s, "%") {
+			return s
+		}
+		if len(numbers) == 0 {
+			return "0"
+		}
+		s, numbers = numbers[0], numbers[1:]
+		return s
+	})
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// parseMarkdown returns the parameters listed in the tables of vmx.md, such as:
+//
+//	| Category                           | Parameter                                             |
+//	|------------------------------------|-------------------------------------------------------|
+//	| AIOMGR                             | aiomgr.noContinueOnFailure                            |
+//	|                                    | aiomgr.numThreads                                     |
+func parseMarkdown(s string) []string {
+	var keys []string
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		cols := strings.Split(strings.TrimSpace(sc.Text()), "|")
+		// "| a | b |" is split into ["", " a ", " b ", ""].
+		if len(cols) != 4 {
+			continue
+		}
+		key := strings.TrimSpace(cols[2])
+		if key == "" || key == "Parameter" || strings.HasPrefix(key, "-") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// stringsKeyRe matches the line of vmware-vmx.strings which looks like a .vmx parameter.
+var stringsKeyRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(%[du])?(:%[du])?(\.[A-Za-z0-9_%:-]+)*$`)
+
+// parseStrings returns the lines of vmware-vmx.strings which look like a .vmx parameter.
+//
+// The strings(1) output also includes the other identifiers of the binary, so the catalog is permissive for the short names.
+func parseStrings(s string) []string {
+	var keys []string
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if len(line) >= 4 && stringsKeyRe.MatchString(line) {
+			keys = append(keys, line)
+		}
+	}
+
+	return keys
+}
+
+// stringsGuestOSRe matches the display name message of a guestOS value in vmware-vmx.strings,
+// such as "@&!*@*@(msg.gostable8.guest.ubuntu-64)Ubuntu 64-bit".
+var stringsGuestOSRe = regexp.MustCompile(`msg\.gostable8\.guest\.([A-Za-z0-9_-]+)\)`)
+
+// parseStringsGuestOS returns the guestOS values which have the display name in vmware-vmx.strings.
+func parseStringsGuestOS(s string) []string {
+	var ids []string
+	for _, m := range stringsGuestOSRe.FindAllStringSubmatch(s, -1) {
+		ids = append(ids, m[1])
+	}
+
+	return ids
+}
+
+var (
+	defaultCatalog     *Catalog
+	defaultCatalogOnce sync.Once
+)
+
+// DefaultCatalog returns the catalog of the embedded docs/vmx.md and docs/vmware-vmx.strings, and the guestOS values
+// of vmx.GuestOSList and docs/vmware-vmx.strings.
+// The catalog is built on the first call.
+func DefaultCatalog() *Catalog {
+	defaultCatalogOnce.Do(func() {
+		defaultCatalog = NewCatalog(parseMarkdown(docs.VMXParameters))
+		defaultCatalog.add(parseStrings(docs.VMXStrings)...)
+		defaultCatalog.add(extraKeys...)
+		defaultCatalog.addGuestOS(parseStringsGuestOS(docs.VMXStrings)...)
+	})
+
+	return defaultCatalog
+}
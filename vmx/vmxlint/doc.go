@@ -0,0 +1,11 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vmxlint validates the .vmx configuration file against the catalog of the known parameters.
+//
+// The default catalog is built from docs/vmx.md and the parameter names found in docs/vmware-vmx.strings,
+// which are embedded into the program. The known guestOS values are the IDs of vmx.GuestOSList and the guest OS names
+// found in docs/vmware-vmx.strings, and ValidateGuestOS reports the others on request.
+// The cmd/vmxlint command runs Validate from the command line.
+package vmxlint
@@ -0,0 +1,300 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmxlint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-vm/vmware/vmx"
+)
+
+// Kind represents a kind of Issue.
+type Kind int
+
+const (
+	// Unknown is the key which is not in the catalog.
+	Unknown Kind = iota
+	// Typo is the unknown key which is close to a known key.
+	Typo
+	// Duplicate is the key defined more than once.
+	Duplicate
+	// InvalidSlot is the device number which is not supported by VMware.
+	InvalidSlot
+	// Conflict is the settings which contradict each other.
+	Conflict
+	// UnknownGuestOS is the guestOS value which is not in the catalog, which is reported only by ValidateGuestOS.
+	UnknownGuestOS
+)
+
+// String implements a fmt.Stringer interface.
+func (k Kind) String() string {
+	switch k {
+	case Unknown:
+		return "unknown key"
+	case Typo:
+		return "probable typo"
+	case Duplicate:
+		return "duplicate key"
+	case InvalidSlot:
+		return "invalid slot"
+	case Conflict:
+		return "conflict"
+	case UnknownGuestOS:
+		return "unknown guest OS"
+	default:
+		return ""
+	}
+}
+
+// Issue represents a problem found in a .vmx file.
+type Issue struct {
+	// Line is the 1-based line number of the key, or 0 if the issue is not about a line.
+	Line int
+	// Key is the key which has the issue.
+	Key  string
+	Kind Kind
+	// Message describes the issue.
+	Message string
+	// Suggestion is the probable correct key of the Typo issue, or the guestOS value of the UnknownGuestOS issue.
+	Suggestion string
+}
+
+// String returns the issue such as "12: scsi0:0.filname: probable typo: did you mean scsi0:0.fileName?".
+func (i Issue) String() string {
+	s := i.Key + ": " + i.Kind.String()
+	if i.Message != "" {
+		s += ": " + i.Message
+	}
+	if i.Line > 0 {
+		s = strconv.Itoa(i.Line) + ": " + s
+	}
+
+	return s
+}
+
+// line returns the first line number of the key in f, or 0 if the key is missing.
+func line(f *vmx.File, key string) int {
+	if lines := f.Lines(key); len(lines) > 0 {
+		return lines[0]
+	}
+
+	return 0
+}
+
+// ValidateFile reads and validates the name .vmx file with the default catalog.
+func ValidateFile(name string) ([]Issue, error) {
+	f, err := vmx.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return Validate(f, nil), nil
+}
+
+// Validate validates f against the catalog c, and returns the issues in the line order.
+// The nil c uses DefaultCatalog.
+func Validate(f *vmx.File, c *Catalog) []Issue {
+	if c == nil {
+		c = DefaultCatalog()
+	}
+
+	var issues []Issue
+	issues = append(issues, checkKeys(f, c)...)
+	issues = append(issues, checkSlots(f)...)
+	issues = append(issues, checkConflicts(f)...)
+	issues = append(issues, checkGuestOS(f)...)
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Line < issues[j].Line
+	})
+
+	return issues
+}
+
+// checkKeys returns the Unknown, Typo and Duplicate issues.
+func checkKeys(f *vmx.File, c *Catalog) []Issue {
+	var issues []Issue
+	seen := make(map[string]int) // lower case key -> number of the occurrences
+	for _, key := range f.Keys() {
+		lower := strings.ToLower(key)
+		lines := f.Lines(key)
+		n := seen[lower]
+		seen[lower]++
+		if n > 0 {
+			issues = append(issues, Issue{Line: lines[n], Key: key, Kind: Duplicate, Message: fmt.Sprintf("first defined at line %d", lines[0])})
+			continue
+		}
+
+		if c.Known(key) {
+			continue
+		}
+		if suggestion := c.Suggest(key); suggestion != "" {
+			issues = append(issues, Issue{Line: lines[0], Key: key, Kind: Typo, Message: "did you mean " + suggestion + "?", Suggestion: suggestion})
+			continue
+		}
+		issues = append(issues, Issue{Line: lines[0], Key: key, Kind: Unknown})
+	}
+
+	return issues
+}
+
+// List of the regexps which match the device keys.
+var (
+	nodeKeyRe       = regexp.MustCompile(`(?i)^((?:ide|scsi|sata|nvme)\d+:\d+)\.`)
+	controllerKeyRe = regexp.MustCompile(`(?i)^(ide|scsi|sata|nvme)(\d+)\.`)
+	nicKeyRe        = regexp.MustCompile(`(?i)^ethernet(\d+)\.`)
+	serialKeyRe     = regexp.MustCompile(`(?i)^serial(\d+)\.`)
+)
+
+// maxControllers is the number of the controllers of each bus.
+var maxControllers = map[string]int{"ide": 2, "scsi": 4, "sata": 4, "nvme": 4}
+
+// checkSlots returns the InvalidSlot issues.
+func checkSlots(f *vmx.File) []Issue {
+	var issues []Issue
+	for _, key := range f.Keys() {
+		l := line(f, key)
+		if m := nodeKeyRe.FindStringSubmatch(key); m != nil {
+			if n, err := vmx.ParseNode(m[1]); err != nil || !n.Valid() {
+				issues = append(issues, Issue{Line: l, Key: key, Kind: InvalidSlot, Message: m[1] + " is not a valid disk slot"})
+			}
+			continue
+		}
+		if m := controllerKeyRe.FindStringSubmatch(key); m != nil {
+			if i, _ := strconv.Atoi(m[2]); i >= maxControllers[strings.ToLower(m[1])] {
+				issues = append(issues, Issue{Line: l, Key: key, Kind: InvalidSlot, Message: fmt.Sprintf("%s supports %d controllers", strings.ToLower(m[1]), maxControllers[strings.ToLower(m[1])])})
+			}
+			continue
+		}
+		for _, d := range []struct {
+			re   *regexp.Regexp
+			name string
+			max  int
+		}{{nicKeyRe, "ethernet", vmx.MaxNICs}, {serialKeyRe, "serial", vmx.MaxSerials}} {
+			if m := d.re.FindStringSubmatch(key); m != nil {
+				if i, _ := strconv.Atoi(m[1]); i >= d.max {
+					issues = append(issues, Issue{Line: l, Key: key, Kind: InvalidSlot, Message: fmt.Sprintf("%s supports %d devices", d.name, d.max)})
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// bootDisk returns the boot disk of vm, which is the first disk of the bios.hddOrder key,
+// or the first disk in the node order if the key is missing.
+func bootDisk(f *vmx.File, vm *vmx.VirtualMachine) (vmx.Node, bool) {
+	if order, ok := f.Get("bios.hddOrder"); ok {
+		if n, err := vmx.ParseNode(strings.TrimSpace(strings.Split(order, ",")[0])); err == nil {
+			return n, true
+		}
+	}
+	if len(vm.Disks) == 0 {
+		return vmx.Node{}, false
+	}
+
+	return vm.Disks[0].Node, true
+}
+
+// checkConflicts returns the Conflict issues.
+func checkConflicts(f *vmx.File) []Issue {
+	vm := f.VirtualMachine()
+
+	var issues []Issue
+	conflict := func(key, format string, args ...interface{}) {
+		issues = append(issues, Issue{Line: line(f, key), Key: key, Kind: Conflict, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if vm.Firmware == vmx.FirmwareEFI {
+		if n, ok := bootDisk(f, vm); ok && n.Bus == vmx.BusIDE {
+			conflict("firmware", "EFI firmware can not boot from the IDE disk %s", n)
+		}
+	}
+	if vm.CPU.CoresPerSocket > 0 && vm.CPU.Count%vm.CPU.CoresPerSocket != 0 {
+		conflict("cpuid.coresPerSocket", "numvcpus %d is not a multiple of %d cores per socket", vm.CPU.Count, vm.CPU.CoresPerSocket)
+	}
+	if vm.Memory%4 != 0 {
+		conflict("memsize", "memsize %d is not a multiple of 4 MB", vm.Memory)
+	}
+
+	var attached []vmx.Node
+	for _, d := range vm.Disks {
+		attached = append(attached, d.Node)
+	}
+	for _, c := range vm.CDROMs {
+		attached = append(attached, c.Node)
+	}
+	controllers := make(map[string]bool)
+	for _, n := range attached {
+		controller := string(n.Bus) + strconv.Itoa(n.Controller)
+		// the IDE controllers are always present.
+		if n.Bus == vmx.BusIDE || controllers[controller] {
+			continue
+		}
+		controllers[controller] = true
+		if v, _ := f.Get(controller + ".present"); !strings.EqualFold(v, "TRUE") {
+			conflict(n.String()+".present", "the controller %s is not present", controller)
+		}
+	}
+
+	for _, nic := range vm.NICs {
+		p := "ethernet" + strconv.Itoa(nic.Index) + "."
+		if strings.EqualFold(nic.AddressType, "static") && nic.Address == "" {
+			conflict(p+"addressType", "the static address type requires %saddress", p)
+		}
+		if strings.EqualFold(nic.ConnectionType, "custom") && nic.VNet == "" {
+			conflict(p+"connectionType", "the custom connection type requires %svnet", p)
+		}
+	}
+
+	return issues
+}
+
+// ValidateGuestOS returns the UnknownGuestOS issue if the guestOS value of f is not in the catalog c.
+// The nil c uses DefaultCatalog.
+//
+// Validate does not report the unknown guestOS values, since the newer VMware products add the values
+// which are not in the catalog yet.
+func ValidateGuestOS(f *vmx.File, c *Catalog) []Issue {
+	if c == nil {
+		c = DefaultCatalog()
+	}
+
+	id, ok := f.Get("guestOS")
+	if !ok || c.KnownGuestOS(id) {
+		return nil
+	}
+
+	issue := Issue{Line: line(f, "guestOS"), Key: "guestOS", Kind: UnknownGuestOS, Message: fmt.Sprintf("%q is not in the catalog", id)}
+	if suggestion := c.SuggestGuestOS(id); suggestion != "" {
+		issue.Message += ", did you mean " + suggestion + "?"
+		issue.Suggestion = suggestion
+	}
+
+	return []Issue{issue}
+}
+
+// checkGuestOS returns the Conflict issues with the requirements of the guest OS in vmx.GuestOSInfo.
+func checkGuestOS(f *vmx.File) []Issue {
+	id, ok := f.Get("guestOS")
+	if !ok {
+		return nil
+	}
+	g, err := vmx.GuestOSInfo(id)
+	if err != nil {
+		return nil
+	}
+
+	var issues []Issue
+	if g.Family == "darwin" && f.VirtualMachine().Firmware != vmx.FirmwareEFI {
+		issues = append(issues, Issue{Line: line(f, "guestOS"), Key: "guestOS", Kind: Conflict, Message: g.DisplayName + " requires the EFI firmware"})
+	}
+
+	return issues
+}
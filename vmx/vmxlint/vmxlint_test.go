@@ -0,0 +1,258 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmxlint
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-vm/vmware/vmx"
+)
+
+var testCatalog = NewCatalog([]string{
+	"config.version",
+	"displayName",
+	"memsize",
+	"numvcpus",
+	"cpuid.coresPerSocket",
+	"firmware",
+	"bios.hddOrder",
+	"ide%d:%d.present",
+	"ide%d:%d.fileName",
+	"scsi%d.present",
+	"scsi%d:%d.present",
+	"scsi%d:%d.fileName",
+	"sata%d.present",
+	"sata%d:%d.present",
+	"sata%d:%d.fileName",
+	"ethernet%d.present",
+	"ethernet%d.addressType",
+	"ethernet%d.connectionType",
+	"serial%d.present",
+})
+
+func TestCatalogSuggest(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "memsise", want: "memsize"},
+		{key: "scsi1:3.filname", want: "scsi1:3.fileName"},
+		{key: "ethernet2.adressType", want: "ethernet2.addressType"},
+		{key: "numcpus", want: "numvcpus"},
+		{key: "mem", want: ""},
+		{key: "totally.unrelated", want: ""},
+	}
+	for _, tt := range tests {
+		if got := testCatalog.Suggest(tt.key); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestCatalogKnown(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "MEMSIZE", want: true},
+		{key: "scsi0:15.fileName", want: true},
+		{key: ".encoding", want: true},
+		{key: "guestinfo.anything", want: true},
+		{key: "scsi0:0.filname"},
+	}
+	for _, tt := range tests {
+		if got := testCatalog.Known(tt.key); got != tt.want {
+			t.Errorf("Known(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		vmx  string
+		want []string
+	}{
+		{
+			name: "valid",
+			vmx:  ".encoding = \"UTF-8\"\nmemsize = \"2048\"\nscsi0.present = \"TRUE\"\nscsi0:0.present = \"TRUE\"\n",
+		},
+		{
+			name: "keys",
+			vmx:  "memsize = \"2048\"\nmemsise = \"1024\"\nfoo.bar = \"1\"\nMEMSIZE = \"4096\"\n",
+			want: []string{
+				"2: memsise: probable typo: did you mean memsize?",
+				"3: foo.bar: unknown key",
+				"4: MEMSIZE: duplicate key: first defined at line 1",
+			},
+		},
+		{
+			name: "slots",
+			vmx:  "scsi0.present = \"TRUE\"\nscsi0:7.present = \"TRUE\"\nide2:0.present = \"TRUE\"\nsata4.present = \"TRUE\"\nethernet10.present = \"TRUE\"\n",
+			want: []string{
+				"2: scsi0:7.present: invalid slot: scsi0:7 is not a valid disk slot",
+				"3: ide2:0.present: invalid slot: ide2:0 is not a valid disk slot",
+				"4: sata4.present: invalid slot: sata supports 4 controllers",
+				"5: ethernet10.present: invalid slot: ethernet supports 10 devices",
+			},
+		},
+		{
+			name: "efi ide boot disk",
+			vmx:  "firmware = \"efi\"\nide0:0.present = \"TRUE\"\nide0:0.fileName = \"test.vmdk\"\n",
+			want: []string{"1: firmware: conflict: EFI firmware can not boot from the IDE disk ide0:0"},
+		},
+		{
+			name: "efi hdd order",
+			vmx:  "firmware = \"efi\"\nide0:0.present = \"TRUE\"\nsata0.present = \"TRUE\"\nsata0:0.present = \"TRUE\"\nbios.hddOrder = \"sata0:0\"\n",
+		},
+		{
+			name: "cpu and memory",
+			vmx:  "numvcpus = \"3\"\ncpuid.coresPerSocket = \"2\"\nmemsize = \"1023\"\n",
+			want: []string{
+				"2: cpuid.coresPerSocket: conflict: numvcpus 3 is not a multiple of 2 cores per socket",
+				"3: memsize: conflict: memsize 1023 is not a multiple of 4 MB",
+			},
+		},
+		{
+			name: "controller not present",
+			vmx:  "sata0:1.present = \"TRUE\"\n",
+			want: []string{"1: sata0:1.present: conflict: the controller sata0 is not present"},
+		},
+		{
+			name: "network",
+			vmx:  "ethernet0.present = \"TRUE\"\nethernet0.addressType = \"static\"\nethernet0.connectionType = \"custom\"\n",
+			want: []string{
+				"2: ethernet0.addressType: conflict: the static address type requires ethernet0.address",
+				"3: ethernet0.connectionType: conflict: the custom connection type requires ethernet0.vnet",
+			},
+		},
+		{
+			name: "guest os",
+			vmx:  "guestOS = \"Ubuntu-64\"\n",
+		},
+		{
+			// the unknown guestOS value is reported only by ValidateGuestOS.
+			name: "unknown guest os",
+			vmx:  "guestOS = \"windows11-64\"\n",
+		},
+		{
+			name: "macos bios",
+			vmx:  "guestOS = \"darwin19-64\"\n",
+			want: []string{"1: guestOS: conflict: macOS 10.15 requires the EFI firmware"},
+		},
+		{
+			name: "macos efi",
+			vmx:  "guestOS = \"darwin19-64\"\nfirmware = \"efi\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := vmx.Parse(strings.NewReader(tt.vmx))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, issue := range Validate(f, testCatalog) {
+				if issue.Kind == Unknown && tt.name != "keys" {
+					// the keys of the conflict tests are not all in testCatalog.
+					continue
+				}
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Validate =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateGuestOS(t *testing.T) {
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{id: "ubuntu-64"},
+		{id: "Ubuntu-64"},
+		// the values which are only in vmware-vmx.strings.
+		{id: "centos-64"},
+		{id: "sles12-64"},
+		{id: "winXPPro-64"},
+		// the values which are only in vmx.GuestOSList.
+		{id: "windows11-64"},
+		{id: "rhel9-64"},
+		{id: "ubuntu64", want: []string{`2: guestOS: unknown guest OS: "ubuntu64" is not in the catalog, did you mean ubuntu-64?`}},
+		{id: "windos9-64", want: []string{`2: guestOS: unknown guest OS: "windos9-64" is not in the catalog, did you mean windows9-64?`}},
+		// another version is not a typo.
+		{id: "windows12-64", want: []string{`2: guestOS: unknown guest OS: "windows12-64" is not in the catalog`}},
+		{id: "rhel10-64", want: []string{`2: guestOS: unknown guest OS: "rhel10-64" is not in the catalog`}},
+		{id: "plan9-64", want: []string{`2: guestOS: unknown guest OS: "plan9-64" is not in the catalog`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			f, err := vmx.Parse(strings.NewReader("memsize = \"2048\"\nguestOS = \"" + tt.id + "\"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, issue := range ValidateGuestOS(f, nil) {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ValidateGuestOS =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	c := DefaultCatalog()
+	for _, key := range []string{"displayName", "guestOS", "memsize", "scsi0:0.fileName", "ethernet0.virtualDev", "aiomgr.numThreads", "scsi0:0.redo"} {
+		if !c.Known(key) {
+			t.Errorf("Known(%q) = false, want true", key)
+		}
+	}
+	if got := c.Suggest("scsi0:0.filname"); got != "scsi0:0.fileName" {
+		t.Errorf("Suggest = %q, want %q", got, "scsi0:0.fileName")
+	}
+}
+
+func TestValidateSetVirtualMachine(t *testing.T) {
+	// the .vmx files written by vmx.SetVirtualMachine for the recommended hardware of the guest OSes have no issue.
+	for _, g := range vmx.GuestOSList() {
+		t.Run(g.ID, func(t *testing.T) {
+			vm := &vmx.VirtualMachine{
+				DisplayName: "test",
+				GuestOS:     g.ID,
+				HWVersion:   14,
+				CPU:         vmx.CPU{Count: 1},
+				Memory:      g.Memory,
+				Firmware:    g.Firmware,
+				USB:         vmx.USB{Present: true},
+			}
+			if _, err := vm.AddDisk(g.DiskBus, "test.vmdk"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := vm.AddCDROM(g.DiskBus, "/iso/test.iso"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := vm.AddNIC("nat", g.NICDevice); err != nil {
+				t.Fatal(err)
+			}
+			f := vmx.New()
+			if err := f.SetVirtualMachine(vm); err != nil {
+				t.Fatal(err)
+			}
+
+			issues := append(Validate(f, nil), ValidateGuestOS(f, nil)...)
+			if len(issues) > 0 {
+				t.Fatalf("issues = %v", issues)
+			}
+		})
+	}
+}
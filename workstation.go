@@ -0,0 +1,17 @@
+// Copyright 2018 The go-vm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vmware
+
+// Workstation represents a VMware Workstation application.
+type Workstation struct {
+	*Host
+}
+
+// NewWorkstation return the new Workstation.
+func NewWorkstation(vmx, username, password string) *Workstation {
+	return &Workstation{
+		Host: NewHost(ProductWorkstation, vmx, username, password),
+	}
+}